@@ -0,0 +1,157 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImageCleanupPolicySpec defines the desired state of ImageCleanupPolicy
+type ImageCleanupPolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The name of the Repository in the same namespace to clean up
+	RepositoryName string `json:"repositoryName"`
+
+	// (Optional) The minimum age since the image was pushed before it is deleted, e.g. 720h. Defaults to 720h.
+	// +optional
+	MinAge *metav1.Duration `json:"minAge,omitempty"`
+
+	// (Optional) The number of most recently pushed images that are always kept. Only top-level images
+	// count, the child manifests of image indexes and the signatures, attestations and SBOMs of images do not.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepLast int `json:"keepLast,omitempty"`
+
+	// (Optional) Regular expressions of the tags to clean up. A tagged image is only deleted if all
+	// of its tags match one of the patterns. Untagged images are always considered.
+	// If not specified, all images are considered.
+	// +optional
+	TagPatterns []string `json:"tagPatterns,omitempty"`
+
+	// (Optional) Regular expressions of the tags to keep, e.g. ^stable$. Images with any matching tag are never deleted.
+	// +optional
+	KeepTagPatterns []string `json:"keepTagPatterns,omitempty"`
+
+	// (Optional) Keys of kubeconfig Secrets in the same namespace of further clusters whose Pods and workloads
+	// keep images in use. The images in use by the local cluster are always kept.
+	// +optional
+	RemoteClusters []corev1.SecretKeySelector `json:"remoteClusters,omitempty"`
+
+	// (Optional) Only report the images that would be deleted in the status.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// (Optional) The number of images deleted per BatchDeleteImage call. Defaults to 100.
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// (Optional) The interval to run the cleanup. Defaults to 1h.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// CleanupCandidate defines an image selected for deletion
+type CleanupCandidate struct {
+	// The sha256 digest of the image manifest
+	Digest string `json:"digest"`
+
+	// The tags of the image
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+
+	// The time the image was pushed
+	// +optional
+	PushedAt *metav1.Time `json:"pushedAt,omitempty"`
+}
+
+// ImageCleanupPolicyStatus defines the observed state of ImageCleanupPolicy
+type ImageCleanupPolicyStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The last time the cleanup was run
+	// +optional
+	LastRunTime *metav1.Time `json:"lastRunTime,omitempty"`
+
+	// The number of images selected for deletion by the last run
+	// +optional
+	CandidateCount int `json:"candidateCount,omitempty"`
+
+	// The number of images deleted by the last run
+	// +optional
+	DeletedCount int `json:"deletedCount,omitempty"`
+
+	// The number of images kept because they are in use
+	// +optional
+	InUseCount int `json:"inUseCount,omitempty"`
+
+	// The images selected for deletion by the last run, oldest first and limited to 100 entries
+	// +optional
+	Candidates []CleanupCandidate `json:"candidates,omitempty"`
+
+	// (Optional) Message with details in case the cleanup failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repositoryName`
+//+kubebuilder:printcolumn:name="Dry Run",type=boolean,JSONPath=`.spec.dryRun`
+//+kubebuilder:printcolumn:name="Candidates",type=integer,JSONPath=`.status.candidateCount`
+//+kubebuilder:printcolumn:name="Deleted",type=integer,JSONPath=`.status.deletedCount`
+//+kubebuilder:printcolumn:name="Last Run",type=date,JSONPath=`.status.lastRunTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageCleanupPolicy is the Schema for the imagecleanuppolicies API
+type ImageCleanupPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageCleanupPolicySpec   `json:"spec,omitempty"`
+	Status ImageCleanupPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageCleanupPolicyList contains a list of ImageCleanupPolicy
+type ImageCleanupPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageCleanupPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageCleanupPolicy{}, &ImageCleanupPolicyList{})
+}
@@ -0,0 +1,146 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImageInventorySpec defines the desired state of ImageInventory
+type ImageInventorySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) The interval to refresh the inventory. Defaults to 10m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// InventoryImage defines an image of a managed Repository run by containers of the cluster
+type InventoryImage struct {
+	// The Repository in the form namespace/name
+	Repository string `json:"repository"`
+
+	// The URI of the repository
+	RepositoryUri string `json:"repositoryUri"`
+
+	// The sha256 digest of the image manifest
+	Digest string `json:"digest"`
+
+	// The tag the containers reference, or the first tag of the image if referenced by digest
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// The time the image was pushed to the repository
+	// +optional
+	PushedAt *metav1.Time `json:"pushedAt,omitempty"`
+
+	// The number of scan findings by severity, if the image has been scanned
+	// +optional
+	// +nullable
+	Summary *VulnerabilitySummary `json:"summary,omitempty"`
+
+	// The number of workload containers running the image
+	// +optional
+	WorkloadCount int `json:"workloadCount,omitempty"`
+
+	// The workload containers running the image, sorted and limited to 10 entries
+	Workloads []InventoryWorkload `json:"workloads"`
+}
+
+// InventoryWorkload defines a container of a workload running an image
+type InventoryWorkload struct {
+	// The namespace of the workload
+	Namespace string `json:"namespace"`
+
+	// The kind of the workload owning the Pod, e.g. Deployment, or Pod if the Pod has no owner
+	Kind string `json:"kind"`
+
+	// The name of the workload
+	Name string `json:"name"`
+
+	// The name of the container
+	Container string `json:"container"`
+}
+
+// ImageInventoryStatus defines the observed state of ImageInventory
+type ImageInventoryStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The last time the inventory was refreshed
+	// +optional
+	LastUpdatedTime *metav1.Time `json:"lastUpdatedTime,omitempty"`
+
+	// The number of images of managed repositories run by containers of the cluster
+	// +optional
+	ImageCount int `json:"imageCount,omitempty"`
+
+	// The number of running containers using images of managed repositories
+	// +optional
+	ContainerCount int `json:"containerCount,omitempty"`
+
+	// The images of managed repositories run by containers of the cluster, sorted by repository and digest
+	// and limited to 200 entries
+	// +optional
+	Images []InventoryImage `json:"images,omitempty"`
+
+	// (Optional) Message with details in case the inventory could not be refreshed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Images",type=integer,JSONPath=`.status.imageCount`
+//+kubebuilder:printcolumn:name="Containers",type=integer,JSONPath=`.status.containerCount`
+//+kubebuilder:printcolumn:name="Last Updated",type=date,JSONPath=`.status.lastUpdatedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageInventory is the Schema for the imageinventories API
+type ImageInventory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageInventorySpec   `json:"spec,omitempty"`
+	Status ImageInventoryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageInventoryList contains a list of ImageInventory
+type ImageInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageInventory `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageInventory{}, &ImageInventoryList{})
+}
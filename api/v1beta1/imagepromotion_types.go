@@ -0,0 +1,154 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImagePromotionSpec defines the desired state of ImagePromotion
+type ImagePromotionSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The source image to promote
+	Source ImagePromotionSource `json:"source"`
+
+	// The destination repository and tags of the promoted image
+	Destination ImagePromotionDestination `json:"destination"`
+}
+
+// ImagePromotionSource defines the image to promote
+type ImagePromotionSource struct {
+	// The name of the Repository object in the same namespace, e.g. app-dev. If registryId or region is given,
+	// the name of the ECR repository instead, which must be allowed as source by an ImagePromotionPolicy.
+	RepositoryName string `json:"repositoryName"`
+
+	// (Optional) The AWS account ID of the registry. Defaults to the registry of the operator.
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// (Optional) The region of the registry. Defaults to the region of the operator.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// (Optional) The tag of the image to promote, either the tag or the digest is required.
+	// +optional
+	ImageTag string `json:"imageTag,omitempty"`
+
+	// (Optional) The sha256 digest of the image to promote, either the tag or the digest is required.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+}
+
+// ImagePromotionDestination defines the repository and tags of the promoted image
+type ImagePromotionDestination struct {
+	// The name of the Repository object in the same namespace, e.g. app-prod. If registryId or region is given,
+	// the name of the ECR repository instead, which must be allowed as destination by an ImagePromotionPolicy.
+	RepositoryName string `json:"repositoryName"`
+
+	// (Optional) The AWS account ID of the registry. Defaults to the registry of the operator.
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// (Optional) The region of the registry. Defaults to the region of the operator.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// (Optional) The tags of the promoted image, e.g. 1.2.3. If empty, the image is promoted by digest only.
+	// +optional
+	Tags []string `json:"tags,omitempty"`
+}
+
+// The PromotionPhase type defines the state of the promotion, Succeeded or Failed
+type PromotionPhase string
+
+const (
+	PromotionPhaseSucceeded PromotionPhase = "Succeeded"
+	PromotionPhaseFailed    PromotionPhase = "Failed"
+)
+
+// ImagePromotionStatus defines the observed state of ImagePromotion
+type ImagePromotionStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The state of the promotion
+	// +optional
+	Phase PromotionPhase `json:"phase,omitempty"`
+
+	// (Optional) Message with details in case the promotion failed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The sha256 digest of the promoted image
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// The time the image was promoted
+	// +optional
+	PromotedAt *metav1.Time `json:"promotedAt,omitempty"`
+
+	// Whether the image layers were transferred, or only the manifest was copied
+	// +optional
+	LayersTransferred bool `json:"layersTransferred,omitempty"`
+
+	// The generation of the spec the promotion was last performed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.spec.source.repositoryName`
+//+kubebuilder:printcolumn:name="Destination",type=string,JSONPath=`.spec.destination.repositoryName`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Promoted",type=date,JSONPath=`.status.promotedAt`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImagePromotion is the Schema for the imagepromotions API
+type ImagePromotion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImagePromotionSpec   `json:"spec,omitempty"`
+	Status ImagePromotionStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImagePromotionList contains a list of ImagePromotion
+type ImagePromotionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImagePromotion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImagePromotion{}, &ImagePromotionList{})
+}
@@ -0,0 +1,90 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImagePromotionPolicySpec defines the desired state of ImagePromotionPolicy
+type ImagePromotionPolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) Selects the namespaces the policy applies to. Applies to all namespaces if empty.
+	// +optional
+	// +nullable
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// (Optional) The repositories images may be promoted from by an explicit registryId or region
+	// +optional
+	Sources []PromotionRepository `json:"sources,omitempty"`
+
+	// (Optional) The repositories images may be promoted to by an explicit registryId or region
+	// +optional
+	Destinations []PromotionRepository `json:"destinations,omitempty"`
+}
+
+// PromotionRepository defines ECR repositories in a registry outside of the Repository objects of a namespace
+type PromotionRepository struct {
+	// (Optional) The AWS account ID of the registry. Matches the registry of the operator if empty.
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// (Optional) The region of the registry. Matches any region if empty.
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// The name of the ECR repositories, may use the wildcards * and ?, e.g. prod/*
+	RepositoryName string `json:"repositoryName"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImagePromotionPolicy is the Schema for the imagepromotionpolicies API
+type ImagePromotionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ImagePromotionPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImagePromotionPolicyList contains a list of ImagePromotionPolicy
+type ImagePromotionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImagePromotionPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImagePromotionPolicy{}, &ImagePromotionPolicyList{})
+}
@@ -0,0 +1,119 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImageScanSpec defines the desired state of ImageScan
+type ImageScanSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The name of the Repository in the same namespace, the scanned ECR repository is its spec.repositoryName
+	RepositoryName string `json:"repositoryName"`
+
+	// (Optional) The tag of the image to scan, either the tag or the digest is required.
+	// +optional
+	ImageTag string `json:"imageTag,omitempty"`
+
+	// (Optional) The sha256 digest of the image to scan, either the tag or the digest is required.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+}
+
+// The ScanStatus type defines the ECR scan status, e.g. IN_PROGRESS, COMPLETE or FAILED
+type ScanStatus string
+
+const (
+	ScanStatusInProgress ScanStatus = "IN_PROGRESS"
+	ScanStatusComplete   ScanStatus = "COMPLETE"
+	ScanStatusFailed     ScanStatus = "FAILED"
+)
+
+// ImageScanStatus defines the observed state of ImageScan
+type ImageScanStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The current state of the scan
+	// +optional
+	ScanStatus ScanStatus `json:"scanStatus,omitempty"`
+
+	// The description of the scan status, e.g. the reason of a failed scan
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// The sha256 digest of the scanned image
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// The time the scan was started
+	// +optional
+	StartedAt *metav1.Time `json:"startedAt,omitempty"`
+
+	// The time the scan was completed
+	// +optional
+	CompletedAt *metav1.Time `json:"completedAt,omitempty"`
+
+	// The number of findings by severity of the completed scan
+	// +optional
+	Summary *VulnerabilitySummary `json:"summary,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repositoryName`
+//+kubebuilder:printcolumn:name="Tag",type=string,JSONPath=`.spec.imageTag`
+//+kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.scanStatus`
+//+kubebuilder:printcolumn:name="Critical",type=integer,JSONPath=`.status.summary.criticalCount`
+//+kubebuilder:printcolumn:name="High",type=integer,JSONPath=`.status.summary.highCount`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageScan is the Schema for the imagescans API
+type ImageScan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageScanSpec   `json:"spec,omitempty"`
+	Status ImageScanStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageScanList contains a list of ImageScan
+type ImageScanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageScan `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageScan{}, &ImageScanList{})
+}
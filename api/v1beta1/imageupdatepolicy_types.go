@@ -0,0 +1,180 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImageUpdatePolicySpec defines the desired state of ImageUpdatePolicy
+type ImageUpdatePolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The name of the Repository in the same namespace to watch for new tags
+	RepositoryName string `json:"repositoryName"`
+
+	// The policy to select the tag to roll out
+	TagPolicy ImageTagPolicy `json:"tagPolicy"`
+
+	// The workloads in the same namespace to update. Containers referencing the repository are updated.
+	// +kubebuilder:validation:MinItems=1
+	Targets []ImageUpdateTarget `json:"targets"`
+
+	// (Optional) Only roll out tags whose scan findings pass the gate.
+	// +optional
+	// +nullable
+	ScanGate *ImageUpdateScanGate `json:"scanGate,omitempty"`
+
+	// (Optional) The interval to check the repository for new tags. Defaults to 5m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// ImageTagPolicy defines how the tag to roll out is selected
+type ImageTagPolicy struct {
+	// (Optional) Semver selects the highest version in the range, Regex and Newest select the most recently pushed tag. Defaults to Newest.
+	// +kubebuilder:default=Newest
+	// +kubebuilder:validation:Enum=Semver;Regex;Newest
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// (Optional) The semver range, e.g. ">=1.2.0 <2.0.0" or "~1.2". Required for the Semver type.
+	// +optional
+	Range string `json:"range,omitempty"`
+
+	// (Optional) The regular expression the tags must match, e.g. "^main-[a-f0-9]+$". Required for the Regex type,
+	// restricts the candidate tags of the other types.
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// ImageUpdateTarget references a workload to update
+type ImageUpdateTarget struct {
+	// The kind of the workload
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;CronJob
+	Kind string `json:"kind"`
+
+	// The name of the workload
+	Name string `json:"name"`
+
+	// (Optional) The name of the container to update. Defaults to all containers referencing the repository.
+	// +optional
+	Container string `json:"container,omitempty"`
+}
+
+// ImageUpdateScanGate defines the scan findings a tag must pass to be rolled out
+type ImageUpdateScanGate struct {
+	// (Optional) The highest allowed finding severity. Defaults to HIGH.
+	// +kubebuilder:default=HIGH
+	// +kubebuilder:validation:Enum=CRITICAL;HIGH;MEDIUM;LOW;INFORMATIONAL
+	// +optional
+	MaxSeverity string `json:"maxSeverity,omitempty"`
+
+	// (Optional) The maximum number of findings of the highest allowed severity. Unlimited if not specified.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCount *int `json:"maxCount,omitempty"`
+
+	// (Optional) Whether tags without a completed scan are held back. Defaults to true.
+	// Tags with a pending scan are always held back until the scan has completed.
+	// +kubebuilder:default=true
+	// +optional
+	RequireScan bool `json:"requireScan"`
+}
+
+// ImageUpdateRecord defines a roll out of a tag to the target workloads
+type ImageUpdateRecord struct {
+	// The rolled out tag
+	Tag string `json:"tag"`
+
+	// The sha256 digest of the rolled out image
+	Digest string `json:"digest"`
+
+	// The time the workloads were updated
+	UpdatedAt metav1.Time `json:"updatedAt"`
+
+	// The updated workloads in the form kind/name
+	// +optional
+	Workloads []string `json:"workloads,omitempty"`
+}
+
+// ImageUpdatePolicyStatus defines the observed state of ImageUpdatePolicy
+type ImageUpdatePolicyStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The currently selected tag
+	// +optional
+	SelectedTag string `json:"selectedTag,omitempty"`
+
+	// The sha256 digest of the currently selected tag
+	// +optional
+	SelectedDigest string `json:"selectedDigest,omitempty"`
+
+	// The last time the repository was checked for new tags
+	// +optional
+	LastCheckedTime *metav1.Time `json:"lastCheckedTime,omitempty"`
+
+	// (Optional) Message with details in case no tag could be selected or rolled out
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The most recent updates of the target workloads, most recent first
+	// +optional
+	History []ImageUpdateRecord `json:"history,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repositoryName`
+//+kubebuilder:printcolumn:name="Tag",type=string,JSONPath=`.status.selectedTag`
+//+kubebuilder:printcolumn:name="Last Checked",type=date,JSONPath=`.status.lastCheckedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageUpdatePolicy is the Schema for the imageupdatepolicies API
+type ImageUpdatePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageUpdatePolicySpec   `json:"spec,omitempty"`
+	Status ImageUpdatePolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageUpdatePolicyList contains a list of ImageUpdatePolicy
+type ImageUpdatePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageUpdatePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageUpdatePolicy{}, &ImageUpdatePolicyList{})
+}
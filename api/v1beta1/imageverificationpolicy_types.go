@@ -0,0 +1,116 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImageVerificationPolicySpec defines the desired state of ImageVerificationPolicy
+type ImageVerificationPolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) Selects the Repositories by label whose images must be signed. Applies to all Repositories if empty.
+	// +optional
+	// +nullable
+	RepositorySelector *metav1.LabelSelector `json:"repositorySelector,omitempty"`
+
+	// (Optional) The signature format, Cosign signatures are stored with the sha256-<digest>.sig tag,
+	// Notation signatures as referrers of the image. Defaults to Cosign.
+	// +kubebuilder:default=Cosign
+	// +kubebuilder:validation:Enum=Cosign;Notation
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// (Optional) PEM encoded public keys, a signature made with any of the keys is valid. Only used for Cosign.
+	// +optional
+	PublicKeys []string `json:"publicKeys,omitempty"`
+
+	// (Optional) PEM encoded root certificates the signing certificates must chain to, e.g. the Fulcio root
+	// for keyless Cosign signatures or the signing CA for Notation. Required for certificate identities.
+	// +optional
+	CertificateRoots string `json:"certificateRoots,omitempty"`
+
+	// (Optional) The identities of the signing certificates, a signature by any of the identities is valid.
+	// Any certificate chaining to the roots is valid if not specified.
+	// +optional
+	CertificateIdentities []CertificateIdentity `json:"certificateIdentities,omitempty"`
+
+	// (Optional) Deny or Warn on images without a valid signature. Defaults to Deny.
+	// +kubebuilder:default=Deny
+	// +kubebuilder:validation:Enum=Deny;Warn
+	// +optional
+	Action string `json:"action,omitempty"`
+}
+
+// CertificateIdentity defines the identity of a signing certificate
+type CertificateIdentity struct {
+	// The subject of the certificate, matches an email or URI subject alternative name,
+	// or the distinguished name, e.g. https://github.com/org/repo/.github/workflows/release.yml@refs/heads/main
+	Subject string `json:"subject"`
+
+	// (Optional) The OIDC issuer of keyless Cosign certificates, e.g. https://token.actions.githubusercontent.com
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// ImageVerificationPolicyStatus defines the observed state of ImageVerificationPolicy
+type ImageVerificationPolicyStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Format",type=string,JSONPath=`.spec.format`
+//+kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageVerificationPolicy is the Schema for the imageverificationpolicies API
+type ImageVerificationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageVerificationPolicySpec   `json:"spec,omitempty"`
+	Status ImageVerificationPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageVerificationPolicyList contains a list of ImageVerificationPolicy
+type ImageVerificationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageVerificationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageVerificationPolicy{}, &ImageVerificationPolicyList{})
+}
@@ -0,0 +1,108 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ImageVulnerabilityPolicySpec defines the desired state of ImageVulnerabilityPolicy
+type ImageVulnerabilityPolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) Selects the namespaces the policy applies to. Applies to all namespaces if empty.
+	// +optional
+	// +nullable
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// (Optional) The highest allowed finding severity, findings of a higher severity are violations. Defaults to HIGH.
+	// +kubebuilder:default=HIGH
+	// +kubebuilder:validation:Enum=CRITICAL;HIGH;MEDIUM;LOW;INFORMATIONAL
+	// +optional
+	MaxSeverity string `json:"maxSeverity,omitempty"`
+
+	// (Optional) The maximum number of findings of the highest allowed severity. Unlimited if not specified.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCount *int `json:"maxCount,omitempty"`
+
+	// (Optional) Violations of images pushed within the grace period only cause a warning.
+	// +optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+
+	// (Optional) Whether images without a completed scan are violations.
+	// +optional
+	RequireScan bool `json:"requireScan,omitempty"`
+
+	// (Optional) Whether the VulnerabilityExceptions of the namespace are applied. Since exceptions are
+	// declared by the namespace owners, findings are counted without exceptions by default.
+	// +optional
+	AllowExceptions bool `json:"allowExceptions,omitempty"`
+
+	// (Optional) Deny or Warn on violations. Defaults to Deny.
+	// +kubebuilder:default=Deny
+	// +kubebuilder:validation:Enum=Deny;Warn
+	// +optional
+	Action string `json:"action,omitempty"`
+}
+
+// ImageVulnerabilityPolicyStatus defines the observed state of ImageVulnerabilityPolicy
+type ImageVulnerabilityPolicyStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Max Severity",type=string,JSONPath=`.spec.maxSeverity`
+//+kubebuilder:printcolumn:name="Action",type=string,JSONPath=`.spec.action`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImageVulnerabilityPolicy is the Schema for the imagevulnerabilitypolicies API
+type ImageVulnerabilityPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImageVulnerabilityPolicySpec   `json:"spec,omitempty"`
+	Status ImageVulnerabilityPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImageVulnerabilityPolicyList contains a list of ImageVulnerabilityPolicy
+type ImageVulnerabilityPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImageVulnerabilityPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImageVulnerabilityPolicy{}, &ImageVulnerabilityPolicyList{})
+}
@@ -0,0 +1,140 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// PublicRepositorySpec defines the desired state of PublicRepository
+type PublicRepositorySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) The catalog data displayed in the Amazon ECR Public Gallery.
+	// +optional
+	// +nullable
+	CatalogData *PublicRepositoryCatalogData `json:"catalogData,omitempty"`
+
+	// (Optional) The repository policy JSON text. If neither this nor PolicyTextFrom
+	// is specified, any existing repository policy is removed.
+	// +optional
+	PolicyText string `json:"policyText,omitempty"`
+
+	// (Optional) Reference to a ConfigMap or Secret key in the same namespace containing the repository policy JSON text.
+	// +optional
+	// +nullable
+	PolicyTextFrom *PolicyTextSource `json:"policyTextFrom,omitempty"`
+}
+
+// PublicRepositoryCatalogData defines the catalog data of a public repository
+type PublicRepositoryCatalogData struct {
+	// (Optional) A short description of the contents of the repository.
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// (Optional) A detailed description of the contents of the repository in markdown format.
+	// +kubebuilder:validation:MaxLength=10240
+	// +optional
+	AboutText string `json:"aboutText,omitempty"`
+
+	// (Optional) Detailed information about how to use the contents of the repository in markdown format.
+	// +kubebuilder:validation:MaxLength=10240
+	// +optional
+	UsageText string `json:"usageText,omitempty"`
+
+	// (Optional) The system architectures the images are compatible with, e.g. ARM, ARM 64, x86 or x86-64.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+
+	// (Optional) The operating systems the images are compatible with, e.g. Linux or Windows.
+	// +kubebuilder:validation:MaxItems=50
+	// +optional
+	OperatingSystems []string `json:"operatingSystems,omitempty"`
+
+	// (Optional) The base64 encoded repository logo in PNG format. The logo is only
+	// visible in the Amazon ECR Public Gallery for verified accounts.
+	// +optional
+	LogoImageBlob []byte `json:"logoImageBlob,omitempty"`
+}
+
+// PublicRepositoryStatus defines the observed state of PublicRepository
+type PublicRepositoryStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// Full ARN of the public repository
+	// +optional
+	RepositoryArn string `json:"repositoryArn,omitempty"`
+
+	// The registry ID where the public repository was created
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// The URI of the public repository (in the form public.ecr.aws/registry_alias/repositoryName)
+	// +optional
+	RepositoryUri string `json:"repositoryUri,omitempty"`
+
+	// (Optional) Message with details in case the catalog data or policy could not be applied
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The last time the catalog data and policy were applied
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="URI",type=string,JSONPath=`.status.repositoryUri`
+//+kubebuilder:printcolumn:name="Last Applied",type=date,JSONPath=`.status.lastAppliedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PublicRepository is the Schema for the publicrepositories API
+type PublicRepository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PublicRepositorySpec   `json:"spec,omitempty"`
+	Status PublicRepositoryStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PublicRepositoryList contains a list of PublicRepository
+type PublicRepositoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PublicRepository `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PublicRepository{}, &PublicRepositoryList{})
+}
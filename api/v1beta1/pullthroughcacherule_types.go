@@ -0,0 +1,118 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// PullThroughCacheRuleSpec defines the desired state of PullThroughCacheRule
+type PullThroughCacheRuleSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The repository name prefix of the cached images, e.g. docker-hub
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`
+	// +kubebuilder:validation:MinLength=2
+	// +kubebuilder:validation:MaxLength=30
+	EcrRepositoryPrefix string `json:"ecrRepositoryPrefix"`
+
+	// The URL of the upstream registry, e.g. registry-1.docker.io, quay.io or ghcr.io
+	UpstreamRegistryUrl string `json:"upstreamRegistryUrl"`
+
+	// (Optional) The ARN of the Secrets Manager secret with the upstream registry credentials.
+	// The secret name must start with ecr-pullthroughcache/.
+	// +optional
+	CredentialArn string `json:"credentialArn,omitempty"`
+
+	// (Optional) The namespace to adopt the cached repositories in as Repository objects.
+	// The objects are named after the cached repository with slashes replaced by --.
+	// Adopted repositories are not deleted from ECR when the Repository object is deleted.
+	// +optional
+	AdoptionNamespace string `json:"adoptionNamespace,omitempty"`
+}
+
+// PullThroughCacheRuleStatus defines the observed state of PullThroughCacheRule
+type PullThroughCacheRuleStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The conditions of the rule, the Ready condition reports whether the rule has been created
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The registry ID the rule was created in
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// The repository name prefix of the created rule
+	// +optional
+	EcrRepositoryPrefix string `json:"ecrRepositoryPrefix,omitempty"`
+
+	// The time the rule was created
+	// +optional
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+
+	// The repositories created by the cache for pulled upstream images
+	// +optional
+	CachedRepositories []string `json:"cachedRepositories,omitempty"`
+
+	// The names of the Repository objects adopting the cached repositories in the adoption namespace
+	// +optional
+	AdoptedRepositories []string `json:"adoptedRepositories,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Prefix",type=string,JSONPath=`.spec.ecrRepositoryPrefix`
+//+kubebuilder:printcolumn:name="Upstream",type=string,JSONPath=`.spec.upstreamRegistryUrl`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PullThroughCacheRule is the Schema for the pullthroughcacherules API
+type PullThroughCacheRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PullThroughCacheRuleSpec   `json:"spec,omitempty"`
+	Status PullThroughCacheRuleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PullThroughCacheRuleList contains a list of PullThroughCacheRule
+type PullThroughCacheRuleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PullThroughCacheRule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PullThroughCacheRule{}, &PullThroughCacheRuleList{})
+}
@@ -0,0 +1,134 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// RegistryCredentialsSpec defines the desired state of RegistryCredentials
+type RegistryCredentialsSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) The name of the kubernetes.io/dockerconfigjson Secret created in the target namespaces.
+	// +kubebuilder:default=ecr-registry-credentials
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// (Optional) The names of the target namespaces.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// (Optional) Selects the target namespaces by label, in addition to the named namespaces.
+	// +optional
+	// +nullable
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// (Optional) The names of the ServiceAccounts in the target namespaces to add the Secret to as imagePullSecret.
+	// +optional
+	ServiceAccountNames []string `json:"serviceAccountNames,omitempty"`
+
+	// (Optional) The AWS account IDs of the registries to get the credentials for. Defaults to the default registry.
+	// +optional
+	RegistryIds []string `json:"registryIds,omitempty"`
+
+	// (Optional) How long before the token expires it is refreshed. ECR tokens are valid for 12 hours,
+	// longer durations are capped to 11 hours.
+	// +kubebuilder:default="6h"
+	// +optional
+	RefreshBefore metav1.Duration `json:"refreshBefore,omitempty"`
+}
+
+// RegistryCredentialsStatus defines the observed state of RegistryCredentials
+type RegistryCredentialsStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The registry endpoints the credentials are valid for
+	// +optional
+	ProxyEndpoints []string `json:"proxyEndpoints,omitempty"`
+
+	// The namespaces the Secret has been written to
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// The target namespaces with an existing Secret of the same name not created for this object,
+	// which is left untouched
+	// +optional
+	ConflictingNamespaces []string `json:"conflictingNamespaces,omitempty"`
+
+	// The name of the Secret written to the namespaces
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// The names of the ServiceAccounts the Secret has been added to as imagePullSecret
+	// +optional
+	ServiceAccountNames []string `json:"serviceAccountNames,omitempty"`
+
+	// The time the current token expires
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// The last time the token was refreshed
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+
+	// The generation of the spec the Secrets were last written for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.secretName`
+//+kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expiresAt`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RegistryCredentials is the Schema for the registrycredentials API
+type RegistryCredentials struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RegistryCredentialsSpec   `json:"spec,omitempty"`
+	Status RegistryCredentialsStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RegistryCredentialsList contains a list of RegistryCredentials
+type RegistryCredentialsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegistryCredentials `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegistryCredentials{}, &RegistryCredentialsList{})
+}
@@ -0,0 +1,108 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// RegistryPolicySpec defines the desired state of RegistryPolicy
+type RegistryPolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) The registry permissions policy JSON text. Either this or PolicyTextFrom is required.
+	// +optional
+	PolicyText string `json:"policyText,omitempty"`
+
+	// (Optional) Reference to a ConfigMap or Secret key containing the registry permissions policy JSON text.
+	// The referenced text may use the Go template variables {{.RegistryId}} and {{.Region}}.
+	// +optional
+	// +nullable
+	PolicyTextFrom *PolicyTextSource `json:"policyTextFrom,omitempty"`
+
+	// (Optional) The namespace of the ConfigMap or Secret referenced by PolicyTextFrom.
+	// Required if PolicyTextFrom is specified, since the RegistryPolicy is cluster-scoped.
+	// +optional
+	PolicyTextFromNamespace string `json:"policyTextFromNamespace,omitempty"`
+}
+
+// RegistryPolicyStatus defines the observed state of RegistryPolicy
+type RegistryPolicyStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The registry ID the policy was applied to
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// The last time the registry policy was applied
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// The last time the registry policy was changed outside of the operator
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+
+	// (Optional) Message with details in case the policy could not be applied
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The generation of the spec the registry policy was last applied for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Registry",type=string,JSONPath=`.status.registryId`
+//+kubebuilder:printcolumn:name="Last Applied",type=date,JSONPath=`.status.lastAppliedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RegistryPolicy is the Schema for the registrypolicies API
+type RegistryPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RegistryPolicySpec   `json:"spec,omitempty"`
+	Status RegistryPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RegistryPolicyList contains a list of RegistryPolicy
+type RegistryPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegistryPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegistryPolicy{}, &RegistryPolicyList{})
+}
@@ -0,0 +1,119 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// RegistryScanningConfigurationSpec defines the desired state of RegistryScanningConfiguration
+type RegistryScanningConfigurationSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) The scanning type of the registry, BASIC or ENHANCED. Defaults to BASIC.
+	// +kubebuilder:default=BASIC
+	// +kubebuilder:validation:Enum=BASIC;ENHANCED
+	// +optional
+	ScanType string `json:"scanType,omitempty"`
+
+	// (Optional) The scanning rules of the registry. If empty, the scanning configuration of the repositories applies.
+	// +optional
+	Rules []ScanningRule `json:"rules,omitempty"`
+}
+
+// ScanningRule defines the scan frequency for the repositories matching the filters
+type ScanningRule struct {
+	// The scan frequency. BASIC scanning supports SCAN_ON_PUSH and MANUAL,
+	// ENHANCED scanning supports SCAN_ON_PUSH and CONTINUOUS_SCAN.
+	// +kubebuilder:validation:Enum=SCAN_ON_PUSH;CONTINUOUS_SCAN;MANUAL
+	ScanFrequency string `json:"scanFrequency"`
+
+	// The wildcard filters matching the repository names, e.g. "prod-*" or "*".
+	// +kubebuilder:validation:MinItems=1
+	RepositoryFilters []string `json:"repositoryFilters"`
+}
+
+// RegistryScanningConfigurationStatus defines the observed state of RegistryScanningConfiguration
+type RegistryScanningConfigurationStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The registry ID the scanning configuration was applied to
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// (Optional) Message with details in case the scanning configuration is not applied
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The last time the scanning configuration was applied
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// The last time the registry scanning configuration was changed outside of the operator
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+
+	// The Repository objects whose imageScanningConfiguration is overridden by the registry rules
+	// +optional
+	OverriddenRepositories []string `json:"overriddenRepositories,omitempty"`
+
+	// The generation of the spec the scanning configuration was last applied for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Scan Type",type=string,JSONPath=`.spec.scanType`
+//+kubebuilder:printcolumn:name="Last Applied",type=date,JSONPath=`.status.lastAppliedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RegistryScanningConfiguration is the Schema for the registryscanningconfigurations API
+type RegistryScanningConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RegistryScanningConfigurationSpec   `json:"spec,omitempty"`
+	Status RegistryScanningConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RegistryScanningConfigurationList contains a list of RegistryScanningConfiguration
+type RegistryScanningConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RegistryScanningConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RegistryScanningConfiguration{}, &RegistryScanningConfigurationList{})
+}
@@ -0,0 +1,121 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// ReplicationConfigurationSpec defines the desired state of ReplicationConfiguration
+type ReplicationConfigurationSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The replication rules of the registry
+	// +kubebuilder:validation:MaxItems=10
+	Rules []ReplicationRule `json:"rules"`
+}
+
+// ReplicationRule defines the destinations the matching repositories are replicated to
+type ReplicationRule struct {
+	// The destination registries of the replication
+	// +kubebuilder:validation:MinItems=1
+	Destinations []ReplicationDestination `json:"destinations"`
+
+	// (Optional) The repository name prefixes to replicate. Replicates all repositories if empty.
+	// +optional
+	RepositoryFilters []string `json:"repositoryFilters,omitempty"`
+}
+
+// ReplicationDestination defines a destination registry of the replication
+type ReplicationDestination struct {
+	// The region to replicate to
+	Region string `json:"region"`
+
+	// The AWS account ID of the destination registry
+	// +kubebuilder:validation:Pattern=`^[0-9]{12}$`
+	RegistryId string `json:"registryId"`
+}
+
+// ReplicationConfigurationStatus defines the observed state of ReplicationConfiguration
+type ReplicationConfigurationStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The registry ID the replication configuration was applied to
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// (Optional) Message with details in case the replication configuration is not applied
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The last time the replication configuration was applied
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// The last time the registry replication configuration was changed outside of the operator
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+
+	// The Repository objects matching any of the replication rules
+	// +optional
+	ReplicatedRepositories []string `json:"replicatedRepositories,omitempty"`
+
+	// The generation of the spec the replication configuration was last applied for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Last Applied",type=date,JSONPath=`.status.lastAppliedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ReplicationConfiguration is the Schema for the replicationconfigurations API
+type ReplicationConfiguration struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ReplicationConfigurationSpec   `json:"spec,omitempty"`
+	Status ReplicationConfigurationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ReplicationConfigurationList contains a list of ReplicationConfiguration
+type ReplicationConfigurationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ReplicationConfiguration `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ReplicationConfiguration{}, &ReplicationConfigurationList{})
+}
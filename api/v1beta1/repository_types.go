@@ -36,6 +36,14 @@ type RepositorySpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
+	// (Optional) The name of the ECR repository, which may contain slashes, e.g. docker-hub/library/nginx.
+	// Defaults to the name of the Repository object. Immutable, an existing ECR repository is only managed
+	// if tagged with ecr.aws.cloud.qaware.de/owner=<namespace>/<name> of this object.
+	// +kubebuilder:validation:Pattern=`^(?:[a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*$`
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	RepositoryName string `json:"repositoryName,omitempty"`
+
 	// (Optional) The tag mutability setting for the repository.
 	// +kubebuilder:default=IMMUTABLE
 	// +kubebuilder:validation:Enum=MUTABLE;IMMUTABLE
@@ -50,6 +58,43 @@ type RepositorySpec struct {
 	// +optional
 	// +nullable
 	EncryptionConfiguration *EncryptionConfiguration `json:"encryptionConfiguration,omitempty"`
+
+	// (Optional) The cron schedule to rescan the most recently pushed images, e.g. "0 3 * * *".
+	// +optional
+	RescanSchedule string `json:"rescanSchedule,omitempty"`
+
+	// (Optional) The number of most recently pushed tagged images to rescan. Defaults to 3.
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RescanTagCount int `json:"rescanTagCount,omitempty"`
+
+	// (Optional) Tags to point at an image, mapping each tag to an image digest (sha256:...)
+	// or to a source tag, e.g. stable: 1.2.3. In IMMUTABLE repositories existing tags are not moved.
+	// +optional
+	TagAliases map[string]string `json:"tagAliases,omitempty"`
+
+	// (Optional) Protects the images in use by Pods and workload templates of the cluster from lifecycle expiry.
+	// +optional
+	// +nullable
+	InUseProtection *InUseProtection `json:"inUseProtection,omitempty"`
+}
+
+// InUseProtection defines how images in use in the cluster are protected from lifecycle expiry
+type InUseProtection struct {
+	// (Optional) Tag adds a protective tag to in-use images, which lifecycle rules can exclude.
+	// Warn reports in-use images expired by a lifecycle policy preview in the InUseImagesProtected condition.
+	// Defaults to Tag.
+	// +kubebuilder:default=Tag
+	// +kubebuilder:validation:Enum=Tag;Warn
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// (Optional) The prefix of the protective tag, followed by the first 12 characters of the digest. Defaults to inuse-.
+	// +kubebuilder:default=inuse-
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,100}$`
+	// +optional
+	TagPrefix string `json:"tagPrefix,omitempty"`
 }
 
 // The ImageTagMutability type defines MUTABLE or IMMUTABLE
@@ -91,10 +136,123 @@ type RepositoryStatus struct {
 
 	// The URI of the repository (in the form aws_account_id.dkr.ecr.region.amazonaws.com/repositoryName)
 	RepositoryUri string `json:"repositoryUri"`
+
+	// The number of images stored in the repository
+	// +optional
+	ImageCount int `json:"imageCount,omitempty"`
+
+	// The number of tagged images stored in the repository
+	// +optional
+	TaggedImageCount int `json:"taggedImageCount,omitempty"`
+
+	// The number of untagged images stored in the repository
+	// +optional
+	UntaggedImageCount int `json:"untaggedImageCount,omitempty"`
+
+	// The total size of all images in the repository in bytes
+	// +optional
+	TotalSizeInBytes int64 `json:"totalSizeInBytes,omitempty"`
+
+	// The time the most recent image was pushed to the repository
+	// +optional
+	LastPushedAt *metav1.Time `json:"lastPushedAt,omitempty"`
+
+	// The most recently pushed image tags with their digests
+	// +optional
+	LatestTags []ImageTag `json:"latestTags,omitempty"`
+
+	// The last time a scheduled rescan was started
+	// +optional
+	LastRescanTime *metav1.Time `json:"lastRescanTime,omitempty"`
+
+	// The conditions of the repository, Owned reports whether the ECR repository is managed by this object,
+	// TagAliasesApplied whether all tag aliases point to their images,
+	// InUseImagesProtected whether the images in use in the cluster are protected from lifecycle expiry
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The images of the repository in use by Pods and workload templates of the cluster
+	// +optional
+	InUseImages []InUseImage `json:"inUseImages,omitempty"`
+
+	// The signature verification results of the most recently pushed tags, if selected by an ImageVerificationPolicy
+	// +optional
+	SignatureVerifications []SignatureVerification `json:"signatureVerifications,omitempty"`
+}
+
+// SignatureVerification defines the result of verifying the signatures of an image tag
+type SignatureVerification struct {
+	// The image tag
+	Tag string `json:"tag"`
+
+	// The sha256 digest of the image manifest
+	Digest string `json:"digest"`
+
+	// Whether the image has a valid signature for each selecting ImageVerificationPolicy
+	Verified bool `json:"verified"`
+
+	// (Optional) Message with the policies the image has no valid signature for
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// The ImageVerificationPolicies the image was verified against, as name/uid/generation
+	// +optional
+	Policies []string `json:"policies,omitempty"`
+
+	// The time the signatures were verified
+	VerifiedAt metav1.Time `json:"verifiedAt"`
+}
+
+// InUseImage defines an image in use by Pods and workload templates
+type InUseImage struct {
+	// The sha256 digest of the image manifest
+	Digest string `json:"digest"`
+
+	// The workloads using the image in the form kind/namespace/name
+	// +optional
+	Workloads []string `json:"workloads,omitempty"`
+}
+
+// ImageTag defines an image tag and the digest it points to
+type ImageTag struct {
+	// The image tag
+	Tag string `json:"tag"`
+
+	// The sha256 digest of the image manifest
+	Digest string `json:"digest"`
+
+	// The time the image was pushed to the repository
+	// +optional
+	PushedAt *metav1.Time `json:"pushedAt,omitempty"`
+
+	// The replication status of the image per destination, if the repository is replicated
+	// +optional
+	ReplicationStatuses []ImageReplicationStatus `json:"replicationStatuses,omitempty"`
+}
+
+// ImageReplicationStatus defines the replication status of an image to a destination registry
+type ImageReplicationStatus struct {
+	// The destination region
+	Region string `json:"region"`
+
+	// The AWS account ID of the destination registry
+	RegistryId string `json:"registryId"`
+
+	// The replication status, IN_PROGRESS, COMPLETE or FAILED
+	Status string `json:"status"`
+
+	// The failure code of a failed replication
+	// +optional
+	FailureCode string `json:"failureCode,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="URI",type=string,JSONPath=`.status.repositoryUri`
+//+kubebuilder:printcolumn:name="Images",type=integer,JSONPath=`.status.imageCount`
+//+kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.status.totalSizeInBytes`
+//+kubebuilder:printcolumn:name="Last Push",type=date,JSONPath=`.status.lastPushedAt`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Repository is the Schema for the repositories API
 type Repository struct {
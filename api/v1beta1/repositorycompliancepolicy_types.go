@@ -0,0 +1,127 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// RepositoryCompliancePolicySpec defines the desired state of RepositoryCompliancePolicy
+type RepositoryCompliancePolicySpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) Selects the namespaces the policy applies to. Applies to all namespaces if empty.
+	// +optional
+	// +nullable
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// (Optional) The allowed encryption types. If empty, all encryption types are allowed.
+	// +optional
+	AllowedEncryptionTypes []EncryptionType `json:"allowedEncryptionTypes,omitempty"`
+
+	// (Optional) The allowed KMS keys for the KMS encryption type. If empty, all keys are allowed.
+	// +optional
+	AllowedKmsKeys []string `json:"allowedKmsKeys,omitempty"`
+
+	// (Optional) Whether images are required to be scanned after being pushed.
+	// +optional
+	RequireScanOnPush bool `json:"requireScanOnPush,omitempty"`
+
+	// (Optional) The required tag mutability setting for repositories.
+	// +kubebuilder:validation:Enum=MUTABLE;IMMUTABLE
+	// +optional
+	RequiredImageTagMutability ImageTagMutability `json:"requiredImageTagMutability,omitempty"`
+
+	// (Optional) The tag keys every repository is required to have. Repository tags are taken from the labels.
+	// +optional
+	RequiredTags []string `json:"requiredTags,omitempty"`
+}
+
+// NonCompliantRepository defines a Repository violating the compliance policy
+type NonCompliantRepository struct {
+	// The namespace of the Repository
+	Namespace string `json:"namespace"`
+
+	// The name of the Repository
+	Name string `json:"name"`
+
+	// The violations of the compliance policy
+	Violations []string `json:"violations"`
+}
+
+// RepositoryCompliancePolicyStatus defines the observed state of RepositoryCompliancePolicy
+type RepositoryCompliancePolicyStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The number of repositories the policy applies to
+	// +optional
+	RepositoryCount int `json:"repositoryCount"`
+
+	// The number of repositories violating the policy
+	// +optional
+	NonCompliantCount int `json:"nonCompliantCount"`
+
+	// The repositories violating the policy
+	// +optional
+	NonCompliantRepositories []NonCompliantRepository `json:"nonCompliantRepositories,omitempty"`
+
+	// The last time the repositories were evaluated
+	// +optional
+	LastEvaluationTime *metav1.Time `json:"lastEvaluationTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Repositories",type=integer,JSONPath=`.status.repositoryCount`
+//+kubebuilder:printcolumn:name="Non-Compliant",type=integer,JSONPath=`.status.nonCompliantCount`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RepositoryCompliancePolicy is the Schema for the repositorycompliancepolicies API
+type RepositoryCompliancePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RepositoryCompliancePolicySpec   `json:"spec,omitempty"`
+	Status RepositoryCompliancePolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RepositoryCompliancePolicyList contains a list of RepositoryCompliancePolicy
+type RepositoryCompliancePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RepositoryCompliancePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RepositoryCompliancePolicy{}, &RepositoryCompliancePolicyList{})
+}
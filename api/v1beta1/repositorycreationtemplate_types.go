@@ -0,0 +1,144 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// RepositoryCreationTemplateSpec defines the desired state of RepositoryCreationTemplate
+type RepositoryCreationTemplateSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The repository namespace prefix the template applies to, e.g. "docker-hub" for all
+	// repositories beginning with docker-hub/. Use ROOT for all repositories without a matching template.
+	// +kubebuilder:validation:Pattern=`^([a-z0-9]+(?:[._-][a-z0-9]+)*/)*[a-z0-9]+(?:[._-][a-z0-9]+)*$|^ROOT$`
+	Prefix string `json:"prefix"`
+
+	// (Optional) A description of the template.
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// The repository creation scenarios the template applies to, PULL_THROUGH_CACHE and/or REPLICATION.
+	// +kubebuilder:validation:MinItems=1
+	AppliedFor []RepositoryCreationTemplateAppliedFor `json:"appliedFor"`
+
+	// (Optional) The tag mutability setting for the created repositories. Defaults to MUTABLE.
+	// +kubebuilder:default=MUTABLE
+	// +kubebuilder:validation:Enum=MUTABLE;IMMUTABLE
+	// +optional
+	ImageTagMutability ImageTagMutability `json:"imageTagMutability,omitempty"`
+
+	// (Optional) The EncryptionConfiguration for the created repositories.
+	// +optional
+	// +nullable
+	EncryptionConfiguration *EncryptionConfiguration `json:"encryptionConfiguration,omitempty"`
+
+	// (Optional) The LifecyclePolicyText JSON text applied to the created repositories.
+	// +optional
+	LifecyclePolicyText string `json:"lifecyclePolicyText,omitempty"`
+
+	// (Optional) The RepositoryPolicy JSON text applied to the created repositories.
+	// +optional
+	RepositoryPolicyText string `json:"repositoryPolicyText,omitempty"`
+
+	// (Optional) The ARN of the IAM role assumed by ECR to create repositories, e.g. for KMS encryption
+	// or resource tags. Defaults to the service-linked role.
+	// +optional
+	CustomRoleArn string `json:"customRoleArn,omitempty"`
+
+	// (Optional) The tags applied to the created repositories.
+	// +optional
+	ResourceTags map[string]string `json:"resourceTags,omitempty"`
+}
+
+// The RepositoryCreationTemplateAppliedFor type defines PULL_THROUGH_CACHE or REPLICATION
+// +kubebuilder:validation:Enum=PULL_THROUGH_CACHE;REPLICATION
+type RepositoryCreationTemplateAppliedFor string
+
+// RepositoryCreationTemplateStatus defines the observed state of RepositoryCreationTemplate
+type RepositoryCreationTemplateStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The conditions of the template, the Ready condition reports whether the template has been applied
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// The registry ID the template was created in
+	// +optional
+	RegistryId string `json:"registryId,omitempty"`
+
+	// The prefix of the created template
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// The last time the template was applied
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+
+	// The last time the template was changed outside of the operator
+	// +optional
+	LastDriftTime *metav1.Time `json:"lastDriftTime,omitempty"`
+
+	// The generation of the spec the template was last applied for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Prefix",type=string,JSONPath=`.spec.prefix`
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Last Applied",type=date,JSONPath=`.status.lastAppliedTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// RepositoryCreationTemplate is the Schema for the repositorycreationtemplates API
+type RepositoryCreationTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RepositoryCreationTemplateSpec   `json:"spec,omitempty"`
+	Status RepositoryCreationTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RepositoryCreationTemplateList contains a list of RepositoryCreationTemplate
+type RepositoryCreationTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RepositoryCreationTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RepositoryCreationTemplate{}, &RepositoryCreationTemplateList{})
+}
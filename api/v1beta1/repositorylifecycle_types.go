@@ -36,17 +36,33 @@ type RepositoryLifecycleSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// The name of the repository to receive the policy.
-	RepositoryName string `json:"repositoryName"`
-
-	// The LifecyclePolicyText JSON text. This member is required.
-	LifecyclePolicyText string `json:"lifecyclePolicyText"`
+	// (Optional) The name of the repository to receive the lifecycle policy. Either this or RepositorySelector is required.
+	// +optional
+	RepositoryName string `json:"repositoryName,omitempty"`
+
+	// (Optional) Label selector over Repository objects in the same namespace to receive the lifecycle policy.
+	// +optional
+	// +nullable
+	RepositorySelector *metav1.LabelSelector `json:"repositorySelector,omitempty"`
+
+	// (Optional) The LifecyclePolicyText JSON text. Either this or LifecyclePolicyTextFrom is required.
+	// +optional
+	LifecyclePolicyText string `json:"lifecyclePolicyText,omitempty"`
+
+	// (Optional) Reference to a ConfigMap or Secret key containing the LifecyclePolicyText JSON text.
+	// +optional
+	// +nullable
+	LifecyclePolicyTextFrom *PolicyTextSource `json:"lifecyclePolicyTextFrom,omitempty"`
 }
 
 // RepositoryLifecycleStatus defines the observed state of RepositoryLifecycle
 type RepositoryLifecycleStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// The apply status for each target repository
+	// +optional
+	Targets []RepositoryTargetStatus `json:"targets,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -25,6 +25,7 @@ SOFTWARE.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,11 +37,23 @@ type RepositoryPolicySpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 
-	// The name of the repository to receive the policy.
-	RepositoryName string `json:"repositoryName"`
+	// (Optional) The name of the repository to receive the policy. Either this or RepositorySelector is required.
+	// +optional
+	RepositoryName string `json:"repositoryName,omitempty"`
+
+	// (Optional) Label selector over Repository objects in the same namespace to receive the policy.
+	// +optional
+	// +nullable
+	RepositorySelector *metav1.LabelSelector `json:"repositorySelector,omitempty"`
+
+	// (Optional) The RepositoryPolicy JSON text. Either this or PolicyTextFrom is required.
+	// +optional
+	PolicyText string `json:"policyText,omitempty"`
 
-	// The RepositoryPolicy JSON text. This member is required.
-	PolicyText string `json:"policyText"`
+	// (Optional) Reference to a ConfigMap or Secret key containing the RepositoryPolicy JSON text.
+	// +optional
+	// +nullable
+	PolicyTextFrom *PolicyTextSource `json:"policyTextFrom,omitempty"`
 
 	// (Optional) Whether to force the policy creation.
 	// Caution, this might prevent further changed to the repository.
@@ -49,10 +62,49 @@ type RepositoryPolicySpec struct {
 	Force bool `json:"force"`
 }
 
+// PolicyTextSource selects a policy document from a ConfigMap or Secret key
+// in the same namespace. The document may use Go template variables such as
+// {{.RepositoryArn}}, {{.RegistryId}}, {{.RepositoryUri}}, {{.Namespace}}
+// and {{.RepositoryName}}, which are resolved from the referenced Repository.
+type PolicyTextSource struct {
+	// (Optional) Selects a key of a ConfigMap.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// (Optional) Selects a key of a Secret.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// RepositoryTargetStatus defines the apply status of a policy for one target repository
+type RepositoryTargetStatus struct {
+	// The name of the target ECR repository
+	RepositoryName string `json:"repositoryName"`
+
+	// Whether the policy has been applied successfully
+	Applied bool `json:"applied"`
+
+	// (Optional) Message with details in case the policy could not be applied
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// (Optional) The last time the policy was applied successfully
+	// +optional
+	LastAppliedTime *metav1.Time `json:"lastAppliedTime,omitempty"`
+}
+
 // RepositoryPolicyStatus defines the observed state of RepositoryPolicy
 type RepositoryPolicyStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// The apply status for each target repository
+	// +optional
+	Targets []RepositoryTargetStatus `json:"targets,omitempty"`
+
+	// The violations of RepositoryPolicyConstraints preventing the policy from being applied
+	// +optional
+	Violations []string `json:"violations,omitempty"`
 }
 
 //+kubebuilder:object:root=true
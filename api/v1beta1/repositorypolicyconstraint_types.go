@@ -0,0 +1,92 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// RepositoryPolicyConstraintSpec defines the desired state of RepositoryPolicyConstraint
+type RepositoryPolicyConstraintSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// (Optional) Principals that must not be granted access by any Allow statement, e.g. "*" or
+	// "arn:aws:iam::*:root". The wildcards * and ? may be used, a wildcard principal or NotPrincipal
+	// in a statement matches every forbidden principal.
+	// +optional
+	ForbiddenPrincipals []string `json:"forbiddenPrincipals,omitempty"`
+
+	// (Optional) The AWS account IDs principals are allowed to belong to.
+	// If empty, principals of all accounts are allowed. Service principals and wildcards
+	// do not belong to an allowed account.
+	// +optional
+	AllowedAccountIds []string `json:"allowedAccountIds,omitempty"`
+
+	// (Optional) Actions that must not be granted to principals outside the allowed accounts,
+	// e.g. "ecr:DeleteRepository" or "ecr:Delete*". If no allowed accounts are specified, the actions
+	// must not be granted to any principal. A NotAction grants every action it does not list.
+	// +optional
+	ForbiddenActions []string `json:"forbiddenActions,omitempty"`
+
+	// (Optional) Condition keys every Allow statement must contain, e.g. "aws:PrincipalOrgID".
+	// +optional
+	RequiredConditions []string `json:"requiredConditions,omitempty"`
+}
+
+// RepositoryPolicyConstraintStatus defines the observed state of RepositoryPolicyConstraint
+type RepositoryPolicyConstraintStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+
+// RepositoryPolicyConstraint is the Schema for the repositorypolicyconstraints API
+type RepositoryPolicyConstraint struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RepositoryPolicyConstraintSpec   `json:"spec,omitempty"`
+	Status RepositoryPolicyConstraintStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RepositoryPolicyConstraintList contains a list of RepositoryPolicyConstraint
+type RepositoryPolicyConstraintList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RepositoryPolicyConstraint `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RepositoryPolicyConstraint{}, &RepositoryPolicyConstraintList{})
+}
@@ -0,0 +1,90 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// VulnerabilityExceptionSpec defines the desired state of VulnerabilityException
+type VulnerabilityExceptionSpec struct {
+	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+
+	// The name of the ECR repository the accepted risk applies to
+	RepositoryName string `json:"repositoryName"`
+
+	// (Optional) The image tags the accepted risk applies to. Applies to all images if empty.
+	// +optional
+	ImageTags []string `json:"imageTags,omitempty"`
+
+	// The accepted vulnerabilities, usually CVE numbers
+	// +kubebuilder:validation:MinItems=1
+	VulnerabilityIDs []string `json:"vulnerabilityIDs"`
+
+	// The time the accepted risk expires
+	ExpiresAt metav1.Time `json:"expiresAt"`
+
+	// (Optional) The reason the risk has been accepted
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// VulnerabilityExceptionStatus defines the observed state of VulnerabilityException
+type VulnerabilityExceptionStatus struct {
+	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
+	// Important: Run "make" to regenerate code after modifying this file
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repositoryName`
+//+kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.spec.expiresAt`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// VulnerabilityException is the Schema for the vulnerabilityexceptions API
+type VulnerabilityException struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VulnerabilityExceptionSpec   `json:"spec,omitempty"`
+	Status VulnerabilityExceptionStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VulnerabilityExceptionList contains a list of VulnerabilityException
+type VulnerabilityExceptionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VulnerabilityException `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VulnerabilityException{}, &VulnerabilityExceptionList{})
+}
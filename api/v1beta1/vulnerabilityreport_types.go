@@ -0,0 +1,160 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+// VulnerabilityReportData defines the scan result of an image, close to the trivy-operator report schema
+type VulnerabilityReportData struct {
+	// The time of the last completed image scan
+	UpdateTimestamp metav1.Time `json:"updateTimestamp"`
+
+	// The scanner that produced the findings
+	Scanner ReportScanner `json:"scanner"`
+
+	// The registry the scanned image is stored in
+	Registry ReportRegistry `json:"registry"`
+
+	// The scanned image
+	Artifact ReportArtifact `json:"artifact"`
+
+	// The number of findings by severity
+	Summary VulnerabilitySummary `json:"summary"`
+
+	// The top findings of the scan, ordered by severity
+	// +optional
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// ReportScanner defines the scanner that produced a report
+type ReportScanner struct {
+	// The name of the scanner
+	Name string `json:"name"`
+
+	// The vendor of the scanner
+	Vendor string `json:"vendor"`
+
+	// The version of the scanner
+	// +optional
+	Version string `json:"version,omitempty"`
+}
+
+// ReportRegistry defines the registry of a scanned image
+type ReportRegistry struct {
+	// The registry server, e.g. aws_account_id.dkr.ecr.region.amazonaws.com
+	Server string `json:"server"`
+}
+
+// ReportArtifact defines a scanned image
+type ReportArtifact struct {
+	// The name of the repository
+	Repository string `json:"repository"`
+
+	// The image tag
+	// +optional
+	Tag string `json:"tag,omitempty"`
+
+	// The sha256 digest of the image manifest
+	Digest string `json:"digest"`
+}
+
+// VulnerabilitySummary defines the number of findings by severity
+type VulnerabilitySummary struct {
+	CriticalCount int `json:"criticalCount"`
+	HighCount     int `json:"highCount"`
+	MediumCount   int `json:"mediumCount"`
+	LowCount      int `json:"lowCount"`
+	NoneCount     int `json:"noneCount"`
+	UnknownCount  int `json:"unknownCount"`
+
+	// The number of enhanced scanning findings that have not been triaged yet
+	// +optional
+	UntriagedCount int `json:"untriagedCount,omitempty"`
+}
+
+// Vulnerability defines a single finding of an image scan
+type Vulnerability struct {
+	// The ID of the vulnerability, usually a CVE number
+	VulnerabilityID string `json:"vulnerabilityID"`
+
+	// The name of the affected package
+	// +optional
+	Resource string `json:"resource,omitempty"`
+
+	// The installed version of the affected package
+	// +optional
+	InstalledVersion string `json:"installedVersion,omitempty"`
+
+	// The version of the package the vulnerability is fixed in
+	// +optional
+	FixedVersion string `json:"fixedVersion,omitempty"`
+
+	// The severity of the finding as reported by ECR
+	Severity string `json:"severity"`
+
+	// The description of the finding
+	// +optional
+	Title string `json:"title,omitempty"`
+
+	// A link containing additional details about the vulnerability
+	// +optional
+	PrimaryLink string `json:"primaryLink,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=vuln;vulns
+//+kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.report.artifact.repository`
+//+kubebuilder:printcolumn:name="Tag",type=string,JSONPath=`.report.artifact.tag`
+//+kubebuilder:printcolumn:name="Critical",type=integer,JSONPath=`.report.summary.criticalCount`
+//+kubebuilder:printcolumn:name="High",type=integer,JSONPath=`.report.summary.highCount`
+//+kubebuilder:printcolumn:name="Medium",type=integer,JSONPath=`.report.summary.mediumCount`
+//+kubebuilder:printcolumn:name="Low",type=integer,JSONPath=`.report.summary.lowCount`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// VulnerabilityReport is the Schema for the vulnerabilityreports API
+type VulnerabilityReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Report VulnerabilityReportData `json:"report"`
+}
+
+//+kubebuilder:object:root=true
+
+// VulnerabilityReportList contains a list of VulnerabilityReport
+type VulnerabilityReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VulnerabilityReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VulnerabilityReport{}, &VulnerabilityReportList{})
+}
@@ -29,9 +29,50 @@ SOFTWARE.
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateIdentity) DeepCopyInto(out *CertificateIdentity) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateIdentity.
+func (in *CertificateIdentity) DeepCopy() *CertificateIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CleanupCandidate) DeepCopyInto(out *CleanupCandidate) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PushedAt != nil {
+		in, out := &in.PushedAt, &out.PushedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CleanupCandidate.
+func (in *CleanupCandidate) DeepCopy() *CleanupCandidate {
+	if in == nil {
+		return nil
+	}
+	out := new(CleanupCandidate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EncryptionConfiguration) DeepCopyInto(out *EncryptionConfiguration) {
 	*out = *in
@@ -53,41 +94,58 @@ func (in *EncryptionConfiguration) DeepCopy() *EncryptionConfiguration {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ImageScanningConfiguration) DeepCopyInto(out *ImageScanningConfiguration) {
+func (in *ImageCleanupPolicy) DeepCopyInto(out *ImageCleanupPolicy) {
 	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanningConfiguration.
-func (in *ImageScanningConfiguration) DeepCopy() *ImageScanningConfiguration {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCleanupPolicy.
+func (in *ImageCleanupPolicy) DeepCopy() *ImageCleanupPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(ImageScanningConfiguration)
+	out := new(ImageCleanupPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageCleanupPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Repository) DeepCopyInto(out *Repository) {
+func (in *ImageCleanupPolicyList) DeepCopyInto(out *ImageCleanupPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageCleanupPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Repository.
-func (in *Repository) DeepCopy() *Repository {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCleanupPolicyList.
+func (in *ImageCleanupPolicyList) DeepCopy() *ImageCleanupPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(Repository)
+	out := new(ImageCleanupPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Repository) DeepCopyObject() runtime.Object {
+func (in *ImageCleanupPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -95,26 +153,94 @@ func (in *Repository) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryLifecycle) DeepCopyInto(out *RepositoryLifecycle) {
+func (in *ImageCleanupPolicySpec) DeepCopyInto(out *ImageCleanupPolicySpec) {
+	*out = *in
+	if in.MinAge != nil {
+		in, out := &in.MinAge, &out.MinAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TagPatterns != nil {
+		in, out := &in.TagPatterns, &out.TagPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeepTagPatterns != nil {
+		in, out := &in.KeepTagPatterns, &out.KeepTagPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemoteClusters != nil {
+		in, out := &in.RemoteClusters, &out.RemoteClusters
+		*out = make([]corev1.SecretKeySelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCleanupPolicySpec.
+func (in *ImageCleanupPolicySpec) DeepCopy() *ImageCleanupPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCleanupPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCleanupPolicyStatus) DeepCopyInto(out *ImageCleanupPolicyStatus) {
+	*out = *in
+	if in.LastRunTime != nil {
+		in, out := &in.LastRunTime, &out.LastRunTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Candidates != nil {
+		in, out := &in.Candidates, &out.Candidates
+		*out = make([]CleanupCandidate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCleanupPolicyStatus.
+func (in *ImageCleanupPolicyStatus) DeepCopy() *ImageCleanupPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCleanupPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageInventory) DeepCopyInto(out *ImageInventory) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycle.
-func (in *RepositoryLifecycle) DeepCopy() *RepositoryLifecycle {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageInventory.
+func (in *ImageInventory) DeepCopy() *ImageInventory {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryLifecycle)
+	out := new(ImageInventory)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RepositoryLifecycle) DeepCopyObject() runtime.Object {
+func (in *ImageInventory) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -122,31 +248,31 @@ func (in *RepositoryLifecycle) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryLifecycleList) DeepCopyInto(out *RepositoryLifecycleList) {
+func (in *ImageInventoryList) DeepCopyInto(out *ImageInventoryList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]RepositoryLifecycle, len(*in))
+		*out = make([]ImageInventory, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycleList.
-func (in *RepositoryLifecycleList) DeepCopy() *RepositoryLifecycleList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageInventoryList.
+func (in *ImageInventoryList) DeepCopy() *ImageInventoryList {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryLifecycleList)
+	out := new(ImageInventoryList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RepositoryLifecycleList) DeepCopyObject() runtime.Object {
+func (in *ImageInventoryList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -154,61 +280,124 @@ func (in *RepositoryLifecycleList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryLifecycleSpec) DeepCopyInto(out *RepositoryLifecycleSpec) {
+func (in *ImageInventorySpec) DeepCopyInto(out *ImageInventorySpec) {
 	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycleSpec.
-func (in *RepositoryLifecycleSpec) DeepCopy() *RepositoryLifecycleSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageInventorySpec.
+func (in *ImageInventorySpec) DeepCopy() *ImageInventorySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryLifecycleSpec)
+	out := new(ImageInventorySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryLifecycleStatus) DeepCopyInto(out *RepositoryLifecycleStatus) {
+func (in *ImageInventoryStatus) DeepCopyInto(out *ImageInventoryStatus) {
 	*out = *in
+	if in.LastUpdatedTime != nil {
+		in, out := &in.LastUpdatedTime, &out.LastUpdatedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]InventoryImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycleStatus.
-func (in *RepositoryLifecycleStatus) DeepCopy() *RepositoryLifecycleStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageInventoryStatus.
+func (in *ImageInventoryStatus) DeepCopy() *ImageInventoryStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryLifecycleStatus)
+	out := new(ImageInventoryStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryList) DeepCopyInto(out *RepositoryList) {
+func (in *ImagePromotion) DeepCopyInto(out *ImagePromotion) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotion.
+func (in *ImagePromotion) DeepCopy() *ImagePromotion {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImagePromotion) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionDestination) DeepCopyInto(out *ImagePromotionDestination) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionDestination.
+func (in *ImagePromotionDestination) DeepCopy() *ImagePromotionDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePromotionDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionList) DeepCopyInto(out *ImagePromotionList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Repository, len(*in))
+		*out = make([]ImagePromotion, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryList.
-func (in *RepositoryList) DeepCopy() *RepositoryList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionList.
+func (in *ImagePromotionList) DeepCopy() *ImagePromotionList {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryList)
+	out := new(ImagePromotionList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RepositoryList) DeepCopyObject() runtime.Object {
+func (in *ImagePromotionList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -216,26 +405,25 @@ func (in *RepositoryList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryPolicy) DeepCopyInto(out *RepositoryPolicy) {
+func (in *ImagePromotionPolicy) DeepCopyInto(out *ImagePromotionPolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicy.
-func (in *RepositoryPolicy) DeepCopy() *RepositoryPolicy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionPolicy.
+func (in *ImagePromotionPolicy) DeepCopy() *ImagePromotionPolicy {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryPolicy)
+	out := new(ImagePromotionPolicy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RepositoryPolicy) DeepCopyObject() runtime.Object {
+func (in *ImagePromotionPolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -243,31 +431,31 @@ func (in *RepositoryPolicy) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryPolicyList) DeepCopyInto(out *RepositoryPolicyList) {
+func (in *ImagePromotionPolicyList) DeepCopyInto(out *ImagePromotionPolicyList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]RepositoryPolicy, len(*in))
+		*out = make([]ImagePromotionPolicy, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyList.
-func (in *RepositoryPolicyList) DeepCopy() *RepositoryPolicyList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionPolicyList.
+func (in *ImagePromotionPolicyList) DeepCopy() *ImagePromotionPolicyList {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryPolicyList)
+	out := new(ImagePromotionPolicyList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RepositoryPolicyList) DeepCopyObject() runtime.Object {
+func (in *ImagePromotionPolicyList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -275,71 +463,2545 @@ func (in *RepositoryPolicyList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryPolicySpec) DeepCopyInto(out *RepositoryPolicySpec) {
+func (in *ImagePromotionPolicySpec) DeepCopyInto(out *ImagePromotionPolicySpec) {
 	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make([]PromotionRepository, len(*in))
+		copy(*out, *in)
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]PromotionRepository, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicySpec.
-func (in *RepositoryPolicySpec) DeepCopy() *RepositoryPolicySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionPolicySpec.
+func (in *ImagePromotionPolicySpec) DeepCopy() *ImagePromotionPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryPolicySpec)
+	out := new(ImagePromotionPolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryPolicyStatus) DeepCopyInto(out *RepositoryPolicyStatus) {
+func (in *ImagePromotionSource) DeepCopyInto(out *ImagePromotionSource) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyStatus.
-func (in *RepositoryPolicyStatus) DeepCopy() *RepositoryPolicyStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionSource.
+func (in *ImagePromotionSource) DeepCopy() *ImagePromotionSource {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryPolicyStatus)
+	out := new(ImagePromotionSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
+func (in *ImagePromotionSpec) DeepCopyInto(out *ImagePromotionSpec) {
 	*out = *in
-	if in.ImageScanningConfiguration != nil {
-		in, out := &in.ImageScanningConfiguration, &out.ImageScanningConfiguration
-		*out = new(ImageScanningConfiguration)
-		**out = **in
+	out.Source = in.Source
+	in.Destination.DeepCopyInto(&out.Destination)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionSpec.
+func (in *ImagePromotionSpec) DeepCopy() *ImagePromotionSpec {
+	if in == nil {
+		return nil
 	}
-	if in.EncryptionConfiguration != nil {
-		in, out := &in.EncryptionConfiguration, &out.EncryptionConfiguration
-		*out = new(EncryptionConfiguration)
-		(*in).DeepCopyInto(*out)
+	out := new(ImagePromotionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePromotionStatus) DeepCopyInto(out *ImagePromotionStatus) {
+	*out = *in
+	if in.PromotedAt != nil {
+		in, out := &in.PromotedAt, &out.PromotedAt
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySpec.
-func (in *RepositorySpec) DeepCopy() *RepositorySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePromotionStatus.
+func (in *ImagePromotionStatus) DeepCopy() *ImagePromotionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositorySpec)
+	out := new(ImagePromotionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RepositoryStatus) DeepCopyInto(out *RepositoryStatus) {
+func (in *ImageReplicationStatus) DeepCopyInto(out *ImageReplicationStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStatus.
-func (in *RepositoryStatus) DeepCopy() *RepositoryStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageReplicationStatus.
+func (in *ImageReplicationStatus) DeepCopy() *ImageReplicationStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(RepositoryStatus)
+	out := new(ImageReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScan) DeepCopyInto(out *ImageScan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScan.
+func (in *ImageScan) DeepCopy() *ImageScan {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageScan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanList) DeepCopyInto(out *ImageScanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageScan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanList.
+func (in *ImageScanList) DeepCopy() *ImageScanList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageScanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanSpec) DeepCopyInto(out *ImageScanSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanSpec.
+func (in *ImageScanSpec) DeepCopy() *ImageScanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanStatus) DeepCopyInto(out *ImageScanStatus) {
+	*out = *in
+	if in.StartedAt != nil {
+		in, out := &in.StartedAt, &out.StartedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedAt != nil {
+		in, out := &in.CompletedAt, &out.CompletedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(VulnerabilitySummary)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanStatus.
+func (in *ImageScanStatus) DeepCopy() *ImageScanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageScanningConfiguration) DeepCopyInto(out *ImageScanningConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageScanningConfiguration.
+func (in *ImageScanningConfiguration) DeepCopy() *ImageScanningConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageScanningConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageTag) DeepCopyInto(out *ImageTag) {
+	*out = *in
+	if in.PushedAt != nil {
+		in, out := &in.PushedAt, &out.PushedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ReplicationStatuses != nil {
+		in, out := &in.ReplicationStatuses, &out.ReplicationStatuses
+		*out = make([]ImageReplicationStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTag.
+func (in *ImageTag) DeepCopy() *ImageTag {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageTag)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageTagPolicy) DeepCopyInto(out *ImageTagPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageTagPolicy.
+func (in *ImageTagPolicy) DeepCopy() *ImageTagPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageTagPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdatePolicy) DeepCopyInto(out *ImageUpdatePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdatePolicy.
+func (in *ImageUpdatePolicy) DeepCopy() *ImageUpdatePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdatePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageUpdatePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdatePolicyList) DeepCopyInto(out *ImageUpdatePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageUpdatePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdatePolicyList.
+func (in *ImageUpdatePolicyList) DeepCopy() *ImageUpdatePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdatePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageUpdatePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdatePolicySpec) DeepCopyInto(out *ImageUpdatePolicySpec) {
+	*out = *in
+	out.TagPolicy = in.TagPolicy
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]ImageUpdateTarget, len(*in))
+		copy(*out, *in)
+	}
+	if in.ScanGate != nil {
+		in, out := &in.ScanGate, &out.ScanGate
+		*out = new(ImageUpdateScanGate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdatePolicySpec.
+func (in *ImageUpdatePolicySpec) DeepCopy() *ImageUpdatePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdatePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdatePolicyStatus) DeepCopyInto(out *ImageUpdatePolicyStatus) {
+	*out = *in
+	if in.LastCheckedTime != nil {
+		in, out := &in.LastCheckedTime, &out.LastCheckedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ImageUpdateRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdatePolicyStatus.
+func (in *ImageUpdatePolicyStatus) DeepCopy() *ImageUpdatePolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdatePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdateRecord) DeepCopyInto(out *ImageUpdateRecord) {
+	*out = *in
+	in.UpdatedAt.DeepCopyInto(&out.UpdatedAt)
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdateRecord.
+func (in *ImageUpdateRecord) DeepCopy() *ImageUpdateRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdateRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdateScanGate) DeepCopyInto(out *ImageUpdateScanGate) {
+	*out = *in
+	if in.MaxCount != nil {
+		in, out := &in.MaxCount, &out.MaxCount
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdateScanGate.
+func (in *ImageUpdateScanGate) DeepCopy() *ImageUpdateScanGate {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdateScanGate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageUpdateTarget) DeepCopyInto(out *ImageUpdateTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageUpdateTarget.
+func (in *ImageUpdateTarget) DeepCopy() *ImageUpdateTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageUpdateTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVerificationPolicy) DeepCopyInto(out *ImageVerificationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVerificationPolicy.
+func (in *ImageVerificationPolicy) DeepCopy() *ImageVerificationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVerificationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageVerificationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVerificationPolicyList) DeepCopyInto(out *ImageVerificationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageVerificationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVerificationPolicyList.
+func (in *ImageVerificationPolicyList) DeepCopy() *ImageVerificationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVerificationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageVerificationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVerificationPolicySpec) DeepCopyInto(out *ImageVerificationPolicySpec) {
+	*out = *in
+	if in.RepositorySelector != nil {
+		in, out := &in.RepositorySelector, &out.RepositorySelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PublicKeys != nil {
+		in, out := &in.PublicKeys, &out.PublicKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CertificateIdentities != nil {
+		in, out := &in.CertificateIdentities, &out.CertificateIdentities
+		*out = make([]CertificateIdentity, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVerificationPolicySpec.
+func (in *ImageVerificationPolicySpec) DeepCopy() *ImageVerificationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVerificationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVerificationPolicyStatus) DeepCopyInto(out *ImageVerificationPolicyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVerificationPolicyStatus.
+func (in *ImageVerificationPolicyStatus) DeepCopy() *ImageVerificationPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVerificationPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVulnerabilityPolicy) DeepCopyInto(out *ImageVulnerabilityPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVulnerabilityPolicy.
+func (in *ImageVulnerabilityPolicy) DeepCopy() *ImageVulnerabilityPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVulnerabilityPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageVulnerabilityPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVulnerabilityPolicyList) DeepCopyInto(out *ImageVulnerabilityPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ImageVulnerabilityPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVulnerabilityPolicyList.
+func (in *ImageVulnerabilityPolicyList) DeepCopy() *ImageVulnerabilityPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVulnerabilityPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ImageVulnerabilityPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVulnerabilityPolicySpec) DeepCopyInto(out *ImageVulnerabilityPolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxCount != nil {
+		in, out := &in.MaxCount, &out.MaxCount
+		*out = new(int)
+		**out = **in
+	}
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVulnerabilityPolicySpec.
+func (in *ImageVulnerabilityPolicySpec) DeepCopy() *ImageVulnerabilityPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVulnerabilityPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageVulnerabilityPolicyStatus) DeepCopyInto(out *ImageVulnerabilityPolicyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageVulnerabilityPolicyStatus.
+func (in *ImageVulnerabilityPolicyStatus) DeepCopy() *ImageVulnerabilityPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageVulnerabilityPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InUseImage) DeepCopyInto(out *InUseImage) {
+	*out = *in
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InUseImage.
+func (in *InUseImage) DeepCopy() *InUseImage {
+	if in == nil {
+		return nil
+	}
+	out := new(InUseImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InUseProtection) DeepCopyInto(out *InUseProtection) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InUseProtection.
+func (in *InUseProtection) DeepCopy() *InUseProtection {
+	if in == nil {
+		return nil
+	}
+	out := new(InUseProtection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryImage) DeepCopyInto(out *InventoryImage) {
+	*out = *in
+	if in.PushedAt != nil {
+		in, out := &in.PushedAt, &out.PushedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = new(VulnerabilitySummary)
+		**out = **in
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = make([]InventoryWorkload, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryImage.
+func (in *InventoryImage) DeepCopy() *InventoryImage {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InventoryWorkload) DeepCopyInto(out *InventoryWorkload) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InventoryWorkload.
+func (in *InventoryWorkload) DeepCopy() *InventoryWorkload {
+	if in == nil {
+		return nil
+	}
+	out := new(InventoryWorkload)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NonCompliantRepository) DeepCopyInto(out *NonCompliantRepository) {
+	*out = *in
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NonCompliantRepository.
+func (in *NonCompliantRepository) DeepCopy() *NonCompliantRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(NonCompliantRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyTextSource) DeepCopyInto(out *PolicyTextSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyTextSource.
+func (in *PolicyTextSource) DeepCopy() *PolicyTextSource {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyTextSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromotionRepository) DeepCopyInto(out *PromotionRepository) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromotionRepository.
+func (in *PromotionRepository) DeepCopy() *PromotionRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(PromotionRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublicRepository) DeepCopyInto(out *PublicRepository) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicRepository.
+func (in *PublicRepository) DeepCopy() *PublicRepository {
+	if in == nil {
+		return nil
+	}
+	out := new(PublicRepository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PublicRepository) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublicRepositoryCatalogData) DeepCopyInto(out *PublicRepositoryCatalogData) {
+	*out = *in
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OperatingSystems != nil {
+		in, out := &in.OperatingSystems, &out.OperatingSystems
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogoImageBlob != nil {
+		in, out := &in.LogoImageBlob, &out.LogoImageBlob
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicRepositoryCatalogData.
+func (in *PublicRepositoryCatalogData) DeepCopy() *PublicRepositoryCatalogData {
+	if in == nil {
+		return nil
+	}
+	out := new(PublicRepositoryCatalogData)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublicRepositoryList) DeepCopyInto(out *PublicRepositoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PublicRepository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicRepositoryList.
+func (in *PublicRepositoryList) DeepCopy() *PublicRepositoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(PublicRepositoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PublicRepositoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublicRepositorySpec) DeepCopyInto(out *PublicRepositorySpec) {
+	*out = *in
+	if in.CatalogData != nil {
+		in, out := &in.CatalogData, &out.CatalogData
+		*out = new(PublicRepositoryCatalogData)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PolicyTextFrom != nil {
+		in, out := &in.PolicyTextFrom, &out.PolicyTextFrom
+		*out = new(PolicyTextSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicRepositorySpec.
+func (in *PublicRepositorySpec) DeepCopy() *PublicRepositorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PublicRepositorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublicRepositoryStatus) DeepCopyInto(out *PublicRepositoryStatus) {
+	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicRepositoryStatus.
+func (in *PublicRepositoryStatus) DeepCopy() *PublicRepositoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PublicRepositoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullThroughCacheRule) DeepCopyInto(out *PullThroughCacheRule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullThroughCacheRule.
+func (in *PullThroughCacheRule) DeepCopy() *PullThroughCacheRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PullThroughCacheRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PullThroughCacheRule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullThroughCacheRuleList) DeepCopyInto(out *PullThroughCacheRuleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PullThroughCacheRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullThroughCacheRuleList.
+func (in *PullThroughCacheRuleList) DeepCopy() *PullThroughCacheRuleList {
+	if in == nil {
+		return nil
+	}
+	out := new(PullThroughCacheRuleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PullThroughCacheRuleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullThroughCacheRuleSpec) DeepCopyInto(out *PullThroughCacheRuleSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullThroughCacheRuleSpec.
+func (in *PullThroughCacheRuleSpec) DeepCopy() *PullThroughCacheRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PullThroughCacheRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullThroughCacheRuleStatus) DeepCopyInto(out *PullThroughCacheRuleStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.CachedRepositories != nil {
+		in, out := &in.CachedRepositories, &out.CachedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AdoptedRepositories != nil {
+		in, out := &in.AdoptedRepositories, &out.AdoptedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullThroughCacheRuleStatus.
+func (in *PullThroughCacheRuleStatus) DeepCopy() *PullThroughCacheRuleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PullThroughCacheRuleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredentials) DeepCopyInto(out *RegistryCredentials) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredentials.
+func (in *RegistryCredentials) DeepCopy() *RegistryCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryCredentials) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredentialsList) DeepCopyInto(out *RegistryCredentialsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RegistryCredentials, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredentialsList.
+func (in *RegistryCredentialsList) DeepCopy() *RegistryCredentialsList {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredentialsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryCredentialsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredentialsSpec) DeepCopyInto(out *RegistryCredentialsSpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccountNames != nil {
+		in, out := &in.ServiceAccountNames, &out.ServiceAccountNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RegistryIds != nil {
+		in, out := &in.RegistryIds, &out.RegistryIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.RefreshBefore = in.RefreshBefore
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredentialsSpec.
+func (in *RegistryCredentialsSpec) DeepCopy() *RegistryCredentialsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredentialsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryCredentialsStatus) DeepCopyInto(out *RegistryCredentialsStatus) {
+	*out = *in
+	if in.ProxyEndpoints != nil {
+		in, out := &in.ProxyEndpoints, &out.ProxyEndpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ConflictingNamespaces != nil {
+		in, out := &in.ConflictingNamespaces, &out.ConflictingNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ServiceAccountNames != nil {
+		in, out := &in.ServiceAccountNames, &out.ServiceAccountNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRefreshTime != nil {
+		in, out := &in.LastRefreshTime, &out.LastRefreshTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryCredentialsStatus.
+func (in *RegistryCredentialsStatus) DeepCopy() *RegistryCredentialsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryCredentialsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryPolicy) DeepCopyInto(out *RegistryPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPolicy.
+func (in *RegistryPolicy) DeepCopy() *RegistryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryPolicyList) DeepCopyInto(out *RegistryPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RegistryPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPolicyList.
+func (in *RegistryPolicyList) DeepCopy() *RegistryPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryPolicySpec) DeepCopyInto(out *RegistryPolicySpec) {
+	*out = *in
+	if in.PolicyTextFrom != nil {
+		in, out := &in.PolicyTextFrom, &out.PolicyTextFrom
+		*out = new(PolicyTextSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPolicySpec.
+func (in *RegistryPolicySpec) DeepCopy() *RegistryPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryPolicyStatus) DeepCopyInto(out *RegistryPolicyStatus) {
+	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryPolicyStatus.
+func (in *RegistryPolicyStatus) DeepCopy() *RegistryPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryScanningConfiguration) DeepCopyInto(out *RegistryScanningConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryScanningConfiguration.
+func (in *RegistryScanningConfiguration) DeepCopy() *RegistryScanningConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryScanningConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryScanningConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryScanningConfigurationList) DeepCopyInto(out *RegistryScanningConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RegistryScanningConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryScanningConfigurationList.
+func (in *RegistryScanningConfigurationList) DeepCopy() *RegistryScanningConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryScanningConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RegistryScanningConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryScanningConfigurationSpec) DeepCopyInto(out *RegistryScanningConfigurationSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ScanningRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryScanningConfigurationSpec.
+func (in *RegistryScanningConfigurationSpec) DeepCopy() *RegistryScanningConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryScanningConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RegistryScanningConfigurationStatus) DeepCopyInto(out *RegistryScanningConfigurationStatus) {
+	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
+	if in.OverriddenRepositories != nil {
+		in, out := &in.OverriddenRepositories, &out.OverriddenRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RegistryScanningConfigurationStatus.
+func (in *RegistryScanningConfigurationStatus) DeepCopy() *RegistryScanningConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RegistryScanningConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationConfiguration) DeepCopyInto(out *ReplicationConfiguration) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationConfiguration.
+func (in *ReplicationConfiguration) DeepCopy() *ReplicationConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReplicationConfiguration) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationConfigurationList) DeepCopyInto(out *ReplicationConfigurationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ReplicationConfiguration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationConfigurationList.
+func (in *ReplicationConfigurationList) DeepCopy() *ReplicationConfigurationList {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationConfigurationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ReplicationConfigurationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationConfigurationSpec) DeepCopyInto(out *ReplicationConfigurationSpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ReplicationRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationConfigurationSpec.
+func (in *ReplicationConfigurationSpec) DeepCopy() *ReplicationConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationConfigurationStatus) DeepCopyInto(out *ReplicationConfigurationStatus) {
+	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ReplicatedRepositories != nil {
+		in, out := &in.ReplicatedRepositories, &out.ReplicatedRepositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationConfigurationStatus.
+func (in *ReplicationConfigurationStatus) DeepCopy() *ReplicationConfigurationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationConfigurationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationDestination) DeepCopyInto(out *ReplicationDestination) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationDestination.
+func (in *ReplicationDestination) DeepCopy() *ReplicationDestination {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationDestination)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationRule) DeepCopyInto(out *ReplicationRule) {
+	*out = *in
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]ReplicationDestination, len(*in))
+		copy(*out, *in)
+	}
+	if in.RepositoryFilters != nil {
+		in, out := &in.RepositoryFilters, &out.RepositoryFilters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationRule.
+func (in *ReplicationRule) DeepCopy() *ReplicationRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportArtifact) DeepCopyInto(out *ReportArtifact) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportArtifact.
+func (in *ReportArtifact) DeepCopy() *ReportArtifact {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportArtifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportRegistry) DeepCopyInto(out *ReportRegistry) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportRegistry.
+func (in *ReportRegistry) DeepCopy() *ReportRegistry {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportRegistry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReportScanner) DeepCopyInto(out *ReportScanner) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReportScanner.
+func (in *ReportScanner) DeepCopy() *ReportScanner {
+	if in == nil {
+		return nil
+	}
+	out := new(ReportScanner)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Repository) DeepCopyInto(out *Repository) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Repository.
+func (in *Repository) DeepCopy() *Repository {
+	if in == nil {
+		return nil
+	}
+	out := new(Repository)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Repository) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCompliancePolicy) DeepCopyInto(out *RepositoryCompliancePolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCompliancePolicy.
+func (in *RepositoryCompliancePolicy) DeepCopy() *RepositoryCompliancePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCompliancePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryCompliancePolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCompliancePolicyList) DeepCopyInto(out *RepositoryCompliancePolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RepositoryCompliancePolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCompliancePolicyList.
+func (in *RepositoryCompliancePolicyList) DeepCopy() *RepositoryCompliancePolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCompliancePolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryCompliancePolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCompliancePolicySpec) DeepCopyInto(out *RepositoryCompliancePolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedEncryptionTypes != nil {
+		in, out := &in.AllowedEncryptionTypes, &out.AllowedEncryptionTypes
+		*out = make([]EncryptionType, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedKmsKeys != nil {
+		in, out := &in.AllowedKmsKeys, &out.AllowedKmsKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredTags != nil {
+		in, out := &in.RequiredTags, &out.RequiredTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCompliancePolicySpec.
+func (in *RepositoryCompliancePolicySpec) DeepCopy() *RepositoryCompliancePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCompliancePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCompliancePolicyStatus) DeepCopyInto(out *RepositoryCompliancePolicyStatus) {
+	*out = *in
+	if in.NonCompliantRepositories != nil {
+		in, out := &in.NonCompliantRepositories, &out.NonCompliantRepositories
+		*out = make([]NonCompliantRepository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastEvaluationTime != nil {
+		in, out := &in.LastEvaluationTime, &out.LastEvaluationTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCompliancePolicyStatus.
+func (in *RepositoryCompliancePolicyStatus) DeepCopy() *RepositoryCompliancePolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCompliancePolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCreationTemplate) DeepCopyInto(out *RepositoryCreationTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCreationTemplate.
+func (in *RepositoryCreationTemplate) DeepCopy() *RepositoryCreationTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCreationTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryCreationTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCreationTemplateList) DeepCopyInto(out *RepositoryCreationTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RepositoryCreationTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCreationTemplateList.
+func (in *RepositoryCreationTemplateList) DeepCopy() *RepositoryCreationTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCreationTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryCreationTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCreationTemplateSpec) DeepCopyInto(out *RepositoryCreationTemplateSpec) {
+	*out = *in
+	if in.AppliedFor != nil {
+		in, out := &in.AppliedFor, &out.AppliedFor
+		*out = make([]RepositoryCreationTemplateAppliedFor, len(*in))
+		copy(*out, *in)
+	}
+	if in.EncryptionConfiguration != nil {
+		in, out := &in.EncryptionConfiguration, &out.EncryptionConfiguration
+		*out = new(EncryptionConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceTags != nil {
+		in, out := &in.ResourceTags, &out.ResourceTags
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCreationTemplateSpec.
+func (in *RepositoryCreationTemplateSpec) DeepCopy() *RepositoryCreationTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCreationTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCreationTemplateStatus) DeepCopyInto(out *RepositoryCreationTemplateStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftTime != nil {
+		in, out := &in.LastDriftTime, &out.LastDriftTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCreationTemplateStatus.
+func (in *RepositoryCreationTemplateStatus) DeepCopy() *RepositoryCreationTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCreationTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryLifecycle) DeepCopyInto(out *RepositoryLifecycle) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycle.
+func (in *RepositoryLifecycle) DeepCopy() *RepositoryLifecycle {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryLifecycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryLifecycle) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryLifecycleList) DeepCopyInto(out *RepositoryLifecycleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RepositoryLifecycle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycleList.
+func (in *RepositoryLifecycleList) DeepCopy() *RepositoryLifecycleList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryLifecycleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryLifecycleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryLifecycleSpec) DeepCopyInto(out *RepositoryLifecycleSpec) {
+	*out = *in
+	if in.RepositorySelector != nil {
+		in, out := &in.RepositorySelector, &out.RepositorySelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LifecyclePolicyTextFrom != nil {
+		in, out := &in.LifecyclePolicyTextFrom, &out.LifecyclePolicyTextFrom
+		*out = new(PolicyTextSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycleSpec.
+func (in *RepositoryLifecycleSpec) DeepCopy() *RepositoryLifecycleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryLifecycleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryLifecycleStatus) DeepCopyInto(out *RepositoryLifecycleStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]RepositoryTargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryLifecycleStatus.
+func (in *RepositoryLifecycleStatus) DeepCopy() *RepositoryLifecycleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryLifecycleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryList) DeepCopyInto(out *RepositoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Repository, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryList.
+func (in *RepositoryList) DeepCopy() *RepositoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicy) DeepCopyInto(out *RepositoryPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicy.
+func (in *RepositoryPolicy) DeepCopy() *RepositoryPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicyConstraint) DeepCopyInto(out *RepositoryPolicyConstraint) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyConstraint.
+func (in *RepositoryPolicyConstraint) DeepCopy() *RepositoryPolicyConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicyConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryPolicyConstraint) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicyConstraintList) DeepCopyInto(out *RepositoryPolicyConstraintList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RepositoryPolicyConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyConstraintList.
+func (in *RepositoryPolicyConstraintList) DeepCopy() *RepositoryPolicyConstraintList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicyConstraintList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryPolicyConstraintList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicyConstraintSpec) DeepCopyInto(out *RepositoryPolicyConstraintSpec) {
+	*out = *in
+	if in.ForbiddenPrincipals != nil {
+		in, out := &in.ForbiddenPrincipals, &out.ForbiddenPrincipals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedAccountIds != nil {
+		in, out := &in.AllowedAccountIds, &out.AllowedAccountIds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ForbiddenActions != nil {
+		in, out := &in.ForbiddenActions, &out.ForbiddenActions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredConditions != nil {
+		in, out := &in.RequiredConditions, &out.RequiredConditions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyConstraintSpec.
+func (in *RepositoryPolicyConstraintSpec) DeepCopy() *RepositoryPolicyConstraintSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicyConstraintSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicyConstraintStatus) DeepCopyInto(out *RepositoryPolicyConstraintStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyConstraintStatus.
+func (in *RepositoryPolicyConstraintStatus) DeepCopy() *RepositoryPolicyConstraintStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicyConstraintStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicyList) DeepCopyInto(out *RepositoryPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RepositoryPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyList.
+func (in *RepositoryPolicyList) DeepCopy() *RepositoryPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RepositoryPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicySpec) DeepCopyInto(out *RepositoryPolicySpec) {
+	*out = *in
+	if in.RepositorySelector != nil {
+		in, out := &in.RepositorySelector, &out.RepositorySelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PolicyTextFrom != nil {
+		in, out := &in.PolicyTextFrom, &out.PolicyTextFrom
+		*out = new(PolicyTextSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicySpec.
+func (in *RepositoryPolicySpec) DeepCopy() *RepositoryPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPolicyStatus) DeepCopyInto(out *RepositoryPolicyStatus) {
+	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]RepositoryTargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPolicyStatus.
+func (in *RepositoryPolicyStatus) DeepCopy() *RepositoryPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
+	*out = *in
+	if in.ImageScanningConfiguration != nil {
+		in, out := &in.ImageScanningConfiguration, &out.ImageScanningConfiguration
+		*out = new(ImageScanningConfiguration)
+		**out = **in
+	}
+	if in.EncryptionConfiguration != nil {
+		in, out := &in.EncryptionConfiguration, &out.EncryptionConfiguration
+		*out = new(EncryptionConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TagAliases != nil {
+		in, out := &in.TagAliases, &out.TagAliases
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.InUseProtection != nil {
+		in, out := &in.InUseProtection, &out.InUseProtection
+		*out = new(InUseProtection)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySpec.
+func (in *RepositorySpec) DeepCopy() *RepositorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryStatus) DeepCopyInto(out *RepositoryStatus) {
+	*out = *in
+	if in.LastPushedAt != nil {
+		in, out := &in.LastPushedAt, &out.LastPushedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LatestTags != nil {
+		in, out := &in.LatestTags, &out.LatestTags
+		*out = make([]ImageTag, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRescanTime != nil {
+		in, out := &in.LastRescanTime, &out.LastRescanTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.InUseImages != nil {
+		in, out := &in.InUseImages, &out.InUseImages
+		*out = make([]InUseImage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SignatureVerifications != nil {
+		in, out := &in.SignatureVerifications, &out.SignatureVerifications
+		*out = make([]SignatureVerification, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStatus.
+func (in *RepositoryStatus) DeepCopy() *RepositoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryTargetStatus) DeepCopyInto(out *RepositoryTargetStatus) {
+	*out = *in
+	if in.LastAppliedTime != nil {
+		in, out := &in.LastAppliedTime, &out.LastAppliedTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryTargetStatus.
+func (in *RepositoryTargetStatus) DeepCopy() *RepositoryTargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryTargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScanningRule) DeepCopyInto(out *ScanningRule) {
+	*out = *in
+	if in.RepositoryFilters != nil {
+		in, out := &in.RepositoryFilters, &out.RepositoryFilters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScanningRule.
+func (in *ScanningRule) DeepCopy() *ScanningRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ScanningRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SignatureVerification) DeepCopyInto(out *SignatureVerification) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.VerifiedAt.DeepCopyInto(&out.VerifiedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SignatureVerification.
+func (in *SignatureVerification) DeepCopy() *SignatureVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(SignatureVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Vulnerability) DeepCopyInto(out *Vulnerability) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Vulnerability.
+func (in *Vulnerability) DeepCopy() *Vulnerability {
+	if in == nil {
+		return nil
+	}
+	out := new(Vulnerability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityException) DeepCopyInto(out *VulnerabilityException) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityException.
+func (in *VulnerabilityException) DeepCopy() *VulnerabilityException {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityException)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VulnerabilityException) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityExceptionList) DeepCopyInto(out *VulnerabilityExceptionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VulnerabilityException, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityExceptionList.
+func (in *VulnerabilityExceptionList) DeepCopy() *VulnerabilityExceptionList {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityExceptionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VulnerabilityExceptionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityExceptionSpec) DeepCopyInto(out *VulnerabilityExceptionSpec) {
+	*out = *in
+	if in.ImageTags != nil {
+		in, out := &in.ImageTags, &out.ImageTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VulnerabilityIDs != nil {
+		in, out := &in.VulnerabilityIDs, &out.VulnerabilityIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityExceptionSpec.
+func (in *VulnerabilityExceptionSpec) DeepCopy() *VulnerabilityExceptionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityExceptionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityExceptionStatus) DeepCopyInto(out *VulnerabilityExceptionStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityExceptionStatus.
+func (in *VulnerabilityExceptionStatus) DeepCopy() *VulnerabilityExceptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityExceptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityReport) DeepCopyInto(out *VulnerabilityReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Report.DeepCopyInto(&out.Report)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityReport.
+func (in *VulnerabilityReport) DeepCopy() *VulnerabilityReport {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VulnerabilityReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityReportData) DeepCopyInto(out *VulnerabilityReportData) {
+	*out = *in
+	in.UpdateTimestamp.DeepCopyInto(&out.UpdateTimestamp)
+	out.Scanner = in.Scanner
+	out.Registry = in.Registry
+	out.Artifact = in.Artifact
+	out.Summary = in.Summary
+	if in.Vulnerabilities != nil {
+		in, out := &in.Vulnerabilities, &out.Vulnerabilities
+		*out = make([]Vulnerability, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityReportData.
+func (in *VulnerabilityReportData) DeepCopy() *VulnerabilityReportData {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityReportData)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilityReportList) DeepCopyInto(out *VulnerabilityReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VulnerabilityReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilityReportList.
+func (in *VulnerabilityReportList) DeepCopy() *VulnerabilityReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilityReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VulnerabilityReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilitySummary) DeepCopyInto(out *VulnerabilitySummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilitySummary.
+func (in *VulnerabilitySummary) DeepCopy() *VulnerabilitySummary {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilitySummary)
 	in.DeepCopyInto(out)
 	return out
 }
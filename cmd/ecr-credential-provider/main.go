@@ -0,0 +1,283 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// The ecr-credential-provider implements the kubelet image credential provider
+// exec protocol and returns ECR authorization tokens for the images of managed repositories.
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	credentialproviderv1alpha1 "k8s.io/kubelet/pkg/apis/credentialprovider/v1alpha1"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/lreimer/aws-ecr-operator/controllers"
+)
+
+// tokens are refreshed this long before they expire
+const expiryMargin = time.Duration(15) * time.Minute
+
+// the file in the cache directory to cache the managed repositories in
+const managedRepositoriesFile = "managed-repositories.json"
+
+// The cachedCredentials are stored on disk per registry
+type cachedCredentials struct {
+	Username  string    `json:"username"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// The cachedRepositories are the RepositoryUri values of the managed repositories published by the operator
+type cachedRepositories struct {
+	RepositoryUris []string  `json:"repositoryUris"`
+	FetchedAt      time.Time `json:"fetchedAt"`
+}
+
+// The managedRepositories are looked up in the ConfigMap published by the operator and cached on disk
+type managedRepositories struct {
+	configMap string
+	ttl       time.Duration
+	cacheDir  string
+}
+
+func main() {
+	var cacheDir string
+	var configMap string
+	var ttl time.Duration
+	flag.StringVar(&cacheDir, "cache-dir", filepath.Join(os.TempDir(), "ecr-credential-provider"),
+		"The directory to cache the authorization tokens and managed repositories in.")
+	flag.StringVar(&configMap, "managed-repositories-configmap", "aws-ecr-operator-system/managed-repositories",
+		"The namespace/name of the ConfigMap the operator publishes the RepositoryUri values of managed repositories to.")
+	flag.DurationVar(&ttl, "managed-repositories-ttl", time.Duration(1)*time.Minute,
+		"How long to cache the managed repositories before reading the ConfigMap again.")
+	flag.Parse()
+
+	// the ConfigMap is read using --kubeconfig as registered by controller-runtime, $KUBECONFIG,
+	// the in-cluster config or ~/.kube/config
+	repositories := &managedRepositories{configMap: configMap, ttl: ttl, cacheDir: cacheDir}
+	if err := run(os.Stdin, os.Stdout, cacheDir, repositories); err != nil {
+		fmt.Fprintf(os.Stderr, "ecr-credential-provider: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(in io.Reader, out io.Writer, cacheDir string, repositories *managedRepositories) error {
+	request := &credentialproviderv1alpha1.CredentialProviderRequest{}
+	if err := json.NewDecoder(in).Decode(request); err != nil {
+		return fmt.Errorf("unable to decode CredentialProviderRequest: %w", err)
+	}
+
+	// credentials are only valid for the repository of the image, so the response is cached per image
+	response := &credentialproviderv1alpha1.CredentialProviderResponse{
+		TypeMeta:     metav1.TypeMeta{APIVersion: request.APIVersion, Kind: "CredentialProviderResponse"},
+		CacheKeyType: credentialproviderv1alpha1.ImagePluginCacheKeyType,
+	}
+
+	ref := controllers.ParseImageReference(request.Image)
+	registryId, region, ok := controllers.ParseEcrRegistry(ref.Registry)
+	if !ok {
+		return fmt.Errorf("image %s is not hosted in an ECR registry", request.Image)
+	}
+
+	managed, err := repositories.contains(ref.Registry + "/" + ref.Repository)
+	if err != nil {
+		return err
+	}
+	if !managed {
+		// no credentials for images of unmanaged repositories, the repository might become managed later
+		response.CacheDuration = &metav1.Duration{}
+		return json.NewEncoder(out).Encode(response)
+	}
+
+	credentials, err := loadCachedCredentials(cacheDir, ref.Registry)
+	if err != nil {
+		credentials, err = getCredentials(registryId, region)
+		if err != nil {
+			return err
+		}
+		if err := storeCachedCredentials(cacheDir, ref.Registry, credentials); err != nil {
+			// caching is best effort, the credentials are still valid
+			fmt.Fprintf(os.Stderr, "ecr-credential-provider: unable to cache credentials: %v\n", err)
+		}
+	}
+
+	response.CacheDuration = &metav1.Duration{Duration: time.Until(credentials.ExpiresAt.Add(-expiryMargin))}
+	response.Auth = map[string]credentialproviderv1alpha1.AuthConfig{
+		ref.Registry + "/" + ref.Repository: {Username: credentials.Username, Password: credentials.Password},
+	}
+	return json.NewEncoder(out).Encode(response)
+}
+
+// Checks whether the RepositoryUri belongs to a repository managed by a Repository object. The cached
+// managed repositories are used until the TTL expires, so not every image pull reads the ConfigMap.
+func (m *managedRepositories) contains(repositoryUri string) (bool, error) {
+	uris, err := m.loadCached()
+	if err != nil {
+		if uris, err = m.fetch(); err != nil {
+			return false, err
+		}
+		if err := m.storeCached(uris); err != nil {
+			// caching is best effort, the managed repositories are still valid
+			fmt.Fprintf(os.Stderr, "ecr-credential-provider: unable to cache managed repositories: %v\n", err)
+		}
+	}
+
+	for _, uri := range uris {
+		if uri == repositoryUri {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Reads the managed repositories from the ConfigMap published by the operator
+func (m *managedRepositories) fetch() ([]string, error) {
+	namespace, name, ok := strings.Cut(m.configMap, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid managed repositories ConfigMap %s, expected namespace/name", m.configMap)
+	}
+
+	config, err := ctrlconfig.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig to look up managed repositories: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read managed repositories: %w", err)
+	}
+	return controllers.ParseManagedRepositories(configMap.Data), nil
+}
+
+func (m *managedRepositories) loadCached() ([]string, error) {
+	bytes, err := os.ReadFile(filepath.Join(m.cacheDir, managedRepositoriesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedRepositories{}
+	if err := json.Unmarshal(bytes, cached); err != nil {
+		return nil, err
+	}
+	if time.Since(cached.FetchedAt) > m.ttl {
+		return nil, errors.New("cached managed repositories expired")
+	}
+	return cached.RepositoryUris, nil
+}
+
+func (m *managedRepositories) storeCached(uris []string) error {
+	bytes, err := json.Marshal(&cachedRepositories{RepositoryUris: uris, FetchedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	return writeCacheFile(m.cacheDir, managedRepositoriesFile, bytes)
+}
+
+// Gets a new authorization token from the ECR registry in the given region
+func getCredentials(registryId string, region string) (*cachedCredentials, error) {
+	client, err := controllers.CreateEcrClientForRegion(region)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.GetAuthorizationToken(context.TODO(), &ecr.GetAuthorizationTokenInput{
+		RegistryIds: []string{registryId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.AuthorizationData) == 0 || output.AuthorizationData[0].AuthorizationToken == nil {
+		return nil, errors.New("no authorization data returned")
+	}
+
+	data := output.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(*data.AuthorizationToken)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("invalid authorization token")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(12) * time.Hour)
+	if data.ExpiresAt != nil {
+		expiresAt = *data.ExpiresAt
+	}
+	return &cachedCredentials{Username: parts[0], Password: parts[1], ExpiresAt: expiresAt}, nil
+}
+
+func loadCachedCredentials(cacheDir string, registry string) (*cachedCredentials, error) {
+	bytes, err := os.ReadFile(filepath.Join(cacheDir, registry+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := &cachedCredentials{}
+	if err := json.Unmarshal(bytes, credentials); err != nil {
+		return nil, err
+	}
+	if time.Now().After(credentials.ExpiresAt.Add(-expiryMargin)) {
+		return nil, errors.New("cached credentials expired")
+	}
+	return credentials, nil
+}
+
+func storeCachedCredentials(cacheDir string, registry string, credentials *cachedCredentials) error {
+	bytes, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+	return writeCacheFile(cacheDir, registry+".json", bytes)
+}
+
+func writeCacheFile(cacheDir string, name string, bytes []byte) error {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, concurrent kubelet invocations must not read partial files
+	file := filepath.Join(cacheDir, name)
+	if err := os.WriteFile(file+".tmp", bytes, 0600); err != nil {
+		return err
+	}
+	return os.Rename(file+".tmp", file)
+}
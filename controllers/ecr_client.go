@@ -3,14 +3,15 @@ package controllers
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
 )
 
 // Creates an ECR client object from the AWS SDK
 func CreateEcrClient() (*ecr.Client, error) {
-	// load the default AWS config from ENV or shared files
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	cfg, err := LoadAwsConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -18,3 +19,34 @@ func CreateEcrClient() (*ecr.Client, error) {
 	client := ecr.NewFromConfig(cfg)
 	return client, nil
 }
+
+// Creates an ECR client object from the AWS SDK for the given region,
+// e.g. to access cross-region or cross-account registries
+func CreateEcrClientForRegion(region string) (*ecr.Client, error) {
+	cfg, err := LoadAwsConfig(config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+
+	client := ecr.NewFromConfig(cfg)
+	return client, nil
+}
+
+// The region of the ECR Public API, which is only available in us-east-1
+const ecrPublicRegion = "us-east-1"
+
+// Creates an ECR Public client object from the AWS SDK
+func CreateEcrPublicClient() (*ecrpublic.Client, error) {
+	cfg, err := LoadAwsConfig(config.WithRegion(ecrPublicRegion))
+	if err != nil {
+		return nil, err
+	}
+
+	client := ecrpublic.NewFromConfig(cfg)
+	return client, nil
+}
+
+// Loads the default AWS config from ENV or shared files
+func LoadAwsConfig(optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	return config.LoadDefaultConfig(context.TODO(), optFns...)
+}
@@ -0,0 +1,76 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// matches registry hosts of the form aws_account_id.dkr.ecr.region.amazonaws.com
+var ecrRegistryPattern = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+
+// The ImageReference holds the parts of a container image reference
+type ImageReference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Parses a container image reference of the form registry/repository:tag@digest
+func ParseImageReference(image string) ImageReference {
+	ref := ImageReference{}
+
+	if i := strings.Index(image, "@"); i >= 0 {
+		ref.Digest = image[i+1:]
+		image = image[:i]
+	}
+	// a colon after the last slash separates the tag, otherwise it is a registry port
+	if i := strings.LastIndex(image, ":"); i >= 0 && i > strings.LastIndex(image, "/") {
+		ref.Tag = image[i+1:]
+		image = image[:i]
+	}
+	// the first path component is a registry if it contains a dot or port, or is localhost
+	if i := strings.Index(image, "/"); i >= 0 {
+		first := image[:i]
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Registry = first
+			image = image[i+1:]
+		}
+	}
+	ref.Repository = image
+
+	return ref
+}
+
+// Parses the registry ID and region of an ECR registry host
+func ParseEcrRegistry(registry string) (registryId string, region string, ok bool) {
+	match := ecrRegistryPattern.FindStringSubmatch(registry)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
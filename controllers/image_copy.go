@@ -0,0 +1,305 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-logr/logr"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	// the part size used if InitiateLayerUpload does not recommend one
+	defaultLayerPartSize = int64(10 * 1024 * 1024)
+	// the maximum number of layer digests per BatchCheckLayerAvailability call
+	layerAvailabilityBatchSize = 100
+)
+
+// the manifest media types accepted when reading images to copy
+var acceptedManifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// The imageManifest holds the references of an image manifest or index needed to copy it
+type imageManifest struct {
+	Config *struct {
+		Digest string `json:"digest"`
+	} `json:"config,omitempty"`
+	Layers []struct {
+		Digest string `json:"digest"`
+	} `json:"layers,omitempty"`
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests,omitempty"`
+}
+
+// An ecrLocation identifies a repository in a registry accessed with the given client
+type ecrLocation struct {
+	client         *ecr.Client
+	registryId     string
+	repositoryName string
+}
+
+func (l ecrLocation) registry() *string {
+	if l.registryId == "" {
+		return nil
+	}
+	return aws.String(l.registryId)
+}
+
+// The imageCopier copies images between ECR repositories, either by copying the manifest
+// only, or by transferring the layers first if they are not available in the destination
+type imageCopier struct {
+	logger      logr.Logger
+	source      ecrLocation
+	destination ecrLocation
+	httpClient  *http.Client
+}
+
+// Returns the image with its manifest from the source repository
+func (c *imageCopier) getImage(imageId *types.ImageIdentifier) (*types.Image, error) {
+	output, err := c.source.client.BatchGetImage(context.TODO(), &ecr.BatchGetImageInput{
+		RegistryId:         c.source.registry(),
+		RepositoryName:     aws.String(c.source.repositoryName),
+		ImageIds:           []types.ImageIdentifier{*imageId},
+		AcceptedMediaTypes: acceptedManifestMediaTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Failures) > 0 {
+		failure := output.Failures[0]
+		return nil, fmt.Errorf("unable to get image from %s: %s %s", c.source.repositoryName, failure.FailureCode, aws.ToString(failure.FailureReason))
+	}
+	if len(output.Images) == 0 {
+		return nil, fmt.Errorf("image not found in %s", c.source.repositoryName)
+	}
+	return &output.Images[0], nil
+}
+
+// Copies the image to the destination repository with the given tags, or by digest only
+// if no tags are given. The layers are only transferred if copyLayers is true.
+func (c *imageCopier) copyImage(image *types.Image, tags []string, copyLayers bool) error {
+	if copyLayers {
+		if err := c.copyReferences(image); err != nil {
+			return err
+		}
+	}
+
+	if len(tags) == 0 {
+		return c.putImage(image, "")
+	}
+	for _, tag := range tags {
+		if err := c.putImage(image, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Copies the layers of an image manifest, or the child manifests and their layers of an image index
+func (c *imageCopier) copyReferences(image *types.Image) error {
+	manifest := imageManifest{}
+	if err := json.Unmarshal([]byte(aws.ToString(image.ImageManifest)), &manifest); err != nil {
+		return fmt.Errorf("unable to parse image manifest: %w", err)
+	}
+
+	for _, child := range manifest.Manifests {
+		childImage, err := c.getImage(&types.ImageIdentifier{ImageDigest: aws.String(child.Digest)})
+		if err != nil {
+			return err
+		}
+		if err := c.copyReferences(childImage); err != nil {
+			return err
+		}
+		if err := c.putImage(childImage, ""); err != nil {
+			return err
+		}
+	}
+
+	digests := make([]string, 0, len(manifest.Layers)+1)
+	if manifest.Config != nil {
+		digests = append(digests, manifest.Config.Digest)
+	}
+	for _, layer := range manifest.Layers {
+		digests = append(digests, layer.Digest)
+	}
+	return c.copyLayers(digests)
+}
+
+// Transfers all layers not yet available in the destination repository
+func (c *imageCopier) copyLayers(digests []string) error {
+	for start := 0; start < len(digests); start += layerAvailabilityBatchSize {
+		end := start + layerAvailabilityBatchSize
+		if end > len(digests) {
+			end = len(digests)
+		}
+
+		output, err := c.destination.client.BatchCheckLayerAvailability(context.TODO(), &ecr.BatchCheckLayerAvailabilityInput{
+			RegistryId:     c.destination.registry(),
+			RepositoryName: aws.String(c.destination.repositoryName),
+			LayerDigests:   digests[start:end],
+		})
+		if err != nil {
+			return err
+		}
+
+		available := make(map[string]bool)
+		for _, layer := range output.Layers {
+			if layer.LayerAvailability == types.LayerAvailabilityAvailable {
+				available[aws.ToString(layer.LayerDigest)] = true
+			}
+		}
+		for _, digest := range digests[start:end] {
+			if available[digest] {
+				continue
+			}
+			if err := c.copyLayer(digest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Downloads the layer from the source and uploads it to the destination repository in parts
+func (c *imageCopier) copyLayer(digest string) error {
+	download, err := c.source.client.GetDownloadUrlForLayer(context.TODO(), &ecr.GetDownloadUrlForLayerInput{
+		RegistryId:     c.source.registry(),
+		RepositoryName: aws.String(c.source.repositoryName),
+		LayerDigest:    aws.String(digest),
+	})
+	if err != nil {
+		return err
+	}
+
+	response, err := c.httpClient.Get(aws.ToString(download.DownloadUrl))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to download layer %s: %s", digest, response.Status)
+	}
+
+	upload, err := c.destination.client.InitiateLayerUpload(context.TODO(), &ecr.InitiateLayerUploadInput{
+		RegistryId:     c.destination.registry(),
+		RepositoryName: aws.String(c.destination.repositoryName),
+	})
+	if err != nil {
+		return err
+	}
+
+	partSize := aws.ToInt64(upload.PartSize)
+	if partSize <= 0 {
+		partSize = defaultLayerPartSize
+	}
+
+	buf := make([]byte, partSize)
+	var firstByte int64
+	for {
+		n, readerr := io.ReadFull(response.Body, buf)
+		if n > 0 {
+			_, err := c.destination.client.UploadLayerPart(context.TODO(), &ecr.UploadLayerPartInput{
+				RegistryId:     c.destination.registry(),
+				RepositoryName: aws.String(c.destination.repositoryName),
+				UploadId:       upload.UploadId,
+				PartFirstByte:  aws.Int64(firstByte),
+				PartLastByte:   aws.Int64(firstByte + int64(n) - 1),
+				LayerPartBlob:  buf[:n],
+			})
+			if err != nil {
+				return err
+			}
+			firstByte += int64(n)
+		}
+		if readerr == io.EOF || readerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readerr != nil {
+			return readerr
+		}
+	}
+
+	_, err = c.destination.client.CompleteLayerUpload(context.TODO(), &ecr.CompleteLayerUploadInput{
+		RegistryId:     c.destination.registry(),
+		RepositoryName: aws.String(c.destination.repositoryName),
+		UploadId:       upload.UploadId,
+		LayerDigests:   []string{digest},
+	})
+	if err != nil {
+		var laee *types.LayerAlreadyExistsException
+		if !errors.As(err, &laee) {
+			return err
+		}
+	}
+
+	c.logger.Info("Transferred image layer.", "digest", digest, "size", firstByte)
+	return nil
+}
+
+// Puts the image manifest into the destination repository, with the tag if not empty
+func (c *imageCopier) putImage(image *types.Image, tag string) error {
+	input := &ecr.PutImageInput{
+		RegistryId:             c.destination.registry(),
+		RepositoryName:         aws.String(c.destination.repositoryName),
+		ImageManifest:          image.ImageManifest,
+		ImageManifestMediaType: image.ImageManifestMediaType,
+		ImageDigest:            image.ImageId.ImageDigest,
+	}
+	if tag != "" {
+		input.ImageTag = aws.String(tag)
+	}
+
+	_, err := c.destination.client.PutImage(context.TODO(), input)
+	if err != nil {
+		// the image already exists with the same manifest and tag
+		var iaee *types.ImageAlreadyExistsException
+		if errors.As(err, &iaee) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Checks whether the error indicates that layers or referenced manifests of the image are missing
+func isMissingImageReferences(err error) bool {
+	var lnfe *types.LayersNotFoundException
+	var rinfe *types.ReferencedImagesNotFoundException
+	return errors.As(err, &lnfe) || errors.As(err, &rinfe)
+}
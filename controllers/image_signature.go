@@ -0,0 +1,605 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	cosignSignatureAnnotation   = "dev.cosignproject.cosign/signature"
+	cosignCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	cosignChainAnnotation       = "dev.sigstore.cosign/chain"
+	notationArtifactType        = "application/vnd.cncf.notary.signature"
+	notationJwsMediaType        = "application/jose+json"
+	// the maximum size of a signature blob
+	signatureBlobLimit = 1024 * 1024
+	// the timeout to download signature blobs and query the referrers API
+	signatureRequestTimeout = time.Duration(30) * time.Second
+)
+
+var (
+	// the Fulcio certificate extensions holding the OIDC issuer, the first one is deprecated
+	fulcioIssuerOID   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	fulcioIssuerV2OID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+	// the tags of Cosign signatures, attestations and SBOMs as well as referrers tag schema indexes
+	signatureTagPattern = regexp.MustCompile(`^sha256-[a-f0-9]{64}(\.[a-z]+)?$`)
+)
+
+// The signatureManifest holds the parts of a signature artifact manifest or referrers index needed for verification
+type signatureManifest struct {
+	ArtifactType string `json:"artifactType,omitempty"`
+	Layers       []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"layers,omitempty"`
+	Manifests []struct {
+		Digest       string `json:"digest"`
+		ArtifactType string `json:"artifactType,omitempty"`
+	} `json:"manifests,omitempty"`
+}
+
+// The cosignPayload holds the signed image digest of a Cosign simple signing payload
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// The notationEnvelope is a Notation signature envelope in JWS JSON serialization
+type notationEnvelope struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Signature string `json:"signature"`
+	Header    struct {
+		X5c []string `json:"x5c"`
+	} `json:"header"`
+}
+
+// A signatureSource reads the signature artifacts of images from an ECR repository,
+// all requests are canceled once the context is done
+type signatureSource struct {
+	ctx        context.Context
+	location   ecrLocation
+	host       string
+	httpClient *http.Client
+	token      string
+}
+
+// Returns the signature source for the repository with the given URI
+func newSignatureSource(ctx context.Context, client *ecr.Client, registryId string, repositoryUri string) *signatureSource {
+	host, repositoryName := repositoryUri, ""
+	if i := strings.Index(repositoryUri, "/"); i >= 0 {
+		host, repositoryName = repositoryUri[:i], repositoryUri[i+1:]
+	}
+	return &signatureSource{
+		ctx:        ctx,
+		location:   ecrLocation{client: client, registryId: registryId, repositoryName: repositoryName},
+		host:       host,
+		httpClient: &http.Client{Timeout: signatureRequestTimeout},
+	}
+}
+
+// Returns the manifest of the artifact with the given tag or digest, or nil if it does not exist
+func (s *signatureSource) getManifest(imageId types.ImageIdentifier) (*signatureManifest, error) {
+	output, err := s.location.client.BatchGetImage(s.ctx, &ecr.BatchGetImageInput{
+		RegistryId:         s.location.registry(),
+		RepositoryName:     aws.String(s.location.repositoryName),
+		ImageIds:           []types.ImageIdentifier{imageId},
+		AcceptedMediaTypes: acceptedManifestMediaTypes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.Images) == 0 {
+		return nil, nil
+	}
+
+	manifest := &signatureManifest{}
+	if err := json.Unmarshal([]byte(aws.ToString(output.Images[0].ImageManifest)), manifest); err != nil {
+		return nil, fmt.Errorf("unable to parse signature manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Downloads the blob with the given digest and checks its integrity
+func (s *signatureSource) getBlob(digest string) ([]byte, error) {
+	download, err := s.location.client.GetDownloadUrlForLayer(s.ctx, &ecr.GetDownloadUrlForLayerInput{
+		RegistryId:     s.location.registry(),
+		RepositoryName: aws.String(s.location.repositoryName),
+		LayerDigest:    aws.String(digest),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(s.ctx, http.MethodGet, aws.ToString(download.DownloadUrl), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to download blob %s: %s", digest, response.Status)
+	}
+
+	blob, err := io.ReadAll(io.LimitReader(response.Body, signatureBlobLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(blob) > signatureBlobLimit {
+		return nil, fmt.Errorf("blob %s exceeds %d bytes", digest, signatureBlobLimit)
+	}
+	if actual := fmt.Sprintf("sha256:%x", sha256.Sum256(blob)); actual != digest {
+		return nil, fmt.Errorf("blob digest %s does not match %s", actual, digest)
+	}
+	return blob, nil
+}
+
+// Returns the digests of the Notation signatures referring to the image, using the OCI referrers API
+// and the referrers tag schema as fallback
+func (s *signatureSource) notationSignatures(digest string) ([]string, error) {
+	index, err := s.referrers(digest)
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		index, err = s.getManifest(types.ImageIdentifier{ImageTag: aws.String(strings.Replace(digest, ":", "-", 1))})
+		if err != nil || index == nil {
+			return nil, err
+		}
+	}
+
+	digests := make([]string, 0)
+	for _, manifest := range index.Manifests {
+		if manifest.ArtifactType == notationArtifactType {
+			digests = append(digests, manifest.Digest)
+		}
+	}
+	return digests, nil
+}
+
+// Queries the OCI referrers API of the registry, returns nil if the API is not supported
+func (s *signatureSource) referrers(digest string) (*signatureManifest, error) {
+	if s.token == "" {
+		input := &ecr.GetAuthorizationTokenInput{}
+		if s.location.registryId != "" {
+			input.RegistryIds = []string{s.location.registryId}
+		}
+		output, err := s.location.client.GetAuthorizationToken(s.ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		if len(output.AuthorizationData) == 0 {
+			return nil, errors.New("no ECR authorization token returned")
+		}
+		s.token = aws.ToString(output.AuthorizationData[0].AuthorizationToken)
+	}
+
+	endpoint := fmt.Sprintf("https://%s/v2/%s/referrers/%s?artifactType=%s", s.host, s.location.repositoryName, digest, url.QueryEscape(notationArtifactType))
+	request, err := http.NewRequestWithContext(s.ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Basic "+s.token)
+	request.Header.Set("Accept", "application/vnd.oci.image.index.v1+json")
+
+	response, err := s.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to list referrers of %s: %s", digest, response.Status)
+	}
+
+	index := &signatureManifest{}
+	if err := json.NewDecoder(io.LimitReader(response.Body, signatureBlobLimit)).Decode(index); err != nil {
+		return nil, fmt.Errorf("unable to parse referrers of %s: %w", digest, err)
+	}
+	return index, nil
+}
+
+// The signatureVerifier verifies image signatures with the keys and certificate identities of an ImageVerificationPolicy
+type signatureVerifier struct {
+	format     string
+	publicKeys []crypto.PublicKey
+	roots      *x509.CertPool
+	identities []ecrv1beta1.CertificateIdentity
+}
+
+// Returns the verifier for the ImageVerificationPolicy, or an error if its keys or certificates are invalid
+func newSignatureVerifier(spec ecrv1beta1.ImageVerificationPolicySpec) (*signatureVerifier, error) {
+	v := &signatureVerifier{format: spec.Format, identities: spec.CertificateIdentities}
+	if v.format == "" {
+		v.format = "Cosign"
+	}
+
+	for _, key := range spec.PublicKeys {
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			return nil, errors.New("invalid PEM public key")
+		}
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		v.publicKeys = append(v.publicKeys, publicKey)
+	}
+
+	if spec.CertificateRoots != "" {
+		v.roots = x509.NewCertPool()
+		if !v.roots.AppendCertsFromPEM([]byte(spec.CertificateRoots)) {
+			return nil, errors.New("invalid PEM certificate roots")
+		}
+	}
+
+	if v.roots == nil && (len(v.identities) > 0 || v.format == "Notation") {
+		return nil, errors.New("certificateRoots are required for certificate identities and Notation")
+	}
+	if v.roots == nil && len(v.publicKeys) == 0 {
+		return nil, errors.New("publicKeys or certificateRoots are required")
+	}
+	return v, nil
+}
+
+// Verifies that the image has at least one valid signature, the error lists why each signature is invalid
+func (v *signatureVerifier) verify(source *signatureSource, digest string) error {
+	var valid bool
+	var errs []string
+	var err error
+	if v.format == "Notation" {
+		valid, errs, err = v.verifyNotation(source, digest)
+	} else {
+		valid, errs, err = v.verifyCosign(source, digest)
+	}
+	if err != nil {
+		return err
+	}
+	if valid {
+		return nil
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no %s signature found", v.format)
+	}
+	return fmt.Errorf("no valid %s signature: %s", v.format, strings.Join(errs, ", "))
+}
+
+// Verifies the Cosign signatures stored with the sha256-<digest>.sig tag. Returns whether a
+// signature is valid, otherwise the errors of the invalid signatures.
+func (v *signatureVerifier) verifyCosign(source *signatureSource, digest string) (bool, []string, error) {
+	manifest, err := source.getManifest(types.ImageIdentifier{ImageTag: aws.String(strings.Replace(digest, ":", "-", 1) + ".sig")})
+	if err != nil {
+		return false, nil, err
+	}
+	errs := make([]string, 0)
+	if manifest == nil {
+		return false, errs, nil
+	}
+
+	for _, layer := range manifest.Layers {
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := source.getBlob(layer.Digest)
+		if err != nil {
+			return false, nil, err
+		}
+		if err := v.verifyCosignSignature(payload, signature, layer.Annotations, digest); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		return true, nil, nil
+	}
+	return false, errs, nil
+}
+
+func (v *signatureVerifier) verifyCosignSignature(payload []byte, signature string, annotations map[string]string, digest string) error {
+	signed := cosignPayload{}
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if signed.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("payload signs %s", signed.Critical.Image.DockerManifestDigest)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	if certificate, ok := annotations[cosignCertificateAnnotation]; ok && v.roots != nil {
+		certs, err := parsePEMCertificates(certificate + "\n" + annotations[cosignChainAnnotation])
+		if err != nil {
+			return err
+		}
+		if err := v.verifyCertificate(certs[0], certs[1:]); err != nil {
+			return err
+		}
+		return verifySignature(certs[0].PublicKey, crypto.SHA256, payload, sig, false)
+	}
+
+	for _, key := range v.publicKeys {
+		if verifySignature(key, crypto.SHA256, payload, sig, false) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature does not match any public key")
+}
+
+// Verifies the Notation signatures referring to the image. Returns whether a signature is
+// valid, otherwise the errors of the invalid signatures.
+func (v *signatureVerifier) verifyNotation(source *signatureSource, digest string) (bool, []string, error) {
+	digests, err := source.notationSignatures(digest)
+	if err != nil {
+		return false, nil, err
+	}
+
+	errs := make([]string, 0)
+	for _, signatureDigest := range digests {
+		manifest, err := source.getManifest(types.ImageIdentifier{ImageDigest: aws.String(signatureDigest)})
+		if err != nil {
+			return false, nil, err
+		}
+		if manifest == nil {
+			continue
+		}
+		for _, layer := range manifest.Layers {
+			if layer.MediaType != notationJwsMediaType {
+				errs = append(errs, fmt.Sprintf("unsupported envelope %s", layer.MediaType))
+				continue
+			}
+			envelope, err := source.getBlob(layer.Digest)
+			if err != nil {
+				return false, nil, err
+			}
+			if err := v.verifyNotationEnvelope(envelope, digest); err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			return true, nil, nil
+		}
+	}
+	return false, errs, nil
+}
+
+func (v *signatureVerifier) verifyNotationEnvelope(data []byte, digest string) error {
+	envelope := notationEnvelope{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("invalid envelope: %w", err)
+	}
+
+	protectedJson, err := base64.RawURLEncoding.DecodeString(envelope.Protected)
+	if err != nil {
+		return fmt.Errorf("invalid protected header encoding: %w", err)
+	}
+	protected := struct {
+		Alg string `json:"alg"`
+	}{}
+	if err := json.Unmarshal(protectedJson, &protected); err != nil {
+		return fmt.Errorf("invalid protected header: %w", err)
+	}
+
+	payloadJson, err := base64.RawURLEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload encoding: %w", err)
+	}
+	payload := struct {
+		TargetArtifact struct {
+			Digest string `json:"digest"`
+		} `json:"targetArtifact"`
+	}{}
+	if err := json.Unmarshal(payloadJson, &payload); err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+	if payload.TargetArtifact.Digest != digest {
+		return fmt.Errorf("payload signs %s", payload.TargetArtifact.Digest)
+	}
+
+	if len(envelope.Header.X5c) == 0 {
+		return errors.New("no certificate chain in envelope")
+	}
+	certs := make([]*x509.Certificate, 0, len(envelope.Header.X5c))
+	for _, encoded := range envelope.Header.X5c {
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid certificate encoding: %w", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if err := v.verifyCertificate(certs[0], certs[1:]); err != nil {
+		return err
+	}
+
+	var hash crypto.Hash
+	switch protected.Alg {
+	case "ES256", "PS256":
+		hash = crypto.SHA256
+	case "ES384", "PS384":
+		hash = crypto.SHA384
+	case "ES512", "PS512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported algorithm %s", protected.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(envelope.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	return verifySignature(certs[0].PublicKey, hash, []byte(envelope.Protected+"."+envelope.Payload), sig, true)
+}
+
+// Verifies the signing certificate chains to the roots and matches an identity. Neither the transparency
+// log nor a timestamp authority is checked, so the signing time chosen by the signer can not be trusted
+// and the certificate chain must be valid now.
+func (v *signatureVerifier) verifyCertificate(cert *x509.Certificate, chain []*x509.Certificate) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   time.Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if err != nil {
+		return fmt.Errorf("untrusted certificate: %w", err)
+	}
+
+	if len(v.identities) == 0 {
+		return nil
+	}
+	subjects := append([]string{cert.Subject.String()}, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+	issuer := certificateIssuer(cert)
+	for _, identity := range v.identities {
+		if containsString(subjects, identity.Subject) && (identity.Issuer == "" || identity.Issuer == issuer) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate identity %s is not allowed", strings.Join(subjects, " "))
+}
+
+// Returns the OIDC issuer of a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(fulcioIssuerV2OID) {
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		}
+		if ext.Id.Equal(fulcioIssuerOID) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// Verifies the signature of the message with the public key. JWS encodes ECDSA signatures as
+// the concatenation of r and s and uses PSS for RSA, otherwise ASN.1 and PKCS #1 v1.5 are used.
+func verifySignature(publicKey crypto.PublicKey, hash crypto.Hash, message []byte, sig []byte, jws bool) error {
+	if key, ok := publicKey.(ed25519.PublicKey); ok {
+		if !ed25519.Verify(key, message, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+
+	h := hash.New()
+	h.Write(message)
+	digest := h.Sum(nil)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid := false
+		if jws && len(sig)%2 == 0 {
+			half := len(sig) / 2
+			r, s := new(big.Int).SetBytes(sig[:half]), new(big.Int).SetBytes(sig[half:])
+			valid = ecdsa.Verify(key, digest, r, s)
+		} else if !jws {
+			valid = ecdsa.VerifyASN1(key, digest, sig)
+		}
+		if !valid {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		var err error
+		if jws {
+			err = rsa.VerifyPSS(key, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		} else {
+			err = rsa.VerifyPKCS1v15(key, hash, digest, sig)
+		}
+		if err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported public key type %T", publicKey)
+}
+
+func parsePEMCertificates(data string) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,537 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+const (
+	testImageDigest    = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	testOtherDigest    = "sha256:fedcba9876543210fedcba9876543210fedcba9876543210fedcba9876543210"
+	testSubject        = "https://github.com/org/app/.github/workflows/release.yml@refs/heads/main"
+	testIssuer         = "https://token.actions.githubusercontent.com"
+	testRepositoryName = "app"
+)
+
+// The fakeRegistry serves the ECR API calls and the registry endpoints used to read signatures
+type fakeRegistry struct {
+	server    *httptest.Server
+	manifests map[string]string
+	blobs     map[string][]byte
+	referrers map[string]string
+}
+
+// Starts a fake registry and returns it with a signature source reading from it
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *signatureSource) {
+	r := &fakeRegistry{
+		manifests: map[string]string{},
+		blobs:     map[string][]byte{},
+		referrers: map[string]string{},
+	}
+	r.server = httptest.NewTLSServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.server.Close)
+
+	client := ecr.New(ecr.Options{
+		Region:       "eu-central-1",
+		BaseEndpoint: aws.String(r.server.URL),
+		HTTPClient:   r.server.Client(),
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	host := strings.TrimPrefix(r.server.URL, "https://")
+	source := newSignatureSource(context.Background(), client, "", host+"/"+testRepositoryName)
+	source.httpClient = r.server.Client()
+	return r, source
+}
+
+func (r *fakeRegistry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if target := req.Header.Get("X-Amz-Target"); target != "" {
+		input := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&input); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_ = json.NewEncoder(w).Encode(r.ecrOperation(target[strings.LastIndex(target, ".")+1:], input))
+		return
+	}
+
+	if strings.HasPrefix(req.URL.Path, "/blobs/") {
+		blob, ok := r.blobs[strings.TrimPrefix(req.URL.Path, "/blobs/")]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write(blob)
+		return
+	}
+
+	prefix := "/v2/" + testRepositoryName + "/referrers/"
+	if strings.HasPrefix(req.URL.Path, prefix) {
+		index, ok := r.referrers[strings.TrimPrefix(req.URL.Path, prefix)]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write([]byte(index))
+		return
+	}
+	http.NotFound(w, req)
+}
+
+func (r *fakeRegistry) ecrOperation(operation string, input map[string]interface{}) interface{} {
+	switch operation {
+	case "BatchGetImage":
+		images := make([]interface{}, 0)
+		failures := make([]interface{}, 0)
+		for _, id := range input["imageIds"].([]interface{}) {
+			imageId := id.(map[string]interface{})
+			key, _ := imageId["imageDigest"].(string)
+			if tag, ok := imageId["imageTag"].(string); ok {
+				key = tag
+			}
+			if manifest, ok := r.manifests[key]; ok {
+				images = append(images, map[string]interface{}{"imageId": imageId, "imageManifest": manifest})
+			} else {
+				failures = append(failures, map[string]interface{}{"imageId": imageId, "failureCode": "ImageNotFound"})
+			}
+		}
+		return map[string]interface{}{"images": images, "failures": failures}
+	case "GetDownloadUrlForLayer":
+		digest := input["layerDigest"].(string)
+		return map[string]interface{}{"downloadUrl": r.server.URL + "/blobs/" + digest, "layerDigest": digest}
+	case "GetAuthorizationToken":
+		token := base64.StdEncoding.EncodeToString([]byte("AWS:password"))
+		return map[string]interface{}{"authorizationData": []interface{}{map[string]interface{}{"authorizationToken": token}}}
+	}
+	return map[string]interface{}{}
+}
+
+// Stores a blob and returns its digest
+func (r *fakeRegistry) putBlob(blob []byte) string {
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(blob))
+	r.blobs[digest] = blob
+	return digest
+}
+
+// Stores a Cosign signature of the image with the payload blob stored under the given digest
+func (r *fakeRegistry) putCosignSignature(imageDigest string, payloadDigest string, annotations map[string]string) {
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"layers": []interface{}{map[string]interface{}{
+			"mediaType":   "application/vnd.dev.cosign.simplesigning.v1+json",
+			"digest":      payloadDigest,
+			"annotations": annotations,
+		}},
+	})
+	r.manifests[strings.Replace(imageDigest, ":", "-", 1)+".sig"] = string(manifest)
+}
+
+// Stores a Notation signature envelope as referrer of the image
+func (r *fakeRegistry) putNotationSignature(imageDigest string, envelope []byte) {
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"artifactType": notationArtifactType,
+		"layers": []interface{}{map[string]interface{}{
+			"mediaType": notationJwsMediaType,
+			"digest":    r.putBlob(envelope),
+		}},
+	})
+	manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	r.manifests[manifestDigest] = string(manifest)
+
+	index, _ := json.Marshal(map[string]interface{}{
+		"manifests": []interface{}{map[string]interface{}{"digest": manifestDigest, "artifactType": notationArtifactType}},
+	})
+	r.referrers[imageDigest] = string(index)
+}
+
+func cosignPayloadFor(digest string) []byte {
+	return []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"app"},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, digest))
+}
+
+// Signs the message like Cosign, ed25519 signs the message itself, all other keys its SHA-256 hash
+func signMessage(t *testing.T, key crypto.Signer, message []byte) []byte {
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		sig, err := key.Sign(rand.Reader, message, crypto.Hash(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	hash := sha256.Sum256(message)
+	sig, err := key.Sign(rand.Reader, hash[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// Signs the JWS signing input with ES256 or PS256
+func signJws(t *testing.T, key crypto.Signer, message []byte) (string, []byte) {
+	hash := sha256.Sum256(message)
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return "ES256", sig
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPSS(rand.Reader, k, crypto.SHA256, hash[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "PS256", sig
+	}
+	t.Fatalf("unsupported key type %T", key)
+	return "", nil
+}
+
+func generateKey(t *testing.T, keyType string) crypto.Signer {
+	var key crypto.Signer
+	var err error
+	switch keyType {
+	case "ECDSA":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "RSA":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func publicKeyPEM(t *testing.T, key crypto.Signer) string {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// The certificateChain holds a root and a code signing leaf certificate issued for an identity
+type certificateChain struct {
+	rootPEM  string
+	leafPEM  string
+	leafDER  []byte
+	signedAt time.Time
+}
+
+func newCertificateChain(t *testing.T, leafKey crypto.Signer, subject string, issuer string, expired bool) certificateChain {
+	rootKey := generateKey(t, "ECDSA")
+	root := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test root"},
+		NotBefore:             time.Now().Add(-2 * time.Hour),
+		NotAfter:              time.Now().Add(2 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, root, root, rootKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err = x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuerValue, err := asn1.Marshal(issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subjectURI, err := url.Parse(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an expired leaf certificate was valid in the hour before
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := time.Now().Add(time.Hour)
+	if expired {
+		notBefore = time.Now().Add(-2 * time.Hour)
+		notAfter = time.Now().Add(-time.Hour)
+	}
+	leaf := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       notBefore,
+		NotAfter:        notAfter,
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:            []*url.URL{subjectURI},
+		ExtraExtensions: []pkix.Extension{{Id: fulcioIssuerV2OID, Value: issuerValue}},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leaf, root, leafKey.Public(), rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificateChain{
+		rootPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})),
+		leafPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})),
+		leafDER:  leafDER,
+		signedAt: notBefore.Add(time.Minute),
+	}
+}
+
+func notationEnvelopeFor(t *testing.T, key crypto.Signer, chain certificateChain, digest string) []byte {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"targetArtifact":{"digest":%q,"mediaType":"application/vnd.oci.image.manifest.v1+json","size":1}}`, digest)))
+	alg, _ := signJws(t, key, nil)
+	protected := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"alg":%q,"cty":"application/vnd.cncf.notary.payload.v1+json","io.cncf.notary.signingTime":%q}`, alg, chain.signedAt.Format(time.RFC3339))))
+	_, sig := signJws(t, key, []byte(protected+"."+payload))
+
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payload":   payload,
+		"protected": protected,
+		"signature": base64.RawURLEncoding.EncodeToString(sig),
+		"header":    map[string]interface{}{"x5c": []string{base64.StdEncoding.EncodeToString(chain.leafDER)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return envelope
+}
+
+func TestVerifyCosignPublicKeys(t *testing.T) {
+	tests := []struct {
+		name          string
+		keyType       string
+		signedDigest  string
+		trustOtherKey bool
+		wantErr       string
+	}{
+		{name: "valid ECDSA signature", keyType: "ECDSA", signedDigest: testImageDigest},
+		{name: "valid RSA signature", keyType: "RSA", signedDigest: testImageDigest},
+		{name: "valid ed25519 signature", keyType: "ed25519", signedDigest: testImageDigest},
+		{name: "wrong payload digest", keyType: "ECDSA", signedDigest: testOtherDigest, wantErr: "payload signs " + testOtherDigest},
+		{name: "untrusted key", keyType: "RSA", signedDigest: testImageDigest, trustOtherKey: true, wantErr: "does not match any public key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, source := newFakeRegistry(t)
+			key := generateKey(t, tt.keyType)
+			payload := cosignPayloadFor(tt.signedDigest)
+			registry.putCosignSignature(testImageDigest, registry.putBlob(payload), map[string]string{
+				cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signMessage(t, key, payload)),
+			})
+
+			trusted := key
+			if tt.trustOtherKey {
+				trusted = generateKey(t, tt.keyType)
+			}
+			policy := ecrv1beta1.ImageVerificationPolicy{Spec: ecrv1beta1.ImageVerificationPolicySpec{
+				PublicKeys: []string{publicKeyPEM(t, trusted)},
+			}}
+			assertVerification(t, verifyImageSignature(source, testImageDigest, policy), tt.wantErr)
+		})
+	}
+}
+
+func TestVerifyCosignCertificates(t *testing.T) {
+	tests := []struct {
+		name         string
+		keyType      string
+		untrusted    bool
+		expired      bool
+		identity     ecrv1beta1.CertificateIdentity
+		wantErr      string
+		noIdentities bool
+	}{
+		{name: "valid ECDSA certificate", keyType: "ECDSA", identity: ecrv1beta1.CertificateIdentity{Subject: testSubject, Issuer: testIssuer}},
+		{name: "valid RSA certificate", keyType: "RSA", identity: ecrv1beta1.CertificateIdentity{Subject: testSubject, Issuer: testIssuer}},
+		{name: "valid ed25519 certificate", keyType: "ed25519", identity: ecrv1beta1.CertificateIdentity{Subject: testSubject}},
+		{name: "any identity of trusted root", keyType: "ECDSA", noIdentities: true},
+		{name: "untrusted root", keyType: "ECDSA", untrusted: true, identity: ecrv1beta1.CertificateIdentity{Subject: testSubject}, wantErr: "untrusted certificate"},
+		{name: "wrong identity", keyType: "ECDSA", identity: ecrv1beta1.CertificateIdentity{Subject: "https://github.com/org/other/.github/workflows/release.yml@refs/heads/main"}, wantErr: "is not allowed"},
+		{name: "wrong issuer", keyType: "ECDSA", identity: ecrv1beta1.CertificateIdentity{Subject: testSubject, Issuer: "https://accounts.google.com"}, wantErr: "is not allowed"},
+		{name: "expired certificate", keyType: "ECDSA", expired: true, identity: ecrv1beta1.CertificateIdentity{Subject: testSubject, Issuer: testIssuer}, wantErr: "certificate has expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, source := newFakeRegistry(t)
+			key := generateKey(t, tt.keyType)
+			chain := newCertificateChain(t, key, testSubject, testIssuer, tt.expired)
+			payload := cosignPayloadFor(testImageDigest)
+			registry.putCosignSignature(testImageDigest, registry.putBlob(payload), map[string]string{
+				cosignSignatureAnnotation:   base64.StdEncoding.EncodeToString(signMessage(t, key, payload)),
+				cosignCertificateAnnotation: chain.leafPEM,
+				cosignChainAnnotation:       chain.rootPEM,
+			})
+
+			roots := chain.rootPEM
+			if tt.untrusted {
+				roots = newCertificateChain(t, key, testSubject, testIssuer, false).rootPEM
+			}
+			spec := ecrv1beta1.ImageVerificationPolicySpec{CertificateRoots: roots}
+			if !tt.noIdentities {
+				spec.CertificateIdentities = []ecrv1beta1.CertificateIdentity{tt.identity}
+			}
+			assertVerification(t, verifyImageSignature(source, testImageDigest, ecrv1beta1.ImageVerificationPolicy{Spec: spec}), tt.wantErr)
+		})
+	}
+}
+
+func TestVerifyNotationCertificates(t *testing.T) {
+	tests := []struct {
+		name         string
+		keyType      string
+		signedDigest string
+		untrusted    bool
+		expired      bool
+		subject      string
+		wantErr      string
+	}{
+		{name: "valid ECDSA certificate", keyType: "ECDSA", signedDigest: testImageDigest, subject: testSubject},
+		{name: "valid RSA certificate", keyType: "RSA", signedDigest: testImageDigest, subject: testSubject},
+		{name: "wrong payload digest", keyType: "ECDSA", signedDigest: testOtherDigest, subject: testSubject, wantErr: "payload signs " + testOtherDigest},
+		{name: "untrusted root", keyType: "RSA", signedDigest: testImageDigest, untrusted: true, subject: testSubject, wantErr: "untrusted certificate"},
+		{name: "wrong identity", keyType: "ECDSA", signedDigest: testImageDigest, subject: "https://example.com/other", wantErr: "is not allowed"},
+		{name: "expired certificate with earlier signing time", keyType: "RSA", signedDigest: testImageDigest, expired: true, subject: testSubject, wantErr: "certificate has expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, source := newFakeRegistry(t)
+			key := generateKey(t, tt.keyType)
+			chain := newCertificateChain(t, key, testSubject, testIssuer, tt.expired)
+			registry.putNotationSignature(testImageDigest, notationEnvelopeFor(t, key, chain, tt.signedDigest))
+
+			roots := chain.rootPEM
+			if tt.untrusted {
+				roots = newCertificateChain(t, key, testSubject, testIssuer, false).rootPEM
+			}
+			policy := ecrv1beta1.ImageVerificationPolicy{Spec: ecrv1beta1.ImageVerificationPolicySpec{
+				Format:                "Notation",
+				CertificateRoots:      roots,
+				CertificateIdentities: []ecrv1beta1.CertificateIdentity{{Subject: tt.subject}},
+			}}
+			assertVerification(t, verifyImageSignature(source, testImageDigest, policy), tt.wantErr)
+		})
+	}
+}
+
+func TestVerifySignatureBlobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		blob    func(payload []byte) []byte
+		wantErr string
+	}{
+		{name: "oversized blob", blob: func(payload []byte) []byte {
+			return append(payload, bytes.Repeat([]byte(" "), signatureBlobLimit)...)
+		}, wantErr: "exceeds"},
+		{name: "bad blob digest", blob: func(payload []byte) []byte {
+			return bytes.Replace(payload, []byte("cosign"), []byte("COSIGN"), 1)
+		}, wantErr: "does not match"},
+		{name: "missing signature", wantErr: "no Cosign signature found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, source := newFakeRegistry(t)
+			key := generateKey(t, "ECDSA")
+			if tt.blob != nil {
+				payload := cosignPayloadFor(testImageDigest)
+				payloadDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(payload))
+				registry.blobs[payloadDigest] = tt.blob(payload)
+				registry.putCosignSignature(testImageDigest, payloadDigest, map[string]string{
+					cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signMessage(t, key, payload)),
+				})
+			}
+
+			policy := ecrv1beta1.ImageVerificationPolicy{Spec: ecrv1beta1.ImageVerificationPolicySpec{
+				PublicKeys: []string{publicKeyPEM(t, key)},
+			}}
+			assertVerification(t, verifyImageSignature(source, testImageDigest, policy), tt.wantErr)
+		})
+	}
+}
+
+func TestVerifySignatureCanceled(t *testing.T) {
+	registry, source := newFakeRegistry(t)
+	key := generateKey(t, "ECDSA")
+	payload := cosignPayloadFor(testImageDigest)
+	registry.putCosignSignature(testImageDigest, registry.putBlob(payload), map[string]string{
+		cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signMessage(t, key, payload)),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	source.ctx = ctx
+
+	policy := ecrv1beta1.ImageVerificationPolicy{Spec: ecrv1beta1.ImageVerificationPolicySpec{
+		PublicKeys: []string{publicKeyPEM(t, key)},
+	}}
+	assertVerification(t, verifyImageSignature(source, testImageDigest, policy), "context canceled")
+}
+
+func assertVerification(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	if wantErr == "" {
+		if err != nil {
+			t.Fatalf("expected valid signature, got %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatalf("expected error containing %q, got valid signature", wantErr)
+	}
+	if !strings.Contains(err.Error(), wantErr) {
+		t.Fatalf("expected error containing %q, got %v", wantErr, err)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The imageUsage describes a container image referenced by a Pod or workload template
+type imageUsage struct {
+	// the image as specified in the container
+	Image string
+	// the digest of the image run by a Pod, if known
+	Digest string
+	// the kind of the Pod or workload, e.g. Pod or Deployment
+	Kind      string
+	Namespace string
+	Name      string
+	Container string
+}
+
+// Returns the workload in the form kind/namespace/name
+func (u imageUsage) Workload() string {
+	return u.Kind + "/" + u.Namespace + "/" + u.Name
+}
+
+// Collects the container images of all Pods and workload templates of the cluster
+func collectImageUsages(ctx context.Context, c client.Client) ([]imageUsage, error) {
+	usages := make([]imageUsage, 0)
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		// the digest of running containers is only known from the container status
+		digests := make(map[string]string)
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			digests[status.Name] = imageIDDigest(status.ImageID)
+		}
+		usages = appendImageUsages(usages, "Pod", pod.Namespace, pod.Name, &pod.Spec, digests)
+	}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments); err != nil {
+		return nil, err
+	}
+	for _, d := range deployments.Items {
+		usages = appendImageUsages(usages, "Deployment", d.Namespace, d.Name, &d.Spec.Template.Spec, nil)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := c.List(ctx, statefulSets); err != nil {
+		return nil, err
+	}
+	for _, s := range statefulSets.Items {
+		usages = appendImageUsages(usages, "StatefulSet", s.Namespace, s.Name, &s.Spec.Template.Spec, nil)
+	}
+
+	daemonSets := &appsv1.DaemonSetList{}
+	if err := c.List(ctx, daemonSets); err != nil {
+		return nil, err
+	}
+	for _, d := range daemonSets.Items {
+		usages = appendImageUsages(usages, "DaemonSet", d.Namespace, d.Name, &d.Spec.Template.Spec, nil)
+	}
+
+	cronJobs := &batchv1.CronJobList{}
+	if err := c.List(ctx, cronJobs); err != nil {
+		return nil, err
+	}
+	for _, j := range cronJobs.Items {
+		usages = appendImageUsages(usages, "CronJob", j.Namespace, j.Name, &j.Spec.JobTemplate.Spec.Template.Spec, nil)
+	}
+
+	return usages, nil
+}
+
+func appendImageUsages(usages []imageUsage, kind string, namespace string, name string, spec *corev1.PodSpec, digests map[string]string) []imageUsage {
+	for _, container := range append(spec.InitContainers, spec.Containers...) {
+		usages = append(usages, imageUsage{
+			Image:     container.Image,
+			Digest:    digests[container.Name],
+			Kind:      kind,
+			Namespace: namespace,
+			Name:      name,
+			Container: container.Name,
+		})
+	}
+	return usages
+}
+
+// Extracts the manifest digest of a container status image ID, e.g. docker-pullable://repository@sha256:...
+func imageIDDigest(imageID string) string {
+	if i := strings.LastIndex(imageID, "@"); i >= 0 {
+		return imageID[i+1:]
+	}
+	return ""
+}
+
+// Returns the in-use digests of the repository with the workloads using them. Tags are
+// resolved to digests using the given tag digests of the repository images.
+func findInUseDigests(usages []imageUsage, repositoryUri string, tagDigests map[string]string) map[string][]string {
+	inUse := make(map[string][]string)
+	for _, usage := range usages {
+		ref := ParseImageReference(usage.Image)
+		if ref.Registry+"/"+ref.Repository != repositoryUri {
+			continue
+		}
+
+		digest := usage.Digest
+		if digest == "" {
+			digest = ref.Digest
+		}
+		if digest == "" {
+			tag := ref.Tag
+			if tag == "" {
+				tag = "latest"
+			}
+			digest = tagDigests[tag]
+		}
+		if digest == "" {
+			continue
+		}
+
+		workload := usage.Workload()
+		if !containsString(inUse[digest], workload) {
+			inUse[digest] = append(inUse[digest], workload)
+		}
+	}
+	return inUse
+}
@@ -0,0 +1,468 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	// the default interval to run the cleanup
+	defaultImageCleanupInterval = time.Duration(1) * time.Hour
+	// the default minimum age of images to delete
+	defaultImageCleanupMinAge = time.Duration(30*24) * time.Hour
+	// the maximum number of images per BatchDeleteImage call
+	imageCleanupBatchSize = 100
+	// the maximum number of candidates listed in the status
+	imageCleanupCandidateLimit = 100
+)
+
+// ImageCleanupPolicyReconciler reconciles a ImageCleanupPolicy object
+type ImageCleanupPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagecleanuppolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagecleanuppolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagecleanuppolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImageCleanupPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("imageCleanupPolicy", req.NamespacedName)
+
+	// lookup the ImageCleanupPolicy instance for this reconcile request
+	cleanupPolicy := &ecrv1beta1.ImageCleanupPolicy{}
+	geterr := r.Get(ctx, req.NamespacedName, cleanupPolicy)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ImageCleanupPolicy already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get ImageCleanupPolicy.")
+		return ctrl.Result{}, geterr
+	}
+
+	interval := defaultImageCleanupInterval
+	if cleanupPolicy.Spec.Interval != nil && cleanupPolicy.Spec.Interval.Duration > 0 {
+		interval = cleanupPolicy.Spec.Interval.Duration
+	}
+
+	status := cleanupPolicy.Status.DeepCopy()
+	cleanupPolicy.Status.LastRunTime = &metav1.Time{Time: time.Now()}
+	cleanupPolicy.Status.Message = ""
+	if err := r.cleanupImages(ctx, cleanupPolicy); err != nil {
+		cleanupPolicy.Status.Message = err.Error()
+	}
+
+	if !equality.Semantic.DeepEqual(status, &cleanupPolicy.Status) {
+		if err := r.Status().Update(ctx, cleanupPolicy); err != nil {
+			logger.Error(err, "Failed to update ImageCleanupPolicy status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// Selects the images to delete and deletes them unless in dry-run. No image is deleted if the
+// images in use by any of the clusters can not be determined.
+func (r *ImageCleanupPolicyReconciler) cleanupImages(ctx context.Context, cleanupPolicy *ecrv1beta1.ImageCleanupPolicy) error {
+	logger := ctrllog.FromContext(ctx)
+	spec := cleanupPolicy.Spec
+
+	repository := &ecrv1beta1.Repository{}
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: spec.RepositoryName, Namespace: cleanupPolicy.Namespace}, repository); err != nil {
+		logger.Error(err, "Failed to get Repository.")
+		return fmt.Errorf("unable to get Repository %s: %w", spec.RepositoryName, err)
+	}
+	if repository.Status.RepositoryUri == "" {
+		return fmt.Errorf("Repository %s has not been created yet", spec.RepositoryName)
+	}
+
+	tagPatterns, err := compileTagPatterns(spec.TagPatterns)
+	if err != nil {
+		return err
+	}
+	keepTagPatterns, err := compileTagPatterns(spec.KeepTagPatterns)
+	if err != nil {
+		return err
+	}
+
+	usages, err := r.collectClusterImageUsages(ctx, cleanupPolicy)
+	if err != nil {
+		logger.Error(err, "Failed to collect image usages.")
+		return err
+	}
+
+	ecrClient, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return awserr
+	}
+
+	images, err := describeAllImages(ecrClient, ecrRepositoryName(repository))
+	if err != nil {
+		logger.Error(err, "Failed to describe images.")
+		return err
+	}
+
+	tagDigests := make(map[string]string)
+	for _, image := range images {
+		for _, tag := range image.ImageTags {
+			tagDigests[tag] = aws.ToString(image.ImageDigest)
+		}
+	}
+	inUse := findInUseDigests(usages, repository.Status.RepositoryUri, tagDigests)
+
+	minAge := defaultImageCleanupMinAge
+	if spec.MinAge != nil {
+		minAge = spec.MinAge.Duration
+	}
+	children, err := describeIndexChildren(ecrClient, ecrRepositoryName(repository), images)
+	if err != nil {
+		logger.Error(err, "Failed to describe image indexes.")
+		return err
+	}
+	candidates := selectCleanupCandidates(images, inUse, children, spec.KeepLast, time.Now().Add(-minAge), tagPatterns, keepTagPatterns)
+
+	cleanupPolicy.Status.InUseCount = len(inUse)
+	cleanupPolicy.Status.CandidateCount = len(candidates)
+	cleanupPolicy.Status.DeletedCount = 0
+	cleanupPolicy.Status.Candidates = createCleanupCandidates(candidates)
+
+	if spec.DryRun || len(candidates) == 0 {
+		return nil
+	}
+
+	batchSize := spec.BatchSize
+	if batchSize <= 0 || batchSize > imageCleanupBatchSize {
+		batchSize = imageCleanupBatchSize
+	}
+	deleted, err := deleteImagesInBatches(ecrClient, ecrRepositoryName(repository), candidates, batchSize)
+	cleanupPolicy.Status.DeletedCount = deleted
+	logger.Info("Deleted images.", "repository", ecrRepositoryName(repository), "count", deleted)
+	return err
+}
+
+// Collects the image usages of the local cluster and of the remote clusters given by their kubeconfig Secrets
+func (r *ImageCleanupPolicyReconciler) collectClusterImageUsages(ctx context.Context, cleanupPolicy *ecrv1beta1.ImageCleanupPolicy) ([]imageUsage, error) {
+	usages, err := collectImageUsages(ctx, r.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ref := range cleanupPolicy.Spec.RemoteClusters {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, k8stypes.NamespacedName{Name: ref.Name, Namespace: cleanupPolicy.Namespace}, secret); err != nil {
+			return nil, fmt.Errorf("unable to get kubeconfig Secret %s: %w", ref.Name, err)
+		}
+		kubeconfig, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, fmt.Errorf("key %s not found in Secret %s", ref.Key, ref.Name)
+		}
+
+		config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kubeconfig in Secret %s: %w", ref.Name, err)
+		}
+		remoteClient, err := client.New(config, client.Options{Scheme: r.Scheme})
+		if err != nil {
+			return nil, fmt.Errorf("unable to create client for Secret %s: %w", ref.Name, err)
+		}
+
+		remoteUsages, err := collectImageUsages(ctx, remoteClient)
+		if err != nil {
+			return nil, fmt.Errorf("unable to collect image usages of cluster in Secret %s: %w", ref.Name, err)
+		}
+		usages = append(usages, remoteUsages...)
+	}
+	return usages, nil
+}
+
+// Selects the images to delete from the images sorted newest first. The most recently pushed
+// top-level images, neither children of an image index nor artifacts of another image, images in use, images pushed after the cutoff and images with kept tags are not selected.
+// The child manifests of kept image indexes and the signatures, attestations and SBOMs tagged with
+// sha256-<digest> of kept images are kept as well. The candidates are returned oldest first.
+func selectCleanupCandidates(images []types.ImageDetail, inUse map[string][]string, children map[string][]string, keepLast int, cutoff time.Time, tagPatterns []*regexp.Regexp, keepTagPatterns []*regexp.Regexp) []types.ImageDetail {
+	parents := make(map[string][]string)
+	for parent, digests := range children {
+		for _, child := range digests {
+			parents[child] = append(parents[child], parent)
+		}
+	}
+
+	kept := make(map[string]bool, len(images))
+	topLevel := 0
+	for _, image := range images {
+		digest := aws.ToString(image.ImageDigest)
+		if len(parents[digest]) == 0 && len(artifactSubjects(image.ImageTags)) == 0 {
+			topLevel++
+			if topLevel <= keepLast {
+				kept[digest] = true
+				continue
+			}
+		}
+		if _, ok := inUse[digest]; ok {
+			kept[digest] = true
+			continue
+		}
+		if !aws.ToTime(image.ImagePushedAt).Before(cutoff) {
+			kept[digest] = true
+			continue
+		}
+		if matchesAnyTag(image.ImageTags, keepTagPatterns) {
+			kept[digest] = true
+			continue
+		}
+		if len(tagPatterns) > 0 && !matchesAllTags(image.ImageTags, tagPatterns) {
+			kept[digest] = true
+		}
+	}
+
+	// keeping an image may keep its own children and artifacts, so repeat until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, image := range images {
+			digest := aws.ToString(image.ImageDigest)
+			if kept[digest] {
+				continue
+			}
+			if anyKept(parents[digest], kept) || anyKept(artifactSubjects(image.ImageTags), kept) {
+				kept[digest] = true
+				changed = true
+			}
+		}
+	}
+
+	candidates := make([]types.ImageDetail, 0)
+	for i := len(images) - 1; i >= 0; i-- {
+		if !kept[aws.ToString(images[i].ImageDigest)] {
+			candidates = append(candidates, images[i])
+		}
+	}
+	return candidates
+}
+
+// Returns the child manifest digests of the image indexes, e.g. of multi-arch images, by index digest
+func describeIndexChildren(ecrClient *ecr.Client, repositoryName string, images []types.ImageDetail) (map[string][]string, error) {
+	imageIds := make([]types.ImageIdentifier, 0)
+	for _, image := range images {
+		if isImageIndex(aws.ToString(image.ImageManifestMediaType)) {
+			imageIds = append(imageIds, types.ImageIdentifier{ImageDigest: image.ImageDigest})
+		}
+	}
+
+	children := make(map[string][]string)
+	for start := 0; start < len(imageIds); start += imageCleanupBatchSize {
+		end := start + imageCleanupBatchSize
+		if end > len(imageIds) {
+			end = len(imageIds)
+		}
+
+		output, err := ecrClient.BatchGetImage(context.TODO(), &ecr.BatchGetImageInput{
+			RepositoryName:     aws.String(repositoryName),
+			ImageIds:           imageIds[start:end],
+			AcceptedMediaTypes: acceptedManifestMediaTypes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(output.Failures) > 0 {
+			failure := output.Failures[0]
+			return nil, fmt.Errorf("unable to get image index %s: %s %s", aws.ToString(failure.ImageId.ImageDigest), failure.FailureCode, aws.ToString(failure.FailureReason))
+		}
+
+		for _, image := range output.Images {
+			digest := aws.ToString(image.ImageId.ImageDigest)
+			manifest := imageManifest{}
+			if err := json.Unmarshal([]byte(aws.ToString(image.ImageManifest)), &manifest); err != nil {
+				return nil, fmt.Errorf("unable to parse image index %s: %w", digest, err)
+			}
+			for _, child := range manifest.Manifests {
+				children[digest] = append(children[digest], child.Digest)
+			}
+		}
+	}
+	return children, nil
+}
+
+func isImageIndex(mediaType string) bool {
+	return mediaType == "application/vnd.oci.image.index.v1+json" || mediaType == "application/vnd.docker.distribution.manifest.list.v2+json"
+}
+
+// Returns the digests of the images the sha256-<digest> artifact tags refer to
+func artifactSubjects(tags []string) []string {
+	subjects := make([]string, 0)
+	for _, tag := range tags {
+		if signatureTagPattern.MatchString(tag) {
+			subjects = append(subjects, "sha256:"+tag[len("sha256-"):len("sha256-")+64])
+		}
+	}
+	return subjects
+}
+
+func anyKept(digests []string, kept map[string]bool) bool {
+	for _, digest := range digests {
+		if kept[digest] {
+			return true
+		}
+	}
+	return false
+}
+
+// Deletes the images by digest in batches of the given size and returns the number of deleted images
+func deleteImagesInBatches(ecrClient *ecr.Client, repositoryName string, images []types.ImageDetail, batchSize int) (int, error) {
+	deleted := 0
+	failures := make([]string, 0)
+	for start := 0; start < len(images); start += batchSize {
+		end := start + batchSize
+		if end > len(images) {
+			end = len(images)
+		}
+
+		imageIds := make([]types.ImageIdentifier, 0, end-start)
+		for _, image := range images[start:end] {
+			imageIds = append(imageIds, types.ImageIdentifier{ImageDigest: image.ImageDigest})
+		}
+		output, err := ecrClient.BatchDeleteImage(context.TODO(), &ecr.BatchDeleteImageInput{
+			RepositoryName: aws.String(repositoryName),
+			ImageIds:       imageIds,
+		})
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted += len(output.ImageIds)
+		for _, failure := range output.Failures {
+			failures = append(failures, fmt.Sprintf("%s: %s %s", aws.ToString(failure.ImageId.ImageDigest), failure.FailureCode, aws.ToString(failure.FailureReason)))
+		}
+	}
+
+	if len(failures) > 0 {
+		return deleted, fmt.Errorf("unable to delete %d images: %s", len(failures), strings.Join(failures, "; "))
+	}
+	return deleted, nil
+}
+
+func createCleanupCandidates(images []types.ImageDetail) []ecrv1beta1.CleanupCandidate {
+	if len(images) == 0 {
+		return nil
+	}
+	if len(images) > imageCleanupCandidateLimit {
+		images = images[:imageCleanupCandidateLimit]
+	}
+
+	candidates := make([]ecrv1beta1.CleanupCandidate, 0, len(images))
+	for _, image := range images {
+		candidate := ecrv1beta1.CleanupCandidate{
+			Digest: aws.ToString(image.ImageDigest),
+			Tags:   image.ImageTags,
+		}
+		if image.ImagePushedAt != nil {
+			candidate.PushedAt = &metav1.Time{Time: *image.ImagePushedAt}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
+
+func compileTagPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Checks whether any of the tags matches any of the patterns
+func matchesAnyTag(tags []string, patterns []*regexp.Regexp) bool {
+	for _, tag := range tags {
+		for _, pattern := range patterns {
+			if pattern.MatchString(tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checks whether each of the tags matches one of the patterns, which is true for untagged images
+func matchesAllTags(tags []string, patterns []*regexp.Regexp) bool {
+	for _, tag := range tags {
+		if !matchesAnyTag([]string{tag}, patterns) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageCleanupPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.ImageCleanupPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
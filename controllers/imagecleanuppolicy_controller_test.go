@@ -0,0 +1,107 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func testDigest(i int) string {
+	return fmt.Sprintf("sha256:%064d", i)
+}
+
+// Returns an image pushed the given number of hours ago with the tags
+func testImage(i int, hoursAgo int, tags ...string) types.ImageDetail {
+	return types.ImageDetail{
+		ImageDigest:   aws.String(testDigest(i)),
+		ImagePushedAt: aws.Time(time.Now().Add(-time.Duration(hoursAgo) * time.Hour)),
+		ImageTags:     tags,
+	}
+}
+
+func TestSelectCleanupCandidatesKeepLast(t *testing.T) {
+	signatureTag := func(i int) string {
+		return fmt.Sprintf("sha256-%064d.sig", i)
+	}
+
+	tests := []struct {
+		name     string
+		images   []types.ImageDetail
+		children map[string][]string
+		keepLast int
+		want     []string
+	}{
+		{
+			name:     "keeps the most recent images",
+			images:   []types.ImageDetail{testImage(1, 10, "v3"), testImage(2, 20, "v2"), testImage(3, 30, "v1")},
+			keepLast: 2,
+			want:     []string{testDigest(3)},
+		},
+		{
+			name: "does not count signatures",
+			images: []types.ImageDetail{
+				testImage(10, 9, signatureTag(1)), testImage(1, 10, "v3"),
+				testImage(20, 19, signatureTag(2)), testImage(2, 20, "v2"),
+				testImage(30, 29, signatureTag(3)), testImage(3, 30, "v1"),
+			},
+			keepLast: 2,
+			want:     []string{testDigest(3), testDigest(30)},
+		},
+		{
+			name: "does not count index children",
+			images: []types.ImageDetail{
+				testImage(11, 10), testImage(12, 10), testImage(1, 10, "v3"),
+				testImage(21, 20), testImage(22, 20), testImage(2, 20, "v2"),
+				testImage(31, 30), testImage(32, 30), testImage(3, 30, "v1"),
+			},
+			children: map[string][]string{
+				testDigest(1): {testDigest(11), testDigest(12)},
+				testDigest(2): {testDigest(21), testDigest(22)},
+				testDigest(3): {testDigest(31), testDigest(32)},
+			},
+			keepLast: 2,
+			want:     []string{testDigest(3), testDigest(32), testDigest(31)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates := selectCleanupCandidates(tt.images, map[string][]string{}, tt.children, tt.keepLast, time.Now(), nil, nil)
+			digests := make([]string, 0, len(candidates))
+			for _, candidate := range candidates {
+				digests = append(digests, aws.ToString(candidate.ImageDigest))
+			}
+			if !reflect.DeepEqual(digests, tt.want) {
+				t.Fatalf("expected candidates %v, got %v", tt.want, digests)
+			}
+		})
+	}
+}
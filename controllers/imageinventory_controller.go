@@ -0,0 +1,336 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	// the default interval to refresh the image inventory
+	defaultImageInventoryInterval = time.Duration(10) * time.Minute
+	// the maximum number of images listed in the status
+	inventoryImageLimit = 200
+	// the maximum number of workloads listed per image in the status
+	inventoryWorkloadLimit = 10
+)
+
+// ImageInventoryReconciler reconciles a ImageInventory object
+type ImageInventoryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageinventories,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageinventories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageinventories/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImageInventoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("imageInventory", req.NamespacedName)
+
+	// lookup the ImageInventory instance for this reconcile request
+	inventory := &ecrv1beta1.ImageInventory{}
+	geterr := r.Get(ctx, req.NamespacedName, inventory)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ImageInventory already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get ImageInventory.")
+		return ctrl.Result{}, geterr
+	}
+
+	interval := defaultImageInventoryInterval
+	if inventory.Spec.Interval != nil && inventory.Spec.Interval.Duration > 0 {
+		interval = inventory.Spec.Interval.Duration
+	}
+
+	status := inventory.Status.DeepCopy()
+	if err := r.updateInventory(ctx, inventory); err != nil {
+		logger.Error(err, "Failed to refresh ImageInventory.")
+		inventory.Status.Message = err.Error()
+	} else {
+		inventory.Status.LastUpdatedTime = &metav1.Time{Time: time.Now()}
+		inventory.Status.Message = ""
+	}
+
+	if !equality.Semantic.DeepEqual(status, &inventory.Status) {
+		if err := r.Status().Update(ctx, inventory); err != nil {
+			logger.Error(err, "Failed to update ImageInventory status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// Maps the running containers of the cluster to the images of the managed repositories
+func (r *ImageInventoryReconciler) updateInventory(ctx context.Context, inventory *ecrv1beta1.ImageInventory) error {
+	managed, err := findManagedRepositories(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		return err
+	}
+
+	// the running containers of each repository
+	containers := make(map[string][]runningContainer)
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+
+		// the image as specified in the container, the status may report a resolved image name
+		specImages := make(map[string]string)
+		for _, container := range pod.Spec.Containers {
+			specImages[container.Name] = container.Image
+		}
+
+		var kind, name string
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Running == nil {
+				continue
+			}
+			ref := ParseImageReference(specImages[status.Name])
+			uri := ref.Registry + "/" + ref.Repository
+			if _, ok := managed[uri]; !ok {
+				continue
+			}
+
+			if kind == "" {
+				kind, name = r.podWorkload(ctx, &pod)
+			}
+			containers[uri] = append(containers[uri], runningContainer{
+				ref:    ref,
+				digest: imageIDDigest(status.ImageID),
+				workload: ecrv1beta1.InventoryWorkload{
+					Namespace: pod.Namespace,
+					Kind:      kind,
+					Name:      name,
+					Container: status.Name,
+				},
+			})
+		}
+	}
+
+	ecrClient, awserr := CreateEcrClient()
+	if awserr != nil {
+		return awserr
+	}
+
+	images := make([]ecrv1beta1.InventoryImage, 0)
+	containerCount := 0
+	for uri, running := range containers {
+		repository := managed[uri]
+		details, err := describeAllImages(ecrClient, ecrRepositoryName(&repository))
+		if err != nil {
+			return err
+		}
+		images = append(images, createInventoryImages(repository, details, running)...)
+		containerCount += len(running)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if images[i].Repository != images[j].Repository {
+			return images[i].Repository < images[j].Repository
+		}
+		return images[i].Digest < images[j].Digest
+	})
+	inventory.Status.ImageCount = len(images)
+	inventory.Status.ContainerCount = containerCount
+	if len(images) > inventoryImageLimit {
+		images = images[:inventoryImageLimit]
+	}
+	inventory.Status.Images = images
+	return nil
+}
+
+// A runningContainer is a container running an image of a managed repository
+type runningContainer struct {
+	ref      ImageReference
+	digest   string
+	workload ecrv1beta1.InventoryWorkload
+}
+
+// Groups the running containers by image digest and adds the tag, push date and scan summary of the images
+func createInventoryImages(repository ecrv1beta1.Repository, details []types.ImageDetail, running []runningContainer) []ecrv1beta1.InventoryImage {
+	byDigest := make(map[string]types.ImageDetail)
+	tagDigests := make(map[string]string)
+	for _, detail := range details {
+		byDigest[aws.ToString(detail.ImageDigest)] = detail
+		for _, tag := range detail.ImageTags {
+			tagDigests[tag] = aws.ToString(detail.ImageDigest)
+		}
+	}
+
+	images := make(map[string]*ecrv1beta1.InventoryImage)
+	for _, container := range running {
+		tag := container.ref.Tag
+		if tag == "" && container.ref.Digest == "" {
+			tag = "latest"
+		}
+		digest := container.digest
+		if digest == "" {
+			digest = container.ref.Digest
+		}
+		if digest == "" {
+			digest = tagDigests[tag]
+		}
+		if digest == "" {
+			continue
+		}
+
+		image, ok := images[digest]
+		if !ok {
+			image = &ecrv1beta1.InventoryImage{
+				Repository:    repository.Namespace + "/" + repository.Name,
+				RepositoryUri: repository.Status.RepositoryUri,
+				Digest:        digest,
+				Tag:           tag,
+				Workloads:     make([]ecrv1beta1.InventoryWorkload, 0),
+			}
+			if detail, ok := byDigest[digest]; ok {
+				if image.Tag == "" && len(detail.ImageTags) > 0 {
+					image.Tag = detail.ImageTags[0]
+				}
+				if detail.ImagePushedAt != nil {
+					image.PushedAt = &metav1.Time{Time: *detail.ImagePushedAt}
+				}
+				if detail.ImageScanFindingsSummary != nil {
+					summary := createVulnerabilitySummary(detail.ImageScanFindingsSummary.FindingSeverityCounts)
+					image.Summary = &summary
+				}
+			}
+			images[digest] = image
+		}
+		// the replicas of a workload run the same container, so each container is listed once
+		if !containsWorkload(image.Workloads, container.workload) {
+			image.Workloads = append(image.Workloads, container.workload)
+		}
+	}
+
+	result := make([]ecrv1beta1.InventoryImage, 0, len(images))
+	for _, image := range images {
+		sort.Slice(image.Workloads, func(i, j int) bool {
+			a, b := image.Workloads[i], image.Workloads[j]
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			if a.Kind != b.Kind {
+				return a.Kind < b.Kind
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return a.Container < b.Container
+		})
+		image.WorkloadCount = len(image.Workloads)
+		if len(image.Workloads) > inventoryWorkloadLimit {
+			image.Workloads = image.Workloads[:inventoryWorkloadLimit]
+		}
+		result = append(result, *image)
+	}
+	return result
+}
+
+func containsWorkload(workloads []ecrv1beta1.InventoryWorkload, workload ecrv1beta1.InventoryWorkload) bool {
+	for _, w := range workloads {
+		if w == workload {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the kind and name of the workload owning the Pod, following ReplicaSets to their
+// Deployment and Jobs to their CronJob. Pods without an owner are returned themselves.
+func (r *ImageInventoryReconciler) podWorkload(ctx context.Context, pod *corev1.Pod) (string, string) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+
+	var parent client.Object
+	switch owner.Kind {
+	case "ReplicaSet":
+		parent = &appsv1.ReplicaSet{}
+	case "Job":
+		parent = &batchv1.Job{}
+	default:
+		return owner.Kind, owner.Name
+	}
+
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: owner.Name, Namespace: pod.Namespace}, parent); err != nil {
+		return owner.Kind, owner.Name
+	}
+	if parentOwner := metav1.GetControllerOf(parent); parentOwner != nil {
+		return parentOwner.Kind, parentOwner.Name
+	}
+	return owner.Kind, owner.Name
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageInventoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.ImageInventory{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,268 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	// the interval to retry a failed promotion, e.g. if the source image is not pushed yet
+	imagePromotionRetryInterval = time.Duration(1) * time.Minute
+	// the timeout to download a single image layer
+	layerDownloadTimeout = time.Duration(10) * time.Minute
+)
+
+// ImagePromotionReconciler reconciles a ImagePromotion object
+type ImagePromotionReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagepromotions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagepromotions/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagepromotions/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagepromotionpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImagePromotionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("imagePromotion", req.NamespacedName)
+
+	// lookup the ImagePromotion instance for this reconcile request
+	imagePromotion := &ecrv1beta1.ImagePromotion{}
+	geterr := r.Get(ctx, req.NamespacedName, imagePromotion)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ImagePromotion already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get ImagePromotion.")
+		return ctrl.Result{}, geterr
+	}
+
+	// a succeeded promotion is only performed again if the spec changes
+	if imagePromotion.Status.Phase == ecrv1beta1.PromotionPhaseSucceeded && imagePromotion.Status.ObservedGeneration == imagePromotion.Generation {
+		return ctrl.Result{}, nil
+	}
+
+	spec := imagePromotion.Spec
+	if spec.Source.ImageTag == "" && spec.Source.ImageDigest == "" {
+		return r.failImagePromotion(ctx, logger, imagePromotion, "either source imageTag or imageDigest is required")
+	}
+
+	source, sourceRegion, err := r.resolveLocation(ctx, imagePromotion.Namespace, spec.Source.RepositoryName, spec.Source.RegistryId, spec.Source.Region, false)
+	if err != nil {
+		return r.failImagePromotion(ctx, logger, imagePromotion, err.Error())
+	}
+	destination, destinationRegion, err := r.resolveLocation(ctx, imagePromotion.Namespace, spec.Destination.RepositoryName, spec.Destination.RegistryId, spec.Destination.Region, true)
+	if err != nil {
+		return r.failImagePromotion(ctx, logger, imagePromotion, err.Error())
+	}
+
+	copier := &imageCopier{
+		logger:      logger,
+		source:      source,
+		destination: destination,
+		httpClient:  &http.Client{Timeout: layerDownloadTimeout},
+	}
+
+	imageId := &types.ImageIdentifier{}
+	if spec.Source.ImageDigest != "" {
+		imageId.ImageDigest = aws.String(spec.Source.ImageDigest)
+	} else {
+		imageId.ImageTag = aws.String(spec.Source.ImageTag)
+	}
+	image, err := copier.getImage(imageId)
+	if err != nil {
+		return r.failImagePromotion(ctx, logger, imagePromotion, err.Error())
+	}
+
+	// within a registry copying the manifest is sufficient if the layers are available,
+	// across registries the layers are always transferred
+	copyLayers := source.registryId != destination.registryId || sourceRegion != destinationRegion
+	err = copier.copyImage(image, spec.Destination.Tags, copyLayers)
+	if err != nil && !copyLayers && isMissingImageReferences(err) {
+		logger.Info("Image layers not available in destination repository. Transferring layers.")
+		copyLayers = true
+		err = copier.copyImage(image, spec.Destination.Tags, copyLayers)
+	}
+	if err != nil {
+		return r.failImagePromotion(ctx, logger, imagePromotion, err.Error())
+	}
+
+	logger.Info("Promoted image.", "imageDigest", image.ImageId.ImageDigest, "tags", spec.Destination.Tags)
+	imagePromotion.Status.Phase = ecrv1beta1.PromotionPhaseSucceeded
+	imagePromotion.Status.Message = ""
+	imagePromotion.Status.ImageDigest = aws.ToString(image.ImageId.ImageDigest)
+	imagePromotion.Status.PromotedAt = &metav1.Time{Time: time.Now()}
+	imagePromotion.Status.LayersTransferred = copyLayers
+	imagePromotion.Status.ObservedGeneration = imagePromotion.Generation
+	if err := r.Status().Update(ctx, imagePromotion); err != nil {
+		logger.Error(err, "Failed to update ImagePromotion status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ImagePromotionReconciler) failImagePromotion(ctx context.Context, logger logr.Logger, imagePromotion *ecrv1beta1.ImagePromotion, message string) (ctrl.Result, error) {
+	logger.Info("Image promotion failed.", "reason", message)
+	imagePromotion.Status.Phase = ecrv1beta1.PromotionPhaseFailed
+	imagePromotion.Status.Message = message
+	imagePromotion.Status.ObservedGeneration = imagePromotion.Generation
+	if err := r.Status().Update(ctx, imagePromotion); err != nil {
+		logger.Error(err, "Failed to update ImagePromotion status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: imagePromotionRetryInterval}, nil
+}
+
+// Resolves the ECR repository and region of a promotion source or destination. Without registryId and region the
+// name references a Repository object in the namespace, so a namespace can only promote images of its own
+// repositories. Otherwise the name is an ECR repository name, which must be allowed by an ImagePromotionPolicy.
+func (r *ImagePromotionReconciler) resolveLocation(ctx context.Context, namespace string, repositoryName string, registryId string, region string, destination bool) (ecrLocation, string, error) {
+	if registryId == "" && region == "" {
+		repository := &ecrv1beta1.Repository{}
+		if err := r.Get(ctx, k8stypes.NamespacedName{Name: repositoryName, Namespace: namespace}, repository); err != nil {
+			if k8serrors.IsNotFound(err) {
+				return ecrLocation{}, "", fmt.Errorf("Repository %s not found", repositoryName)
+			}
+			return ecrLocation{}, "", err
+		}
+		// the ARN is only set once the repository was created and is owned by the Repository
+		if repository.Status.RepositoryArn == "" {
+			return ecrLocation{}, "", fmt.Errorf("Repository %s not created yet", repositoryName)
+		}
+
+		client, err := CreateEcrClient()
+		if err != nil {
+			return ecrLocation{}, "", err
+		}
+		location := ecrLocation{client: client, registryId: repository.Status.RegistryId, repositoryName: ecrRepositoryName(repository)}
+		return location, client.Options().Region, nil
+	}
+
+	allowed, err := r.isPromotionAllowed(ctx, namespace, ecrv1beta1.PromotionRepository{RegistryId: registryId, Region: region, RepositoryName: repositoryName}, destination)
+	if err != nil {
+		return ecrLocation{}, "", err
+	}
+	if !allowed {
+		direction := "source"
+		if destination {
+			direction = "destination"
+		}
+		return ecrLocation{}, "", fmt.Errorf("%s repository %s is not allowed by an ImagePromotionPolicy", direction, repositoryName)
+	}
+
+	client, err := createEcrClientForOptionalRegion(region)
+	if err != nil {
+		return ecrLocation{}, "", err
+	}
+	return ecrLocation{client: client, registryId: registryId, repositoryName: repositoryName}, client.Options().Region, nil
+}
+
+// Checks whether an ImagePromotionPolicy selecting the namespace allows the repository as source or destination
+func (r *ImagePromotionReconciler) isPromotionAllowed(ctx context.Context, namespace string, repository ecrv1beta1.PromotionRepository, destination bool) (bool, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+	policies := &ecrv1beta1.ImagePromotionPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return false, err
+	}
+
+	for _, policy := range policies.Items {
+		if policy.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+			if err != nil || !selector.Matches(labels.Set(ns.Labels)) {
+				continue
+			}
+		}
+
+		allowed := policy.Spec.Sources
+		if destination {
+			allowed = policy.Spec.Destinations
+		}
+		for _, a := range allowed {
+			if promotionRepositoryMatches(a, repository) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func promotionRepositoryMatches(allowed ecrv1beta1.PromotionRepository, repository ecrv1beta1.PromotionRepository) bool {
+	return allowed.RegistryId == repository.RegistryId &&
+		(allowed.Region == "" || allowed.Region == repository.Region) &&
+		wildcardMatches(allowed.RepositoryName, repository.RepositoryName, false)
+}
+
+// Creates an ECR client for the given region, or the default region if empty
+func createEcrClientForOptionalRegion(region string) (*ecr.Client, error) {
+	if region == "" {
+		return CreateEcrClient()
+	}
+	return CreateEcrClientForRegion(region)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImagePromotionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.ImagePromotion{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,89 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+func TestPromotionRepositoryMatches(t *testing.T) {
+	allowed := ecrv1beta1.PromotionRepository{RegistryId: "450802564356", Region: "eu-west-1", RepositoryName: "prod/*"}
+
+	tests := []struct {
+		name       string
+		allowed    ecrv1beta1.PromotionRepository
+		repository ecrv1beta1.PromotionRepository
+		want       bool
+	}{
+		{
+			name:       "matching repository",
+			allowed:    allowed,
+			repository: ecrv1beta1.PromotionRepository{RegistryId: "450802564356", Region: "eu-west-1", RepositoryName: "prod/app"},
+			want:       true,
+		},
+		{
+			name:       "other repository",
+			allowed:    allowed,
+			repository: ecrv1beta1.PromotionRepository{RegistryId: "450802564356", Region: "eu-west-1", RepositoryName: "dev/app"},
+		},
+		{
+			name:       "other account",
+			allowed:    allowed,
+			repository: ecrv1beta1.PromotionRepository{RegistryId: "123456789012", Region: "eu-west-1", RepositoryName: "prod/app"},
+		},
+		{
+			name:       "other region",
+			allowed:    allowed,
+			repository: ecrv1beta1.PromotionRepository{RegistryId: "450802564356", Region: "us-east-1", RepositoryName: "prod/app"},
+		},
+		{
+			name:       "any region",
+			allowed:    ecrv1beta1.PromotionRepository{RegistryId: "450802564356", RepositoryName: "prod/*"},
+			repository: ecrv1beta1.PromotionRepository{RegistryId: "450802564356", Region: "us-east-1", RepositoryName: "prod/app"},
+			want:       true,
+		},
+		{
+			name:       "registry of the operator",
+			allowed:    ecrv1beta1.PromotionRepository{Region: "us-east-1", RepositoryName: "prod/app"},
+			repository: ecrv1beta1.PromotionRepository{Region: "us-east-1", RepositoryName: "prod/app"},
+			want:       true,
+		},
+		{
+			name:       "registry of the operator not allowed",
+			allowed:    ecrv1beta1.PromotionRepository{Region: "us-east-1", RepositoryName: "prod/app"},
+			repository: ecrv1beta1.PromotionRepository{RegistryId: "450802564356", Region: "us-east-1", RepositoryName: "prod/app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promotionRepositoryMatches(tt.allowed, tt.repository); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
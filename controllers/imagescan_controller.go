@@ -0,0 +1,303 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+	"github.com/robfig/cron/v3"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	ecrRescanLabel = "ecr.aws.cloud.qaware.de/scheduled-rescan"
+	// the interval to poll the status of a running scan
+	imageScanPollInterval = time.Duration(15) * time.Second
+	// ECR allows one scan per image every 24 hours
+	imageScanMinInterval = time.Duration(24) * time.Hour
+)
+
+// ImageScanReconciler reconciles a ImageScan object
+type ImageScanReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagescans,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagescans/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagescans/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImageScanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("imageScan", req.NamespacedName)
+
+	// lookup the ImageScan instance for this reconcile request
+	imageScan := &ecrv1beta1.ImageScan{}
+	geterr := r.Get(ctx, req.NamespacedName, imageScan)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ImageScan already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get ImageScan.")
+		return ctrl.Result{}, geterr
+	}
+
+	// a finished scan is never started again
+	if isImageScanFinished(imageScan.Status.ScanStatus) {
+		return ctrl.Result{}, nil
+	}
+
+	if imageScan.Spec.ImageTag == "" && imageScan.Spec.ImageDigest == "" {
+		return r.failImageScan(ctx, logger, imageScan, "either imageTag or imageDigest is required")
+	}
+
+	// only repositories of the same namespace can be scanned
+	repository := &ecrv1beta1.Repository{}
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: imageScan.Spec.RepositoryName, Namespace: imageScan.Namespace}, repository); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return r.failImageScan(ctx, logger, imageScan, fmt.Sprintf("Repository %s not found", imageScan.Spec.RepositoryName))
+		}
+		logger.Error(err, "Failed to get Repository.")
+		return ctrl.Result{}, err
+	}
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	if imageScan.Status.StartedAt == nil {
+		output, err := client.StartImageScan(context.TODO(), &ecr.StartImageScanInput{
+			RepositoryName: aws.String(ecrRepositoryName(repository)),
+			ImageId:        createImageIdentifier(imageScan),
+		})
+		if err != nil {
+			var lee *types.LimitExceededException
+			var infe *types.ImageNotFoundException
+			var rnfe *types.RepositoryNotFoundException
+			var uite *types.UnsupportedImageTypeException
+			if errors.As(err, &lee) {
+				return r.failImageScan(ctx, logger, imageScan, fmt.Sprintf("image can only be scanned once every 24 hours: %s", lee.ErrorMessage()))
+			} else if errors.As(err, &infe) || errors.As(err, &rnfe) || errors.As(err, &uite) {
+				return r.failImageScan(ctx, logger, imageScan, err.Error())
+			}
+
+			logger.Error(err, "Could not start image scan.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+
+		logger.Info("Started image scan.", "imageId", output.ImageId)
+		imageScan.Status.StartedAt = &metav1.Time{Time: time.Now()}
+		imageScan.Status.ImageDigest = aws.ToString(output.ImageId.ImageDigest)
+		imageScan.Status.ScanStatus = ecrv1beta1.ScanStatusInProgress
+		if output.ImageScanStatus != nil {
+			imageScan.Status.Description = aws.ToString(output.ImageScanStatus.Description)
+		}
+		if err := r.Status().Update(ctx, imageScan); err != nil {
+			logger.Error(err, "Failed to update ImageScan status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: imageScanPollInterval}, nil
+	}
+
+	// track the scan until it is COMPLETE or FAILED
+	output, err := client.DescribeImageScanFindings(context.TODO(), &ecr.DescribeImageScanFindingsInput{
+		RepositoryName: aws.String(ecrRepositoryName(repository)),
+		ImageId:        &types.ImageIdentifier{ImageDigest: aws.String(imageScan.Status.ImageDigest)},
+		MaxResults:     aws.Int32(1),
+	})
+	if err != nil {
+		var snfe *types.ScanNotFoundException
+		if errors.As(err, &snfe) {
+			logger.Info("Scan not found yet. Retrying.")
+			return ctrl.Result{RequeueAfter: imageScanPollInterval}, nil
+		}
+
+		logger.Error(err, "Could not describe image scan findings.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+	}
+
+	if output.ImageScanStatus != nil {
+		imageScan.Status.ScanStatus = ecrv1beta1.ScanStatus(output.ImageScanStatus.Status)
+		imageScan.Status.Description = aws.ToString(output.ImageScanStatus.Description)
+	}
+	if isImageScanFinished(imageScan.Status.ScanStatus) && output.ImageScanFindings != nil {
+		summary := createVulnerabilitySummary(output.ImageScanFindings.FindingSeverityCounts)
+		imageScan.Status.Summary = &summary
+		imageScan.Status.CompletedAt = &metav1.Time{Time: aws.ToTime(output.ImageScanFindings.ImageScanCompletedAt)}
+	}
+	if err := r.Status().Update(ctx, imageScan); err != nil {
+		logger.Error(err, "Failed to update ImageScan status")
+		return ctrl.Result{}, err
+	}
+
+	if !isImageScanFinished(imageScan.Status.ScanStatus) {
+		return ctrl.Result{RequeueAfter: imageScanPollInterval}, nil
+	}
+	logger.Info("Finished image scan.", "scanStatus", imageScan.Status.ScanStatus)
+	return ctrl.Result{}, nil
+}
+
+func (r *ImageScanReconciler) failImageScan(ctx context.Context, logger logr.Logger, imageScan *ecrv1beta1.ImageScan, message string) (ctrl.Result, error) {
+	logger.Info("Image scan failed.", "reason", message)
+	imageScan.Status.ScanStatus = ecrv1beta1.ScanStatusFailed
+	imageScan.Status.Description = message
+	if err := r.Status().Update(ctx, imageScan); err != nil {
+		logger.Error(err, "Failed to update ImageScan status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// Checks whether the scan has a final status, such as COMPLETE, FAILED or UNSUPPORTED_IMAGE
+func isImageScanFinished(scanStatus ecrv1beta1.ScanStatus) bool {
+	switch types.ScanStatus(scanStatus) {
+	case "", types.ScanStatusInProgress, types.ScanStatusPending:
+		return false
+	}
+	return true
+}
+
+func createImageIdentifier(imageScan *ecrv1beta1.ImageScan) *types.ImageIdentifier {
+	imageId := &types.ImageIdentifier{}
+	if imageScan.Spec.ImageDigest != "" {
+		imageId.ImageDigest = aws.String(imageScan.Spec.ImageDigest)
+	}
+	if imageScan.Spec.ImageTag != "" {
+		imageId.ImageTag = aws.String(imageScan.Spec.ImageTag)
+	}
+	return imageId
+}
+
+// Creates ImageScan objects for the most recently pushed tagged images if the rescan schedule of
+// the repository is due, and returns the duration until the next scheduled rescan.
+func scheduleImageRescans(ctx context.Context, c client.Client, scheme *runtime.Scheme, repository *ecrv1beta1.Repository, images []types.ImageDetail) (time.Duration, error) {
+	schedule, err := cron.ParseStandard(repository.Spec.RescanSchedule)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rescanSchedule: %w", err)
+	}
+
+	last := repository.CreationTimestamp.Time
+	if repository.Status.LastRescanTime != nil {
+		last = repository.Status.LastRescanTime.Time
+	}
+	now := time.Now()
+	if next := schedule.Next(last); now.Before(next) {
+		return next.Sub(now), nil
+	}
+
+	// remove the finished ImageScans of the previous rescan
+	previous := &ecrv1beta1.ImageScanList{}
+	if err := c.List(ctx, previous, client.InNamespace(repository.Namespace), client.MatchingLabels{ecrRepositoryLabel: repository.Name, ecrRescanLabel: "true"}); err != nil {
+		return 0, err
+	}
+	for i := range previous.Items {
+		if isImageScanFinished(previous.Items[i].Status.ScanStatus) {
+			if err := c.Delete(ctx, &previous.Items[i]); err != nil && !k8serrors.IsNotFound(err) {
+				return 0, err
+			}
+		}
+	}
+
+	count := repository.Spec.RescanTagCount
+	if count <= 0 {
+		count = 3
+	}
+
+	// the images are ordered by push time, most recent first
+	for _, image := range images {
+		if count == 0 {
+			break
+		}
+		if len(image.ImageTags) == 0 {
+			continue
+		}
+		count--
+
+		if image.ImageScanStatus != nil && image.ImageScanStatus.Status == types.ScanStatusInProgress {
+			continue
+		}
+		if summary := image.ImageScanFindingsSummary; summary != nil && now.Sub(aws.ToTime(summary.ImageScanCompletedAt)) < imageScanMinInterval {
+			continue
+		}
+
+		imageScan := &ecrv1beta1.ImageScan{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: repository.Name + "-rescan-",
+				Namespace:    repository.Namespace,
+				Labels:       map[string]string{ecrRepositoryLabel: repository.Name, ecrRescanLabel: "true"},
+			},
+			Spec: ecrv1beta1.ImageScanSpec{
+				RepositoryName: repository.Name,
+				ImageTag:       image.ImageTags[0],
+				ImageDigest:    aws.ToString(image.ImageDigest),
+			},
+		}
+		if err := ctrl.SetControllerReference(repository, imageScan, scheme); err != nil {
+			return 0, err
+		}
+		if err := c.Create(ctx, imageScan); err != nil {
+			return 0, err
+		}
+	}
+
+	repository.Status.LastRescanTime = &metav1.Time{Time: now}
+	return schedule.Next(now).Sub(now), nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageScanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.ImageScan{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,193 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+const (
+	// the namespace label to opt-in to the signature verification of Pod images
+	verifySignaturesLabel = "ecr.aws.cloud.qaware.de/verify-signatures"
+	// the time to verify the images of a Pod, below the default webhook timeout of 10 seconds
+	signatureWebhookTimeout = time.Duration(8) * time.Second
+)
+
+//+kubebuilder:webhook:path=/validate-v1-pod-signatures,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods;pods/ephemeralcontainers,verbs=create;update,versions=v1,name=vpod-signatures.kb.io,admissionReviewVersions={v1,v1beta1}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageverificationpolicies,verbs=get;list;watch
+
+// ImageSignatureValidator validates the signatures of Pod images against the ImageVerificationPolicies
+type ImageSignatureValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle resolves the container images of managed repositories to their digests, and denies or warns if
+// they have no valid signature for any ImageVerificationPolicy selecting the Repository, if the namespace
+// has opted-in with the ecr.aws.cloud.qaware.de/verify-signatures=enabled label. Images that can not be
+// resolved or verified in time are denied by Deny policies. On update, e.g. of ephemeral containers, only
+// changed images are verified. Digests verified by the periodic verification of the Repository against
+// the current policies are not verified again.
+func (v *ImageSignatureValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrllog.FromContext(ctx).WithValues("pod", req.Namespace+"/"+req.Name)
+
+	pod, err := decodePod(v.decoder, req.Object, req.Kind.Kind)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, k8stypes.NamespacedName{Name: req.Namespace}, namespace); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if namespace.Labels[verifySignaturesLabel] != "enabled" {
+		return admission.Allowed("")
+	}
+
+	// only changed images are verified on update, e.g. of Pods created before the namespace opted-in
+	containers, err := changedContainers(v.decoder, req, pod)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if len(containers) == 0 {
+		return admission.Allowed("")
+	}
+
+	repositories, err := findManagedRepositories(ctx, v.Client)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// the AWS requests are canceled in time to respond before the API server gives up on the webhook
+	ctx, cancel := context.WithTimeout(ctx, signatureWebhookTimeout)
+	defer cancel()
+
+	denials := make([]string, 0)
+	warnings := make([]string, 0)
+	clients := map[string]*ecr.Client{}
+
+	for _, container := range containers {
+		ref := ParseImageReference(container.Image)
+		repository, ok := repositories[ref.Registry+"/"+ref.Repository]
+		if !ok {
+			continue
+		}
+
+		policies, err := listVerificationPolicies(ctx, v.Client, &repository)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if len(policies) == 0 {
+			continue
+		}
+
+		// reports a failure as denial for Deny policies, otherwise as warning
+		violate := func(policy ecrv1beta1.ImageVerificationPolicy, message string) {
+			if policy.Spec.Action == "Warn" {
+				warnings = append(warnings, message)
+			} else {
+				denials = append(denials, message)
+			}
+		}
+
+		registryId, region, _ := ParseEcrRegistry(ref.Registry)
+		ecrClient, ok := clients[region]
+		if !ok {
+			ecrClient, err = CreateEcrClientForRegion(region)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			clients[region] = ecrClient
+		}
+
+		digest := ref.Digest
+		if digest == "" {
+			image, err := describeImage(ctx, ecrClient, registryId, ref)
+			if err != nil {
+				logger.Info("Could not resolve image for signature verification.", "image", container.Image, "error", err.Error())
+				for _, policy := range policies {
+					violate(policy, fmt.Sprintf("container %s: could not resolve image %s: %s: %s", container.Name, container.Image, policy.Name, err))
+				}
+				continue
+			}
+			digest = aws.ToString(image.ImageDigest)
+		}
+		if isSignatureVerified(repository.Status.SignatureVerifications, digest, policies) {
+			continue
+		}
+
+		signatures := newSignatureSource(ctx, ecrClient, registryId, ref.Registry+"/"+ref.Repository)
+		for _, policy := range policies {
+			if err := verifyImageSignature(signatures, digest, policy); err != nil {
+				violate(policy, fmt.Sprintf("container %s: image %s: %s: %s", container.Name, container.Image, policy.Name, err))
+			}
+		}
+	}
+
+	if len(denials) > 0 {
+		response := admission.Denied(strings.Join(denials, "; "))
+		response.Warnings = warnings
+		return response
+	}
+	if len(warnings) > 0 {
+		return admission.Allowed("").WithWarnings(warnings...)
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder into the ImageSignatureValidator.
+func (v *ImageSignatureValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Checks whether the digest has been verified by the periodic verification of the Repository against
+// the current revisions of the policies, results of added or changed policies are not trusted
+func isSignatureVerified(verifications []ecrv1beta1.SignatureVerification, digest string, policies []ecrv1beta1.ImageVerificationPolicy) bool {
+	revisions := policyRevisions(policies)
+	for _, verification := range verifications {
+		if verification.Digest == digest && verification.Verified && equality.Semantic.DeepEqual(verification.Policies, revisions) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,347 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/Masterminds/semver/v3"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	// the default interval to check the repository for new tags
+	defaultImageUpdateInterval = time.Duration(5) * time.Minute
+	// the number of updates kept in the status history
+	imageUpdateHistoryLimit = 10
+)
+
+// ImageUpdatePolicyReconciler reconciles a ImageUpdatePolicy object
+type ImageUpdatePolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageupdatepolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageupdatepolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageupdatepolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=vulnerabilityexceptions,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImageUpdatePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("imageUpdatePolicy", req.NamespacedName)
+
+	// lookup the ImageUpdatePolicy instance for this reconcile request
+	updatePolicy := &ecrv1beta1.ImageUpdatePolicy{}
+	geterr := r.Get(ctx, req.NamespacedName, updatePolicy)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ImageUpdatePolicy already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get ImageUpdatePolicy.")
+		return ctrl.Result{}, geterr
+	}
+
+	interval := defaultImageUpdateInterval
+	if updatePolicy.Spec.Interval != nil && updatePolicy.Spec.Interval.Duration > 0 {
+		interval = updatePolicy.Spec.Interval.Duration
+	}
+
+	status := updatePolicy.Status.DeepCopy()
+	updatePolicy.Status.LastCheckedTime = &metav1.Time{Time: time.Now()}
+	updatePolicy.Status.Message = ""
+	r.updateImages(ctx, updatePolicy)
+
+	if !equality.Semantic.DeepEqual(status, &updatePolicy.Status) {
+		if err := r.Status().Update(ctx, updatePolicy); err != nil {
+			logger.Error(err, "Failed to update ImageUpdatePolicy status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: interval}, nil
+}
+
+// Selects the tag to roll out and updates the target workloads, problems are reported in the status message
+func (r *ImageUpdatePolicyReconciler) updateImages(ctx context.Context, updatePolicy *ecrv1beta1.ImageUpdatePolicy) {
+	logger := ctrllog.FromContext(ctx)
+	spec := updatePolicy.Spec
+
+	repository := &ecrv1beta1.Repository{}
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: spec.RepositoryName, Namespace: updatePolicy.Namespace}, repository); err != nil {
+		logger.Error(err, "Failed to get Repository.")
+		updatePolicy.Status.Message = fmt.Sprintf("unable to get Repository %s: %s", spec.RepositoryName, err)
+		return
+	}
+	if repository.Status.RepositoryUri == "" {
+		updatePolicy.Status.Message = fmt.Sprintf("Repository %s has not been created yet", spec.RepositoryName)
+		return
+	}
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		updatePolicy.Status.Message = awserr.Error()
+		return
+	}
+
+	images, err := describeAllImages(client, ecrRepositoryName(repository))
+	if err != nil {
+		logger.Error(err, "Could not describe images of ECR repository.")
+		updatePolicy.Status.Message = err.Error()
+		return
+	}
+
+	tag, image, err := selectImageTag(spec.TagPolicy, images)
+	if err != nil {
+		updatePolicy.Status.Message = err.Error()
+		return
+	}
+	if image == nil {
+		updatePolicy.Status.Message = "no tag matches the tag policy"
+		return
+	}
+	digest := aws.ToString(image.ImageDigest)
+
+	// newly selected tags are only rolled out if their scan findings pass the gate
+	if spec.ScanGate != nil && (tag != updatePolicy.Status.SelectedTag || digest != updatePolicy.Status.SelectedDigest) {
+		violations, err := r.evaluateScanGate(ctx, client, repository, tag, image, spec.ScanGate)
+		if err != nil {
+			logger.Error(err, "Could not evaluate scan findings.", "tag", tag)
+			updatePolicy.Status.Message = err.Error()
+			return
+		}
+		if len(violations) > 0 {
+			logger.Info("Tag held back by scan gate.", "tag", tag, "violations", violations)
+			updatePolicy.Status.Message = fmt.Sprintf("tag %s held back by scan gate: %s", tag, strings.Join(violations, "; "))
+			return
+		}
+	}
+
+	// the image is pinned by digest, so a moved tag is rolled out as a changed pod template
+	updated := make([]string, 0)
+	failed := make([]string, 0)
+	imageName := repository.Status.RepositoryUri + ":" + tag + "@" + digest
+	for _, target := range spec.Targets {
+		workload := strings.ToLower(target.Kind) + "/" + target.Name
+		changed, err := r.updateWorkload(ctx, updatePolicy.Namespace, target, repository.Status.RepositoryUri, imageName)
+		if err != nil {
+			logger.Error(err, "Could not update workload.", "workload", workload)
+			failed = append(failed, fmt.Sprintf("%s: %s", workload, err))
+			continue
+		}
+		if changed {
+			logger.Info("Updated workload image.", "workload", workload, "image", imageName)
+			updated = append(updated, workload)
+		}
+	}
+
+	updatePolicy.Status.SelectedTag = tag
+	updatePolicy.Status.SelectedDigest = digest
+	if len(updated) > 0 {
+		record := ecrv1beta1.ImageUpdateRecord{Tag: tag, Digest: digest, UpdatedAt: metav1.Now(), Workloads: updated}
+		history := append([]ecrv1beta1.ImageUpdateRecord{record}, updatePolicy.Status.History...)
+		if len(history) > imageUpdateHistoryLimit {
+			history = history[:imageUpdateHistoryLimit]
+		}
+		updatePolicy.Status.History = history
+	}
+	if len(failed) > 0 {
+		updatePolicy.Status.Message = strings.Join(failed, "; ")
+	}
+}
+
+// Returns the violations of the scan gate by the scan findings of the image
+func (r *ImageUpdatePolicyReconciler) evaluateScanGate(ctx context.Context, ecrClient *ecr.Client, repository *ecrv1beta1.Repository, tag string, image *types.ImageDetail, gate *ecrv1beta1.ImageUpdateScanGate) ([]string, error) {
+	exceptions := &ecrv1beta1.VulnerabilityExceptionList{}
+	if err := r.List(ctx, exceptions, client.InNamespace(repository.Namespace)); err != nil {
+		return nil, err
+	}
+
+	// a pending scan is always waited for, the scan requirement only holds back tags that are never scanned
+	if status := image.ImageScanStatus; status != nil && (status.Status == types.ScanStatusPending || status.Status == types.ScanStatusInProgress) {
+		return []string{"image scan has not completed yet"}, nil
+	}
+
+	ref := ImageReference{Repository: ecrRepositoryName(repository), Tag: tag}
+	counts, completedAt, err := countVulnerabilities(ctx, ecrClient, repository.Status.RegistryId, ref, image, exceptions.Items)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateVulnerabilityPolicy(counts, completedAt, ecrv1beta1.ImageVulnerabilityPolicySpec{
+		MaxSeverity: gate.MaxSeverity,
+		MaxCount:    gate.MaxCount,
+		RequireScan: gate.RequireScan,
+	}), nil
+}
+
+// Sets the image of the matching containers of the workload, returns whether the workload was changed
+func (r *ImageUpdatePolicyReconciler) updateWorkload(ctx context.Context, namespace string, target ecrv1beta1.ImageUpdateTarget, repositoryUri string, image string) (bool, error) {
+	var workload client.Object
+	var podSpec *corev1.PodSpec
+	switch target.Kind {
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		workload, podSpec = deployment, &deployment.Spec.Template.Spec
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		workload, podSpec = statefulSet, &statefulSet.Spec.Template.Spec
+	case "CronJob":
+		cronJob := &batchv1.CronJob{}
+		workload, podSpec = cronJob, &cronJob.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return false, fmt.Errorf("unsupported kind %s", target.Kind)
+	}
+
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: target.Name, Namespace: namespace}, workload); err != nil {
+		return false, err
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	changed := setContainerImages(podSpec.InitContainers, target.Container, repositoryUri, image)
+	changed = setContainerImages(podSpec.Containers, target.Container, repositoryUri, image) || changed
+	if !changed {
+		return false, nil
+	}
+	return true, r.Patch(ctx, workload, patch)
+}
+
+// Sets the image of the named container, or of all containers referencing the repository if no name is given
+func setContainerImages(containers []corev1.Container, name string, repositoryUri string, image string) bool {
+	changed := false
+	for i := range containers {
+		if name != "" && containers[i].Name != name {
+			continue
+		}
+		if name == "" {
+			ref := ParseImageReference(containers[i].Image)
+			if ref.Registry+"/"+ref.Repository != repositoryUri {
+				continue
+			}
+		}
+		if containers[i].Image != image {
+			containers[i].Image = image
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Selects the tag to roll out from the images ordered by push time, most recent first.
+// Returns a nil image if no tag matches the policy.
+func selectImageTag(policy ecrv1beta1.ImageTagPolicy, images []types.ImageDetail) (string, *types.ImageDetail, error) {
+	var pattern *regexp.Regexp
+	if policy.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(policy.Pattern); err != nil {
+			return "", nil, fmt.Errorf("invalid tag pattern: %w", err)
+		}
+	}
+
+	switch policy.Type {
+	case "Semver":
+		if policy.Range == "" {
+			return "", nil, errors.New("the Semver tag policy requires a range")
+		}
+		constraint, err := semver.NewConstraint(policy.Range)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid semver range: %w", err)
+		}
+
+		var best *semver.Version
+		var bestTag string
+		var bestImage *types.ImageDetail
+		for i := range images {
+			for _, tag := range images[i].ImageTags {
+				if pattern != nil && !pattern.MatchString(tag) {
+					continue
+				}
+				version, err := semver.NewVersion(tag)
+				if err != nil || !constraint.Check(version) {
+					continue
+				}
+				if best == nil || version.GreaterThan(best) {
+					best, bestTag, bestImage = version, tag, &images[i]
+				}
+			}
+		}
+		return bestTag, bestImage, nil
+	case "Regex":
+		if pattern == nil {
+			return "", nil, errors.New("the Regex tag policy requires a pattern")
+		}
+	}
+
+	// the most recently pushed matching tag
+	for i := range images {
+		for _, tag := range images[i].ImageTags {
+			if pattern == nil || pattern.MatchString(tag) {
+				return tag, &images[i], nil
+			}
+		}
+	}
+	return "", nil, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageUpdatePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.ImageUpdatePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,352 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	conditionTypeInUseImagesProtected = "InUseImagesProtected"
+	// the interval to refresh the in-use images of a repository
+	imageUsageRefreshInterval = time.Duration(10) * time.Minute
+	// the protective tag prefix used if none is specified
+	defaultInUseTagPrefix = "inuse-"
+)
+
+// ImageUsageReconciler protects the images of a Repository in use by Pods and workload templates
+// of the cluster from lifecycle expiry
+type ImageUsageReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImageUsageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("repository", req.NamespacedName)
+
+	// lookup the Repository instance for this reconcile request
+	repository := &ecrv1beta1.Repository{}
+	geterr := r.Get(ctx, req.NamespacedName, repository)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get Repository.")
+		return ctrl.Result{}, geterr
+	}
+
+	if repository.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	status := repository.Status.DeepCopy()
+	protection := repository.Spec.InUseProtection
+	if protection == nil {
+		// protective tags are left in place, they may be removed by hand or expire by lifecycle rules
+		meta.RemoveStatusCondition(&repository.Status.Conditions, conditionTypeInUseImagesProtected)
+		repository.Status.InUseImages = nil
+		return ctrl.Result{}, r.updateStatus(ctx, logger, status, repository)
+	}
+
+	if repository.Status.RepositoryUri == "" {
+		logger.Info("Repository not created yet. Retrying.")
+		return ctrl.Result{RequeueAfter: time.Duration(30) * time.Second}, nil
+	}
+
+	ecrClient, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	usages, err := collectImageUsages(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to collect image usages.")
+		return ctrl.Result{}, err
+	}
+
+	images, err := describeAllImages(ecrClient, ecrRepositoryName(repository))
+	if err != nil {
+		logger.Error(err, "Failed to describe images.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+	}
+
+	tagDigests := make(map[string]string)
+	for _, image := range images {
+		for _, tag := range image.ImageTags {
+			tagDigests[tag] = aws.ToString(image.ImageDigest)
+		}
+	}
+	inUse := findInUseDigests(usages, repository.Status.RepositoryUri, tagDigests)
+	repository.Status.InUseImages = createInUseImages(inUse)
+
+	var condition metav1.Condition
+	if protection.Mode == "Warn" {
+		condition = r.checkLifecyclePreview(logger, ecrClient, ecrRepositoryName(repository), inUse)
+	} else {
+		condition = r.tagInUseImages(logger, ecrClient, ecrRepositoryName(repository), protection, images, inUse)
+	}
+	condition.ObservedGeneration = repository.Generation
+	meta.SetStatusCondition(&repository.Status.Conditions, condition)
+
+	if err := r.updateStatus(ctx, logger, status, repository); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: imageUsageRefreshInterval}, nil
+}
+
+func (r *ImageUsageReconciler) updateStatus(ctx context.Context, logger logr.Logger, status *ecrv1beta1.RepositoryStatus, repository *ecrv1beta1.Repository) error {
+	if equality.Semantic.DeepEqual(status, &repository.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, repository); err != nil {
+		logger.Error(err, "Failed to update Repository status")
+		return err
+	}
+	return nil
+}
+
+// Tags each in-use image with the protective tag and removes the protective tag from images no
+// longer in use. Removing the last tag of an image deletes it, so such protective tags are kept.
+func (r *ImageUsageReconciler) tagInUseImages(logger logr.Logger, ecrClient *ecr.Client, repositoryName string, protection *ecrv1beta1.InUseProtection, images []types.ImageDetail, inUse map[string][]string) metav1.Condition {
+	prefix := protection.TagPrefix
+	if prefix == "" {
+		prefix = defaultInUseTagPrefix
+	}
+
+	copier := &imageCopier{
+		logger:      logger,
+		source:      ecrLocation{client: ecrClient, repositoryName: repositoryName},
+		destination: ecrLocation{client: ecrClient, repositoryName: repositoryName},
+	}
+
+	failed := make([]string, 0)
+	released := make([]types.ImageIdentifier, 0)
+	for _, image := range images {
+		digest := aws.ToString(image.ImageDigest)
+		protectiveTag := inUseTag(prefix, digest)
+
+		if _, ok := inUse[digest]; ok {
+			if containsString(image.ImageTags, protectiveTag) {
+				continue
+			}
+			ecrImage, err := copier.getImage(&types.ImageIdentifier{ImageDigest: image.ImageDigest})
+			if err == nil {
+				err = copier.putImage(ecrImage, protectiveTag)
+			}
+			if err != nil {
+				logger.Error(err, "Could not tag in-use image.", "imageDigest", digest)
+				failed = append(failed, fmt.Sprintf("%s: %s", digest, err))
+				continue
+			}
+			logger.Info("Tagged in-use image.", "imageDigest", digest, "tag", protectiveTag)
+			continue
+		}
+
+		// only the protective tag of the digest is released, removing the last tag would delete the image
+		if containsString(image.ImageTags, protectiveTag) && len(image.ImageTags) > 1 {
+			released = append(released, types.ImageIdentifier{ImageTag: aws.String(protectiveTag)})
+		}
+	}
+
+	if len(released) > 0 {
+		output, err := ecrClient.BatchDeleteImage(context.TODO(), &ecr.BatchDeleteImageInput{
+			RepositoryName: aws.String(repositoryName),
+			ImageIds:       released,
+		})
+		if err != nil {
+			logger.Error(err, "Could not remove protective tags.")
+			failed = append(failed, err.Error())
+		} else {
+			for _, failure := range output.Failures {
+				failed = append(failed, fmt.Sprintf("%s: %s %s", aws.ToString(failure.ImageId.ImageTag), failure.FailureCode, aws.ToString(failure.FailureReason)))
+			}
+			logger.Info("Removed protective tags of images no longer in use.", "count", len(output.ImageIds))
+		}
+	}
+
+	if len(failed) > 0 {
+		return metav1.Condition{
+			Type:    conditionTypeInUseImagesProtected,
+			Status:  metav1.ConditionFalse,
+			Reason:  "Failed",
+			Message: strings.Join(failed, "; "),
+		}
+	}
+	return metav1.Condition{
+		Type:    conditionTypeInUseImagesProtected,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Tagged",
+		Message: fmt.Sprintf("%d in-use images are tagged with prefix %s", len(inUse), prefix),
+	}
+}
+
+// Checks the latest lifecycle policy preview for in-use images it would expire and starts a new
+// preview, so the next check reflects the current lifecycle policy and images
+func (r *ImageUsageReconciler) checkLifecyclePreview(logger logr.Logger, ecrClient *ecr.Client, repositoryName string, inUse map[string][]string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    conditionTypeInUseImagesProtected,
+		Status:  metav1.ConditionUnknown,
+		Reason:  "PreviewInProgress",
+		Message: "Waiting for the lifecycle policy preview",
+	}
+
+	expired := make([]string, 0)
+	previewStatus := types.LifecyclePolicyPreviewStatus("")
+	paginator := ecr.NewGetLifecyclePolicyPreviewPaginator(ecrClient, &ecr.GetLifecyclePolicyPreviewInput{
+		RepositoryName: aws.String(repositoryName),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			var lpnfe *types.LifecyclePolicyNotFoundException
+			var lppnfe *types.LifecyclePolicyPreviewNotFoundException
+			if errors.As(err, &lpnfe) {
+				condition.Status = metav1.ConditionTrue
+				condition.Reason = "NoLifecyclePolicy"
+				condition.Message = "The repository has no lifecycle policy"
+				return condition
+			} else if !errors.As(err, &lppnfe) {
+				logger.Error(err, "Could not get lifecycle policy preview.")
+				condition.Reason = "Failed"
+				condition.Message = err.Error()
+			}
+			break
+		}
+
+		previewStatus = output.Status
+		for _, result := range output.PreviewResults {
+			if result.Action == nil || result.Action.Type != types.ImageActionTypeExpire {
+				continue
+			}
+			digest := aws.ToString(result.ImageDigest)
+			if workloads, ok := inUse[digest]; ok {
+				expired = append(expired, fmt.Sprintf("%s (%s)", digest, strings.Join(workloads, ", ")))
+			}
+		}
+	}
+
+	if previewStatus == types.LifecyclePolicyPreviewStatusInProgress {
+		return condition
+	}
+	if previewStatus == types.LifecyclePolicyPreviewStatusComplete {
+		if len(expired) > 0 {
+			sort.Strings(expired)
+			condition.Status = metav1.ConditionFalse
+			condition.Reason = "LifecycleExpiresInUseImages"
+			condition.Message = fmt.Sprintf("The lifecycle policy expires in-use images: %s", strings.Join(expired, "; "))
+		} else {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "NotExpired"
+			condition.Message = "The lifecycle policy expires no in-use images"
+		}
+	}
+
+	_, err := ecrClient.StartLifecyclePolicyPreview(context.TODO(), &ecr.StartLifecyclePolicyPreviewInput{
+		RepositoryName: aws.String(repositoryName),
+	})
+	if err != nil {
+		var lpnfe *types.LifecyclePolicyNotFoundException
+		var lppipe *types.LifecyclePolicyPreviewInProgressException
+		if errors.As(err, &lpnfe) {
+			condition.Status = metav1.ConditionTrue
+			condition.Reason = "NoLifecyclePolicy"
+			condition.Message = "The repository has no lifecycle policy"
+		} else if !errors.As(err, &lppipe) {
+			logger.Error(err, "Could not start lifecycle policy preview.")
+		}
+	}
+	return condition
+}
+
+// Returns the protective tag of the image digest
+func inUseTag(prefix string, digest string) string {
+	hash := strings.TrimPrefix(digest, "sha256:")
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return prefix + hash
+}
+
+func createInUseImages(inUse map[string][]string) []ecrv1beta1.InUseImage {
+	if len(inUse) == 0 {
+		return nil
+	}
+	images := make([]ecrv1beta1.InUseImage, 0, len(inUse))
+	for digest, workloads := range inUse {
+		sorted := append([]string{}, workloads...)
+		sort.Strings(sorted)
+		images = append(images, ecrv1beta1.InUseImage{Digest: digest, Workloads: sorted})
+	}
+	sort.Slice(images, func(i, j int) bool {
+		return images[i].Digest < images[j].Digest
+	})
+	return images
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageUsageReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("imageusage").
+		For(&ecrv1beta1.Repository{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
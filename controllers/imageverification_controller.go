@@ -0,0 +1,259 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// the interval to verify the signatures of the most recently pushed tags
+const signatureVerificationInterval = time.Duration(10) * time.Minute
+
+// ImageVerificationReconciler verifies the signatures of the most recently pushed tags of a Repository
+// against the ImageVerificationPolicies selecting it
+type ImageVerificationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imageverificationpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories/status,verbs=get;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ImageVerificationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("repository", req.NamespacedName)
+
+	// lookup the Repository instance for this reconcile request
+	repository := &ecrv1beta1.Repository{}
+	geterr := r.Get(ctx, req.NamespacedName, repository)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get Repository.")
+		return ctrl.Result{}, geterr
+	}
+
+	if repository.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	policies, err := listVerificationPolicies(ctx, r.Client, repository)
+	if err != nil {
+		logger.Error(err, "Failed to list ImageVerificationPolicies.")
+		return ctrl.Result{}, err
+	}
+
+	status := repository.Status.DeepCopy()
+	if len(policies) == 0 {
+		repository.Status.SignatureVerifications = nil
+		return ctrl.Result{}, r.updateStatus(ctx, repository, status)
+	}
+
+	if repository.Status.RepositoryUri == "" {
+		logger.Info("Repository not created yet. Retrying.")
+		return ctrl.Result{RequeueAfter: time.Duration(30) * time.Second}, nil
+	}
+
+	ecrClient, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	images, err := describeAllImages(ecrClient, ecrRepositoryName(repository))
+	if err != nil {
+		logger.Error(err, "Failed to describe images.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+	}
+
+	previous := make(map[string]ecrv1beta1.SignatureVerification)
+	for _, verification := range repository.Status.SignatureVerifications {
+		previous[verification.Tag] = verification
+	}
+
+	revisions := policyRevisions(policies)
+	signatures := newSignatureSource(ctx, ecrClient, repository.Status.RegistryId, repository.Status.RepositoryUri)
+	verifications := make([]ecrv1beta1.SignatureVerification, 0)
+	// the failures by digest, images with several tags are verified once
+	results := make(map[string][]string)
+	for _, image := range images {
+		digest := aws.ToString(image.ImageDigest)
+		for _, tag := range image.ImageTags {
+			if signatureTagPattern.MatchString(tag) || len(verifications) == latestTagsLimit {
+				continue
+			}
+
+			verification := ecrv1beta1.SignatureVerification{
+				Tag:        tag,
+				Digest:     digest,
+				Verified:   true,
+				Policies:   revisions,
+				VerifiedAt: metav1.Time{Time: time.Now()},
+			}
+			failures, ok := results[digest]
+			if !ok {
+				failures = verifyImageSignatures(signatures, digest, policies)
+				results[digest] = failures
+			}
+			if len(failures) > 0 {
+				verification.Verified = false
+				verification.Message = strings.Join(failures, "; ")
+				logger.Info("Image signature verification failed.", "tag", tag, "imageDigest", digest, "failures", failures)
+			}
+
+			// keep the time of unchanged results to not update the status on every run
+			if last, ok := previous[tag]; ok && last.Digest == digest && last.Verified == verification.Verified && last.Message == verification.Message && equality.Semantic.DeepEqual(last.Policies, verification.Policies) {
+				verification.VerifiedAt = last.VerifiedAt
+			}
+			verifications = append(verifications, verification)
+		}
+	}
+	repository.Status.SignatureVerifications = verifications
+
+	if err := r.updateStatus(ctx, repository, status); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: signatureVerificationInterval}, nil
+}
+
+func (r *ImageVerificationReconciler) updateStatus(ctx context.Context, repository *ecrv1beta1.Repository, status *ecrv1beta1.RepositoryStatus) error {
+	if equality.Semantic.DeepEqual(status, &repository.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, repository); err != nil {
+		ctrllog.FromContext(ctx).Error(err, "Failed to update Repository status")
+		return err
+	}
+	return nil
+}
+
+// Lists all ImageVerificationPolicy objects selecting the Repository
+func listVerificationPolicies(ctx context.Context, c client.Client, repository *ecrv1beta1.Repository) ([]ecrv1beta1.ImageVerificationPolicy, error) {
+	list := &ecrv1beta1.ImageVerificationPolicyList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	policies := make([]ecrv1beta1.ImageVerificationPolicy, 0, len(list.Items))
+	for _, policy := range list.Items {
+		if policy.Spec.RepositorySelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.RepositorySelector)
+			if err != nil || !selector.Matches(labels.Set(repository.Labels)) {
+				continue
+			}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// Returns the name/uid/generation of each policy, which changes whenever a policy is recreated or changed
+func policyRevisions(policies []ecrv1beta1.ImageVerificationPolicy) []string {
+	revisions := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		revisions = append(revisions, fmt.Sprintf("%s/%s/%d", policy.Name, policy.UID, policy.Generation))
+	}
+	sort.Strings(revisions)
+	return revisions
+}
+
+// Verifies the signatures of the image against each policy and returns the failed policies with the reason
+func verifyImageSignatures(signatures *signatureSource, digest string, policies []ecrv1beta1.ImageVerificationPolicy) []string {
+	failures := make([]string, 0)
+	for _, policy := range policies {
+		if err := verifyImageSignature(signatures, digest, policy); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", policy.Name, err))
+		}
+	}
+	return failures
+}
+
+// Verifies the signatures of the image against the policy
+func verifyImageSignature(signatures *signatureSource, digest string, policy ecrv1beta1.ImageVerificationPolicy) error {
+	verifier, err := newSignatureVerifier(policy.Spec)
+	if err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+	return verifier.verify(signatures, digest)
+}
+
+// find all Repositories to verify again after an ImageVerificationPolicy changed
+func (r *ImageVerificationReconciler) findObjectsForPolicy(obj client.Object) []reconcile.Request {
+	list := &ecrv1beta1.RepositoryList{}
+	if err := r.List(context.TODO(), list); err != nil {
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, item := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImageVerificationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("imageverification").
+		For(&ecrv1beta1.Repository{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(
+			&source.Kind{Type: &ecrv1beta1.ImageVerificationPolicy{}},
+			handler.EnqueueRequestsFromMapFunc(r.findObjectsForPolicy),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
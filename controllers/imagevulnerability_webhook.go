@@ -0,0 +1,377 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	// the namespace label to opt-in to the vulnerability checks of Pod and Deployment images
+	checkVulnerabilitiesLabel = "ecr.aws.cloud.qaware.de/check-vulnerabilities"
+	// the time to check the images of a Pod or Deployment, below the default webhook timeout of 10 seconds
+	vulnerabilityWebhookTimeout = time.Duration(8) * time.Second
+)
+
+//+kubebuilder:webhook:path=/validate-v1-pod-vulnerabilities,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=vpod-vulnerabilities.kb.io,admissionReviewVersions={v1,v1beta1}
+//+kubebuilder:webhook:path=/validate-apps-v1-deployment-vulnerabilities,mutating=false,failurePolicy=fail,sideEffects=None,groups=apps,resources=deployments,verbs=create;update,versions=v1,name=vdeployment-vulnerabilities.kb.io,admissionReviewVersions={v1,v1beta1}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagevulnerabilitypolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=vulnerabilityexceptions,verbs=get;list;watch
+
+// ImageVulnerabilityValidator validates the images of Pods and Deployments against the ImageVulnerabilityPolicies
+type ImageVulnerabilityValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle resolves all container images of managed repositories to their latest scan findings, and
+// denies or warns if they violate any ImageVulnerabilityPolicy selecting the namespace, if the namespace
+// has opted-in with the ecr.aws.cloud.qaware.de/check-vulnerabilities=enabled label. Accepted risks
+// declared by VulnerabilityExceptions in the namespace are only not counted for policies allowing them.
+// Images that can not be evaluated in time are denied by policies with the Deny action.
+func (v *ImageVulnerabilityValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrllog.FromContext(ctx).WithValues("kind", req.Kind.Kind, "object", req.Namespace+"/"+req.Name)
+
+	podSpec, err := decodePodSpec(v.decoder, req)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// allow updates that do not change the pod template, e.g. scaling of existing deployments
+	if req.Operation == admissionv1.Update && req.Kind.Kind == "Deployment" {
+		old := &appsv1.Deployment{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if equality.Semantic.DeepEqual(&old.Spec.Template.Spec, podSpec) {
+			return admission.Allowed("")
+		}
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, k8stypes.NamespacedName{Name: req.Namespace}, namespace); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if namespace.Labels[checkVulnerabilitiesLabel] != "enabled" {
+		return admission.Allowed("")
+	}
+
+	policies, err := listVulnerabilityPolicies(ctx, v.Client, namespace)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(policies) == 0 {
+		return admission.Allowed("")
+	}
+
+	repositories, err := findManagedRepositories(ctx, v.Client)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	exceptions := &ecrv1beta1.VulnerabilityExceptionList{}
+	if err := v.Client.List(ctx, exceptions, client.InNamespace(req.Namespace)); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// the AWS requests are canceled in time to respond before the API server gives up on the webhook
+	ctx, cancel := context.WithTimeout(ctx, vulnerabilityWebhookTimeout)
+	defer cancel()
+
+	denials := make([]string, 0)
+	warnings := make([]string, 0)
+	clients := map[string]*ecr.Client{}
+
+	// images that can not be evaluated are only allowed by policies with the Warn action
+	violate := func(policy ecrv1beta1.ImageVulnerabilityPolicy, message string) {
+		if policy.Spec.Action == "Warn" {
+			warnings = append(warnings, message)
+		} else {
+			denials = append(denials, message)
+		}
+	}
+
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		ref := ParseImageReference(container.Image)
+		if _, ok := repositories[ref.Registry+"/"+ref.Repository]; !ok {
+			continue
+		}
+		registryId, region, _ := ParseEcrRegistry(ref.Registry)
+
+		ecrClient, ok := clients[region]
+		if !ok {
+			ecrClient, err = CreateEcrClientForRegion(region)
+			if err != nil {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			clients[region] = ecrClient
+		}
+
+		image, err := describeImage(ctx, ecrClient, registryId, ref)
+		if err != nil {
+			logger.Info("Could not resolve image for vulnerability check.", "image", container.Image, "error", err.Error())
+			for _, policy := range policies {
+				violate(policy, fmt.Sprintf("container %s: %s: could not resolve image %s", container.Name, policy.Name, container.Image))
+			}
+			continue
+		}
+
+		// the findings are counted with and without exceptions, as needed by the policies
+		counts := map[bool]map[string]int32{}
+		var completedAt *time.Time
+		if image.ImageScanFindingsSummary != nil {
+			completedAt = image.ImageScanFindingsSummary.ImageScanCompletedAt
+		}
+		for _, policy := range policies {
+			if _, ok := counts[policy.Spec.AllowExceptions]; !ok {
+				policyExceptions := []ecrv1beta1.VulnerabilityException{}
+				if policy.Spec.AllowExceptions {
+					policyExceptions = exceptions.Items
+				}
+				counts[policy.Spec.AllowExceptions], _, err = countVulnerabilities(ctx, ecrClient, registryId, ref, image, policyExceptions)
+				if err != nil {
+					logger.Info("Could not read image scan findings.", "image", container.Image, "error", err.Error())
+					violate(policy, fmt.Sprintf("container %s: %s: could not read scan findings of image %s", container.Name, policy.Name, container.Image))
+					delete(counts, policy.Spec.AllowExceptions)
+					continue
+				}
+			}
+
+			violations := evaluateVulnerabilityPolicy(counts[policy.Spec.AllowExceptions], completedAt, policy.Spec)
+			if len(violations) == 0 {
+				continue
+			}
+			message := fmt.Sprintf("container %s: %s: %s", container.Name, policy.Name, strings.Join(violations, ", "))
+			// the grace period starts with the push, rescans of the image do not restart it
+			pushedAt := image.ImagePushedAt
+			inGracePeriod := pushedAt != nil && policy.Spec.GracePeriod != nil && time.Since(*pushedAt) < policy.Spec.GracePeriod.Duration
+			if inGracePeriod {
+				warnings = append(warnings, message)
+			} else {
+				violate(policy, message)
+			}
+		}
+	}
+
+	if len(denials) > 0 {
+		response := admission.Denied(strings.Join(denials, "; "))
+		response.Warnings = warnings
+		return response
+	}
+	if len(warnings) > 0 {
+		return admission.Allowed("").WithWarnings(warnings...)
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder into the ImageVulnerabilityValidator.
+func (v *ImageVulnerabilityValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Decodes the pod spec of a Pod or the pod template spec of a Deployment
+func decodePodSpec(decoder *admission.Decoder, req admission.Request) (*corev1.PodSpec, error) {
+	switch req.Kind.Kind {
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := decoder.Decode(req, pod); err != nil {
+			return nil, err
+		}
+		return &pod.Spec, nil
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		if err := decoder.Decode(req, deployment); err != nil {
+			return nil, err
+		}
+		return &deployment.Spec.Template.Spec, nil
+	}
+	return nil, fmt.Errorf("unsupported kind %s", req.Kind.Kind)
+}
+
+// Lists all ImageVulnerabilityPolicy objects selecting the namespace
+func listVulnerabilityPolicies(ctx context.Context, c client.Client, namespace *corev1.Namespace) ([]ecrv1beta1.ImageVulnerabilityPolicy, error) {
+	list := &ecrv1beta1.ImageVulnerabilityPolicyList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	policies := make([]ecrv1beta1.ImageVulnerabilityPolicy, 0, len(list.Items))
+	for _, policy := range list.Items {
+		if policy.Spec.NamespaceSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+			if err != nil || !selector.Matches(labels.Set(namespace.Labels)) {
+				continue
+			}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// Describes the image by digest, or by tag defaulting to latest
+func describeImage(ctx context.Context, client *ecr.Client, registryId string, ref ImageReference) (*types.ImageDetail, error) {
+	imageId := types.ImageIdentifier{}
+	if ref.Digest != "" {
+		imageId.ImageDigest = aws.String(ref.Digest)
+	} else if ref.Tag != "" {
+		imageId.ImageTag = aws.String(ref.Tag)
+	} else {
+		imageId.ImageTag = aws.String("latest")
+	}
+
+	output, err := client.DescribeImages(ctx, &ecr.DescribeImagesInput{
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(ref.Repository),
+		ImageIds:       []types.ImageIdentifier{imageId},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.ImageDetails) == 0 {
+		return nil, errors.New("image not found")
+	}
+	return &output.ImageDetails[0], nil
+}
+
+// Returns the number of findings by severity of the latest completed scan, without the accepted
+// vulnerabilities, and the time the scan completed. Returns nil counts if the image has not been scanned.
+func countVulnerabilities(ctx context.Context, client *ecr.Client, registryId string, ref ImageReference, image *types.ImageDetail, exceptions []ecrv1beta1.VulnerabilityException) (map[string]int32, *time.Time, error) {
+	summary := image.ImageScanFindingsSummary
+	if summary == nil || summary.ImageScanCompletedAt == nil {
+		return nil, nil, nil
+	}
+
+	accepted := map[string]bool{}
+	for _, exception := range exceptions {
+		if exception.Spec.RepositoryName != ref.Repository || exception.Spec.ExpiresAt.Time.Before(time.Now()) {
+			continue
+		}
+		if len(exception.Spec.ImageTags) > 0 && !containsAnyString(exception.Spec.ImageTags, image.ImageTags) {
+			continue
+		}
+		for _, id := range exception.Spec.VulnerabilityIDs {
+			accepted[id] = true
+		}
+	}
+	if len(accepted) == 0 {
+		return summary.FindingSeverityCounts, summary.ImageScanCompletedAt, nil
+	}
+
+	// recount the individual findings without the accepted vulnerabilities
+	counts := map[string]int32{}
+	paginator := ecr.NewDescribeImageScanFindingsPaginator(client, &ecr.DescribeImageScanFindingsInput{
+		RegistryId:     aws.String(registryId),
+		RepositoryName: aws.String(ref.Repository),
+		ImageId:        &types.ImageIdentifier{ImageDigest: image.ImageDigest},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if output.ImageScanFindings == nil {
+			continue
+		}
+		for _, finding := range output.ImageScanFindings.Findings {
+			if !accepted[aws.ToString(finding.Name)] {
+				counts[string(finding.Severity)]++
+			}
+		}
+		for _, finding := range output.ImageScanFindings.EnhancedFindings {
+			if details := finding.PackageVulnerabilityDetails; details == nil || !accepted[aws.ToString(details.VulnerabilityId)] {
+				counts[aws.ToString(finding.Severity)]++
+			}
+		}
+	}
+	return counts, summary.ImageScanCompletedAt, nil
+}
+
+// Evaluates the finding counts against the policy and returns all violations
+func evaluateVulnerabilityPolicy(counts map[string]int32, completedAt *time.Time, spec ecrv1beta1.ImageVulnerabilityPolicySpec) []string {
+	violations := make([]string, 0)
+	if completedAt == nil {
+		if spec.RequireScan {
+			violations = append(violations, "image has not been scanned")
+		}
+		return violations
+	}
+
+	maxSeverity := types.FindingSeverity(spec.MaxSeverity)
+	if maxSeverity == "" {
+		maxSeverity = types.FindingSeverityHigh
+	}
+
+	severities := make([]string, 0, len(counts))
+	for severity := range counts {
+		severities = append(severities, severity)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		return severityRank(types.FindingSeverity(severities[i])) < severityRank(types.FindingSeverity(severities[j]))
+	})
+
+	for _, severity := range severities {
+		if counts[severity] > 0 && severityRank(types.FindingSeverity(severity)) < severityRank(maxSeverity) {
+			violations = append(violations, fmt.Sprintf("%d %s findings exceed max severity %s", counts[severity], severity, maxSeverity))
+		}
+	}
+	if spec.MaxCount != nil && int(counts[string(maxSeverity)]) > *spec.MaxCount {
+		violations = append(violations, fmt.Sprintf("%d %s findings exceed max count %d", counts[string(maxSeverity)], maxSeverity, *spec.MaxCount))
+	}
+	return violations
+}
+
+func containsAnyString(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
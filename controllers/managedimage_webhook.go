@@ -0,0 +1,194 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+const (
+	// the namespace label to opt-in to the restriction of Pods to images of managed repositories
+	managedImagesLabel = "ecr.aws.cloud.qaware.de/managed-images"
+	// the namespace label to additionally require images to be pinned by digest
+	requireImageDigestLabel = "ecr.aws.cloud.qaware.de/require-image-digest"
+)
+
+//+kubebuilder:webhook:path=/validate-v1-pod-managed-images,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods;pods/ephemeralcontainers,verbs=create;update,versions=v1,name=vpod-managed-images.kb.io,admissionReviewVersions={v1,v1beta1}
+
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+
+// ManagedImageValidator restricts the images of Pods to the repositories managed in their namespace
+type ManagedImageValidator struct {
+	Client client.Client
+	// the image prefixes allowed in all namespaces in addition to the managed repositories
+	AllowedImagePrefixes []string
+	decoder              *admission.Decoder
+}
+
+// Handle denies Pods with images that are neither from a Repository in the same namespace nor match
+// one of the allowed image prefixes, if the namespace has opted-in with the
+// ecr.aws.cloud.qaware.de/managed-images=enabled label. With the
+// ecr.aws.cloud.qaware.de/require-image-digest=true label all images must be pinned by digest.
+func (v *ManagedImageValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrllog.FromContext(ctx).WithValues("pod", req.Namespace+"/"+req.Name)
+
+	pod, err := decodePod(v.decoder, req.Object, req.Kind.Kind)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, k8stypes.NamespacedName{Name: req.Namespace}, namespace); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if namespace.Labels[managedImagesLabel] != "enabled" {
+		return admission.Allowed("")
+	}
+	requireDigest := namespace.Labels[requireImageDigestLabel] == "true"
+
+	repositories := &ecrv1beta1.RepositoryList{}
+	if err := v.Client.List(ctx, repositories, client.InNamespace(req.Namespace)); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	managed := make([]string, 0, len(repositories.Items))
+	for _, repository := range repositories.Items {
+		if repository.Status.RepositoryUri != "" {
+			managed = append(managed, repository.Status.RepositoryUri)
+		}
+	}
+	sort.Strings(managed)
+
+	// only changed images are validated on update, e.g. of Pods created before the namespace opted-in
+	containers, err := changedContainers(v.decoder, req, pod)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	denials := make([]string, 0)
+	for _, container := range containers {
+		ref := ParseImageReference(container.Image)
+		if !containsString(managed, ref.Registry+"/"+ref.Repository) && !hasAnyPrefix(container.Image, v.AllowedImagePrefixes) {
+			denials = append(denials, fmt.Sprintf("container %s: image %s is not from a managed repository", container.Name, container.Image))
+			continue
+		}
+		if requireDigest && ref.Digest == "" {
+			denials = append(denials, fmt.Sprintf("container %s: image %s is not pinned by digest", container.Name, container.Image))
+		}
+	}
+	if len(denials) == 0 {
+		return admission.Allowed("")
+	}
+
+	allowed := append(append([]string{}, managed...), v.AllowedImagePrefixes...)
+	if len(allowed) == 0 {
+		allowed = []string{"<none>"}
+	}
+	logger.Info("Denied Pod with images not from managed repositories.", "denials", denials)
+	return admission.Denied(fmt.Sprintf("%s; allowed image prefixes: %s", strings.Join(denials, "; "), strings.Join(allowed, ", ")))
+}
+
+// Decodes the Pod of the request. Before Kubernetes 1.22 the ephemeralcontainers subresource
+// is an EphemeralContainers object, which is returned as Pod with only the ephemeral containers.
+func decodePod(decoder *admission.Decoder, raw runtime.RawExtension, kind string) (*corev1.Pod, error) {
+	pod := &corev1.Pod{}
+	if kind == "EphemeralContainers" {
+		ephemeral := &corev1.EphemeralContainers{}
+		if err := decoder.DecodeRaw(raw, ephemeral); err != nil {
+			return nil, err
+		}
+		pod.Spec.EphemeralContainers = ephemeral.EphemeralContainers
+		return pod, nil
+	}
+	if err := decoder.DecodeRaw(raw, pod); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+// Returns the init, app and ephemeral containers of the Pod. On update only the containers with a
+// changed image are returned.
+func changedContainers(decoder *admission.Decoder, req admission.Request, pod *corev1.Pod) ([]corev1.Container, error) {
+	unchanged := map[string]string{}
+	if req.Operation == admissionv1.Update {
+		old, err := decodePod(decoder, req.OldObject, req.Kind.Kind)
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range podContainers(old) {
+			unchanged[container.Name] = container.Image
+		}
+	}
+
+	containers := make([]corev1.Container, 0)
+	for _, container := range podContainers(pod) {
+		if image, ok := unchanged[container.Name]; ok && image == container.Image {
+			continue
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// Returns the init, app and ephemeral containers of the Pod
+func podContainers(pod *corev1.Pod) []corev1.Container {
+	containers := make([]corev1.Container, 0)
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, ephemeral := range pod.Spec.EphemeralContainers {
+		containers = append(containers, corev1.Container{Name: ephemeral.Name, Image: ephemeral.Image})
+	}
+	return containers
+}
+
+// InjectDecoder injects the decoder into the ManagedImageValidator.
+func (v *ManagedImageValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Checks whether the image starts with any of the prefixes
+func hasAnyPrefix(image string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+// ManagedRepositoriesKey is the ConfigMap key listing the RepositoryUri values of all managed repositories, one per line
+const ManagedRepositoriesKey = "repositoryUris"
+
+// ManagedRepositoriesReconciler publishes the RepositoryUri values of all Repository objects to a ConfigMap,
+// so the ecr-credential-provider on the nodes does not need to list Repository objects
+type ManagedRepositoriesReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	// the namespace and name of the published ConfigMap
+	ConfigMap k8stypes.NamespacedName
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ManagedRepositoriesReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("configMap", r.ConfigMap)
+
+	repositories := &ecrv1beta1.RepositoryList{}
+	if err := r.List(ctx, repositories); err != nil {
+		logger.Error(err, "Failed to list Repositories.")
+		return ctrl.Result{}, err
+	}
+
+	uris := make([]string, 0, len(repositories.Items))
+	for _, repository := range repositories.Items {
+		if repository.Status.RepositoryUri != "" && repository.GetDeletionTimestamp() == nil {
+			uris = append(uris, repository.Status.RepositoryUri)
+		}
+	}
+	sort.Strings(uris)
+
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: r.ConfigMap.Name, Namespace: r.ConfigMap.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, configMap, func() error {
+		configMap.Data = map[string]string{ManagedRepositoriesKey: strings.Join(uris, "\n")}
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "Failed to publish managed repositories.")
+		return ctrl.Result{}, err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info("Published managed repositories.", "repositories", len(uris))
+	}
+	return ctrl.Result{}, nil
+}
+
+// ParseManagedRepositories returns the RepositoryUri values published in the ConfigMap data
+func ParseManagedRepositories(data map[string]string) []string {
+	uris := make([]string, 0)
+	for _, uri := range strings.Split(data[ManagedRepositoriesKey], "\n") {
+		if uri = strings.TrimSpace(uri); uri != "" {
+			uris = append(uris, uri)
+		}
+	}
+	return uris
+}
+
+// the published ConfigMap is the only object to reconcile, whatever Repository changed
+func (r *ManagedRepositoriesReconciler) findConfigMap(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: r.ConfigMap}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ManagedRepositoriesReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isConfigMap := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == r.ConfigMap.Namespace && obj.GetName() == r.ConfigMap.Name
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("managedrepositories").
+		// revert changes and recreate the ConfigMap if it was deleted
+		For(&corev1.ConfigMap{}, builder.WithPredicates(isConfigMap)).
+		// the RepositoryUri is set in the status, so status updates must not be filtered
+		Watches(&source.Kind{Type: &ecrv1beta1.Repository{}}, handler.EnqueueRequestsFromMapFunc(r.findConfigMap)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
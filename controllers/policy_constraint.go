@@ -0,0 +1,270 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+var accountIdPattern = regexp.MustCompile(`^\d{12}$`)
+
+// The policyDocument holds the relevant parts of an IAM policy document
+type policyDocument struct {
+	Statement policyStatements `json:"Statement"`
+}
+
+type policyStatement struct {
+	Sid          string                            `json:"Sid"`
+	Effect       string                            `json:"Effect"`
+	Principal    json.RawMessage                   `json:"Principal"`
+	NotPrincipal json.RawMessage                   `json:"NotPrincipal"`
+	Action       stringOrSlice                     `json:"Action"`
+	NotAction    stringOrSlice                     `json:"NotAction"`
+	Condition    map[string]map[string]interface{} `json:"Condition"`
+}
+
+// The policyStatements type accepts a single statement object or a list of statements
+type policyStatements []policyStatement
+
+func (s *policyStatements) UnmarshalJSON(data []byte) error {
+	var list []policyStatement
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var single policyStatement
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*s = []policyStatement{single}
+	return nil
+}
+
+// The stringOrSlice type accepts a single string or a list of strings
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*s = list
+		return nil
+	}
+	var single string
+	if err := json.Unmarshal(data, &single); err != nil {
+		return err
+	}
+	*s = []string{single}
+	return nil
+}
+
+// Returns the AWS, Service and Federated principals of the statement, a wildcard principal is
+// returned as "*". A NotPrincipal grants access to everyone else, so it is returned as "*" too.
+func (s policyStatement) principals() ([]string, error) {
+	if len(s.NotPrincipal) > 0 {
+		return []string{"*"}, nil
+	}
+	if len(s.Principal) == 0 {
+		return nil, nil
+	}
+
+	var wildcard string
+	if err := json.Unmarshal(s.Principal, &wildcard); err == nil {
+		return []string{wildcard}, nil
+	}
+
+	principals := map[string]stringOrSlice{}
+	if err := json.Unmarshal(s.Principal, &principals); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	for _, key := range []string{"AWS", "Service", "Federated", "CanonicalUser"} {
+		result = append(result, principals[key]...)
+	}
+	return result, nil
+}
+
+// Lists all RepositoryPolicyConstraint objects of the cluster
+func listPolicyConstraints(ctx context.Context, c client.Client) ([]ecrv1beta1.RepositoryPolicyConstraint, error) {
+	list := &ecrv1beta1.RepositoryPolicyConstraintList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// Evaluates the policy text against the given constraints and returns all violations
+func evaluatePolicyConstraints(policyText string, constraints []ecrv1beta1.RepositoryPolicyConstraint) ([]string, error) {
+	if len(constraints) == 0 {
+		return nil, nil
+	}
+
+	document := policyDocument{}
+	if err := json.Unmarshal([]byte(policyText), &document); err != nil {
+		return nil, fmt.Errorf("unable to parse policy text: %w", err)
+	}
+
+	violations := make([]string, 0)
+	for _, constraint := range constraints {
+		for i, statement := range document.Statement {
+			if !strings.EqualFold(statement.Effect, "Allow") {
+				continue
+			}
+
+			name := statement.Sid
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+
+			principals, err := statement.principals()
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse principal of statement %s: %w", name, err)
+			}
+
+			for _, violation := range evaluateStatement(statement, principals, constraint.Spec) {
+				violations = append(violations, fmt.Sprintf("%s: statement %s %s", constraint.Name, name, violation))
+			}
+		}
+	}
+	return violations, nil
+}
+
+func evaluateStatement(statement policyStatement, principals []string, spec ecrv1beta1.RepositoryPolicyConstraintSpec) []string {
+	violations := make([]string, 0)
+
+	outsiders := make([]string, 0)
+	for _, principal := range principals {
+		for _, forbidden := range spec.ForbiddenPrincipals {
+			if principalMatches(principal, forbidden) {
+				violations = append(violations, fmt.Sprintf("grants access to forbidden principal %s", principal))
+			}
+		}
+		if len(spec.AllowedAccountIds) > 0 && !containsString(spec.AllowedAccountIds, principalAccountId(principal)) {
+			violations = append(violations, fmt.Sprintf("grants access to principal %s outside the allowed accounts", principal))
+			outsiders = append(outsiders, principal)
+		}
+	}
+
+	// forbidden actions apply to outsiders, or to all principals if no accounts are allowed explicitly
+	if len(spec.AllowedAccountIds) == 0 {
+		outsiders = principals
+	}
+	if len(outsiders) > 0 {
+		for _, forbidden := range spec.ForbiddenActions {
+			if grantsAction(statement, forbidden) {
+				violations = append(violations, fmt.Sprintf("grants forbidden action %s to %s", forbidden, strings.Join(outsiders, ", ")))
+			}
+		}
+	}
+
+	for _, required := range spec.RequiredConditions {
+		if !hasConditionKey(statement.Condition, required) {
+			violations = append(violations, fmt.Sprintf("is missing required condition %s", required))
+		}
+	}
+
+	return violations
+}
+
+// Returns the account ID of an account ID or IAM ARN principal, or an empty string
+func principalAccountId(principal string) string {
+	if accountIdPattern.MatchString(principal) {
+		return principal
+	}
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(principal, ":", 6)
+	if len(parts) == 6 && parts[0] == "arn" && accountIdPattern.MatchString(parts[4]) {
+		return parts[4]
+	}
+	return ""
+}
+
+// Checks whether the statement grants the (possibly wildcard) action. A NotAction grants every
+// action not listed, so it grants the action unless a listed action covers it entirely.
+func grantsAction(statement policyStatement, action string) bool {
+	for _, pattern := range statement.Action {
+		if actionMatches(pattern, action) {
+			return true
+		}
+	}
+	if len(statement.NotAction) == 0 {
+		return false
+	}
+	for _, pattern := range statement.NotAction {
+		if wildcardMatches(pattern, action, true) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks whether the (possibly wildcard) statement action and the (possibly wildcard) action
+// overlap, e.g. ecr:* covers ecr:DeleteRepository, which is covered by ecr:Delete* too
+func actionMatches(pattern string, action string) bool {
+	return wildcardMatches(pattern, action, true) || wildcardMatches(action, pattern, true)
+}
+
+// Checks whether the statement principal matches the (possibly wildcard) forbidden principal.
+// A wildcard principal grants access to everyone, so it matches every forbidden principal.
+func principalMatches(principal string, forbidden string) bool {
+	return principal == "*" || wildcardMatches(forbidden, principal, false)
+}
+
+func wildcardMatches(pattern string, value string, ignoreCase bool) bool {
+	expr := "^" + strings.ReplaceAll(strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*"), `\?`, ".") + "$"
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	matched, err := regexp.MatchString(expr, value)
+	return err == nil && matched
+}
+
+func hasConditionKey(conditions map[string]map[string]interface{}, key string) bool {
+	for _, values := range conditions {
+		for k := range values {
+			if strings.EqualFold(k, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
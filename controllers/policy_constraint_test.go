@@ -0,0 +1,78 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+func TestEvaluatePolicyConstraintsActions(t *testing.T) {
+	tests := []struct {
+		name          string
+		statement     string
+		forbidden     []string
+		wantViolation string
+	}{
+		{name: "allowed action", statement: `"Action": "ecr:BatchGetImage"`, forbidden: []string{"ecr:DeleteRepository"}},
+		{name: "forbidden action", statement: `"Action": "ecr:DeleteRepository"`, forbidden: []string{"ecr:DeleteRepository"}, wantViolation: "grants forbidden action ecr:DeleteRepository"},
+		{name: "wildcard statement action", statement: `"Action": "ecr:*"`, forbidden: []string{"ecr:DeleteRepository"}, wantViolation: "grants forbidden action ecr:DeleteRepository"},
+		{name: "wildcard forbidden action", statement: `"Action": ["ecr:BatchGetImage", "ecr:DeleteRepository"]`, forbidden: []string{"ecr:Delete*"}, wantViolation: "grants forbidden action ecr:Delete*"},
+		{name: "wildcard forbidden action not granted", statement: `"Action": "ecr:BatchGetImage"`, forbidden: []string{"ecr:Delete*"}},
+		{name: "case insensitive action", statement: `"Action": "ECR:deleterepository"`, forbidden: []string{"ecr:DeleteRepository"}, wantViolation: "grants forbidden action ecr:DeleteRepository"},
+		{name: "not action grants other actions", statement: `"NotAction": "ecr:GetAuthorizationToken"`, forbidden: []string{"ecr:DeleteRepository"}, wantViolation: "grants forbidden action ecr:DeleteRepository"},
+		{name: "not action excludes forbidden action", statement: `"NotAction": ["ecr:DeleteRepository"]`, forbidden: []string{"ecr:DeleteRepository"}},
+		{name: "not action wildcard excludes forbidden action", statement: `"NotAction": "ecr:Delete*"`, forbidden: []string{"ecr:DeleteRepository", "ecr:Delete*"}},
+		{name: "not action partially excludes forbidden wildcard", statement: `"NotAction": "ecr:DeleteRepository"`, forbidden: []string{"ecr:Delete*"}, wantViolation: "grants forbidden action ecr:Delete*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policyText := `{"Version": "2012-10-17", "Statement": [{"Sid": "test", "Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::123456789012:root"}, ` + tt.statement + `}]}`
+			constraint := ecrv1beta1.RepositoryPolicyConstraint{
+				ObjectMeta: metav1.ObjectMeta{Name: "constraint"},
+				Spec:       ecrv1beta1.RepositoryPolicyConstraintSpec{ForbiddenActions: tt.forbidden},
+			}
+
+			violations, err := evaluatePolicyConstraints(policyText, []ecrv1beta1.RepositoryPolicyConstraint{constraint})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantViolation == "" {
+				if len(violations) > 0 {
+					t.Fatalf("expected no violations, got %v", violations)
+				}
+				return
+			}
+			if !strings.Contains(strings.Join(violations, "; "), tt.wantViolation) {
+				t.Fatalf("expected violation containing %q, got %v", tt.wantViolation, violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+const (
+	policyConfigMapIndexKey    = ".spec.policyTextFrom.configMapKeyRef.name"
+	policySecretIndexKey       = ".spec.policyTextFrom.secretKeyRef.name"
+	lifecycleConfigMapIndexKey = ".spec.lifecyclePolicyTextFrom.configMapKeyRef.name"
+	lifecycleSecretIndexKey    = ".spec.lifecyclePolicyTextFrom.secretKeyRef.name"
+)
+
+// The policyTemplateData holds the variables available to policy document templates
+type policyTemplateData struct {
+	RepositoryArn  string
+	RegistryId     string
+	RepositoryUri  string
+	Namespace      string
+	RepositoryName string
+}
+
+func (d policyTemplateData) values() map[string]string {
+	return nonEmptyValues(map[string]string{
+		"RepositoryArn":  d.RepositoryArn,
+		"RegistryId":     d.RegistryId,
+		"RepositoryUri":  d.RepositoryUri,
+		"Namespace":      d.Namespace,
+		"RepositoryName": d.RepositoryName,
+	})
+}
+
+// The registryPolicyTemplateData holds the variables available to registry policy document templates
+type registryPolicyTemplateData struct {
+	RegistryId string
+	Region     string
+}
+
+func (d registryPolicyTemplateData) values() map[string]string {
+	return nonEmptyValues(map[string]string{
+		"RegistryId": d.RegistryId,
+		"Region":     d.Region,
+	})
+}
+
+// The policyTemplateValues provides the variables of a policy document template
+type policyTemplateValues interface {
+	values() map[string]string
+}
+
+// Drops empty variables, so templates referencing them fail instead of rendering an empty string
+func nonEmptyValues(values map[string]string) map[string]string {
+	for key, value := range values {
+		if value == "" {
+			delete(values, key)
+		}
+	}
+	return values
+}
+
+// Resolves the policy document either from the inline text or the referenced
+// ConfigMap or Secret key. Only referenced documents are rendered as Go template
+// for the given Repository.
+func resolvePolicyText(ctx context.Context, c client.Client, namespace string, text string, from *ecrv1beta1.PolicyTextSource, repository *ecrv1beta1.Repository) (string, error) {
+	data := policyTemplateData{
+		RepositoryArn:  repository.Status.RepositoryArn,
+		RegistryId:     repository.Status.RegistryId,
+		RepositoryUri:  repository.Status.RepositoryUri,
+		Namespace:      repository.Namespace,
+		RepositoryName: ecrRepositoryName(repository),
+	}
+	return renderPolicyText(ctx, c, namespace, text, from, data)
+}
+
+// Resolves the policy document either from the inline text or the referenced
+// ConfigMap or Secret key. Inline text is used as is, referenced documents are
+// rendered as Go template with the given data. Referencing a variable that is
+// not known yet, e.g. before the repository was created, fails the rendering.
+func renderPolicyText(ctx context.Context, c client.Client, namespace string, text string, from *ecrv1beta1.PolicyTextSource, data policyTemplateValues) (string, error) {
+	if from == nil {
+		if text == "" {
+			return "", errors.New("no policy text or policy text reference specified")
+		}
+		return text, nil
+	}
+
+	text, err := lookupPolicyTextSource(ctx, c, namespace, from)
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", errors.New("referenced policy text is empty")
+	}
+
+	tmpl, err := template.New("policy").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse policy template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data.values()); err != nil {
+		return "", fmt.Errorf("unable to render policy template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// Checks whether both policy documents are semantically equal JSON, ignoring formatting
+func policyTextEqual(desired string, actual string) bool {
+	var d, a interface{}
+	if err := json.Unmarshal([]byte(desired), &d); err != nil {
+		return desired == actual
+	}
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(d, a)
+}
+
+func lookupPolicyTextSource(ctx context.Context, c client.Client, namespace string, from *ecrv1beta1.PolicyTextSource) (string, error) {
+	if ref := from.ConfigMapKeyRef; ref != nil {
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, k8stypes.NamespacedName{Name: ref.Name, Namespace: namespace}, configMap); err != nil {
+			return "", err
+		}
+		value, ok := configMap.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("key %s not found in ConfigMap %s", ref.Key, ref.Name)
+		}
+		return value, nil
+	}
+
+	if ref := from.SecretKeyRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, k8stypes.NamespacedName{Name: ref.Name, Namespace: namespace}, secret); err != nil {
+			return "", err
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", fmt.Errorf("key %s not found in Secret %s", ref.Key, ref.Name)
+		}
+		return string(value), nil
+	}
+
+	return "", errors.New("policy text reference requires a configMapKeyRef or secretKeyRef")
+}
+
+func policyConfigMapName(from *ecrv1beta1.PolicyTextSource) []string {
+	if from == nil || from.ConfigMapKeyRef == nil {
+		return nil
+	}
+	return []string{from.ConfigMapKeyRef.Name}
+}
+
+func policySecretName(from *ecrv1beta1.PolicyTextSource) []string {
+	if from == nil || from.SecretKeyRef == nil {
+		return nil
+	}
+	return []string{from.SecretKeyRef.Name}
+}
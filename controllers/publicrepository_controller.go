@@ -0,0 +1,394 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic"
+	"github.com/aws/aws-sdk-go-v2/service/ecrpublic/types"
+)
+
+const ecrPublicRepositoryFinalizer = "publicrepository.ecr.aws.cloud.qaware.de/finalizer"
+
+// PublicRepositoryReconciler reconciles a PublicRepository object
+type PublicRepositoryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=publicrepositories,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=publicrepositories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=publicrepositories/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorypolicyconstraints,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *PublicRepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("publicRepository", req.NamespacedName)
+
+	client, awserr := CreateEcrPublicClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR Public client.")
+		return ctrl.Result{}, awserr
+	}
+
+	// lookup the PublicRepository instance for this reconcile request
+	repository := &ecrv1beta1.PublicRepository{}
+	geterr := r.Get(ctx, req.NamespacedName, repository)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("PublicRepository already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get PublicRepository.")
+		return ctrl.Result{}, geterr
+	}
+
+	// Check if the PublicRepository instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isPublicRepositoryMarkedToBeDeleted := repository.GetDeletionTimestamp() != nil
+	if isPublicRepositoryMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(repository, ecrPublicRepositoryFinalizer) {
+			// Run finalization logic for ecrPublicRepositoryFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizePublicRepository(logger, client, repository); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrPublicRepositoryFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(repository, ecrPublicRepositoryFinalizer)
+			err := r.Update(ctx, repository)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(repository, ecrPublicRepositoryFinalizer) {
+		logger.Info("Update Finalizer for PublicRepository.")
+		controllerutil.AddFinalizer(repository, ecrPublicRepositoryFinalizer)
+		upderr := r.Update(ctx, repository)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update PublicRepository with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	status := repository.Status.DeepCopy()
+
+	// try to get the matching AWS ECR Public repository
+	var publicRepository *types.Repository
+	created := false
+	descout, descerr := client.DescribeRepositories(context.TODO(), &ecrpublic.DescribeRepositoriesInput{
+		RepositoryNames: []string{repository.Name},
+	})
+	if descerr != nil {
+		var rnfe *types.RepositoryNotFoundException
+		if !errors.As(descerr, &rnfe) {
+			logger.Error(descerr, "Could not retrieve list of ECR Public repository.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, descerr
+		}
+
+		// reconcile and create AWS ECR Public repository
+		output, err := client.CreateRepository(context.TODO(), &ecrpublic.CreateRepositoryInput{
+			RepositoryName: aws.String(repository.Name),
+			CatalogData:    createRepositoryCatalogData(repository.Spec.CatalogData),
+			Tags:           createPublicTags(repository.Labels),
+		})
+		if err != nil {
+			logger.Error(err, "Could not create ECR Public repository.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+
+		logger.Info("Created ECR Public repository.", "RepositoryName", output.Repository.RepositoryName,
+			"RepositoryUri", output.Repository.RepositoryUri)
+		publicRepository = output.Repository
+		created = true
+	} else if len(descout.Repositories) > 0 {
+		publicRepository = &descout.Repositories[0]
+	} else {
+		err := errors.New("ECR Public repository not found in DescribeRepositories output")
+		logger.Error(err, "Could not retrieve ECR Public repository.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+	}
+
+	repository.Status.RepositoryArn = aws.ToString(publicRepository.RepositoryArn)
+	repository.Status.RegistryId = aws.ToString(publicRepository.RegistryId)
+	repository.Status.RepositoryUri = aws.ToString(publicRepository.RepositoryUri)
+
+	if !created {
+		// reconcile and update AWS ECR Public repository catalog data
+		_, caterr := client.PutRepositoryCatalogData(context.TODO(), &ecrpublic.PutRepositoryCatalogDataInput{
+			RepositoryName: aws.String(repository.Name),
+			CatalogData:    createRepositoryCatalogData(repository.Spec.CatalogData),
+		})
+		if caterr != nil {
+			logger.Error(caterr, "Could not update catalog data for ECR Public repository.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, caterr
+		}
+		logger.Info("Updated catalog data for ECR Public repository.", "RepositoryName", repository.Name)
+
+		// reconcile and update AWS ECR Public repository tags
+		if tags := createPublicTags(repository.Labels); len(tags) > 0 {
+			_, tagerr := client.TagResource(context.TODO(), &ecrpublic.TagResourceInput{
+				ResourceArn: aws.String(repository.Status.RepositoryArn),
+				Tags:        tags,
+			})
+			if tagerr != nil {
+				logger.Error(tagerr, "Could not update Tags for ECR Public repository.")
+				return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, tagerr
+			}
+			logger.Info("Updated Tags for ECR Public repository.", "ResourceArn", repository.Status.RepositoryArn)
+		}
+	}
+
+	// reconcile and update AWS ECR Public repository policy
+	message, policyerr := r.applyPublicRepositoryPolicy(ctx, logger, client, repository)
+	repository.Status.Message = message
+	if policyerr != nil {
+		repository.Status.Message = policyerr.Error()
+	} else if message == "" {
+		repository.Status.LastAppliedTime = &metav1.Time{Time: time.Now()}
+	}
+
+	if !equality.Semantic.DeepEqual(status, &repository.Status) {
+		if err := r.Status().Update(ctx, repository); err != nil {
+			logger.Error(err, "Failed to update PublicRepository status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if policyerr != nil {
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, policyerr
+	}
+	return ctrl.Result{RequeueAfter: driftDetectionInterval}, nil
+}
+
+// Sets the repository policy of the public repository, or deletes it if none is specified. Returns a message
+// for problems that are not retried until the spec, the referenced policy text or a constraint changes, and
+// an error for failed AWS calls.
+func (r *PublicRepositoryReconciler) applyPublicRepositoryPolicy(ctx context.Context, logger logr.Logger, client *ecrpublic.Client, repository *ecrv1beta1.PublicRepository) (string, error) {
+	if repository.Spec.PolicyText == "" && repository.Spec.PolicyTextFrom == nil {
+		return "", r.deletePublicRepositoryPolicy(logger, client, repository.Name)
+	}
+
+	data := policyTemplateData{
+		RepositoryArn:  repository.Status.RepositoryArn,
+		RegistryId:     repository.Status.RegistryId,
+		RepositoryUri:  repository.Status.RepositoryUri,
+		Namespace:      repository.Namespace,
+		RepositoryName: repository.Name,
+	}
+	policyText, texterr := renderPolicyText(ctx, r.Client, repository.Namespace, repository.Spec.PolicyText, repository.Spec.PolicyTextFrom, data)
+	if texterr != nil {
+		// the referenced ConfigMap or Secret is watched, so we will be called again on changes
+		logger.Error(texterr, "Could not resolve policy text of ECR Public repository.")
+		return texterr.Error(), nil
+	}
+
+	// enforce the cluster-wide constraints, a previously applied policy that can not be evaluated
+	// or violates a constraint now is removed
+	constraints, listerr := listPolicyConstraints(ctx, r.Client)
+	if listerr != nil {
+		logger.Error(listerr, "Unable to list RepositoryPolicyConstraint objects.")
+		return "", listerr
+	}
+	violations, evalerr := evaluatePolicyConstraints(policyText, constraints)
+	if evalerr != nil {
+		logger.Error(evalerr, "Unable to evaluate RepositoryPolicyConstraints.", "RepositoryName", repository.Name)
+		return evalerr.Error(), r.deletePublicRepositoryPolicy(logger, client, repository.Name)
+	}
+	if len(violations) > 0 {
+		logger.Info("Policy of ECR Public repository violates RepositoryPolicyConstraints. Removing.", "RepositoryName", repository.Name, "violations", violations)
+		message := fmt.Sprintf("policy violates %d constraint(s): %s", len(violations), strings.Join(violations, "; "))
+		return message, r.deletePublicRepositoryPolicy(logger, client, repository.Name)
+	}
+
+	_, seterr := client.SetRepositoryPolicy(context.TODO(), &ecrpublic.SetRepositoryPolicyInput{
+		RepositoryName: aws.String(repository.Name),
+		PolicyText:     aws.String(policyText),
+	})
+	if seterr != nil {
+		logger.Error(seterr, "Could not set policy of ECR Public repository.")
+		return "", seterr
+	}
+
+	logger.Info("Updated policy of ECR Public repository.", "RepositoryName", repository.Name)
+	return "", nil
+}
+
+func (r *PublicRepositoryReconciler) deletePublicRepositoryPolicy(logger logr.Logger, client *ecrpublic.Client, repositoryName string) error {
+	_, delerr := client.DeleteRepositoryPolicy(context.TODO(), &ecrpublic.DeleteRepositoryPolicyInput{
+		RepositoryName: aws.String(repositoryName),
+	})
+	if delerr != nil {
+		var rpnfe *types.RepositoryPolicyNotFoundException
+		if !errors.As(delerr, &rpnfe) {
+			logger.Error(delerr, "Could not delete policy of ECR Public repository.")
+			return delerr
+		}
+	}
+	return nil
+}
+
+func (r *PublicRepositoryReconciler) finalizePublicRepository(logger logr.Logger, client *ecrpublic.Client, repository *ecrv1beta1.PublicRepository) error {
+	output, err := client.DeleteRepository(context.TODO(), &ecrpublic.DeleteRepositoryInput{
+		RepositoryName: aws.String(repository.Name),
+		Force:          true,
+	})
+	if err != nil {
+		var rnfe *types.RepositoryNotFoundException
+		if errors.As(err, &rnfe) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ECR Public repository already deleted. Skipping.")
+			return nil
+		}
+
+		logger.Error(err, "Could not delete ECR Public repository.")
+		return err
+	}
+
+	logger.Info("Successfully finalized and deleted ECR Public repository.", "repositoryUri", output.Repository.RepositoryUri)
+	return nil
+}
+
+func createRepositoryCatalogData(c *ecrv1beta1.PublicRepositoryCatalogData) *types.RepositoryCatalogDataInput {
+	if c == nil {
+		return &types.RepositoryCatalogDataInput{}
+	}
+	input := &types.RepositoryCatalogDataInput{
+		Architectures:    c.Architectures,
+		OperatingSystems: c.OperatingSystems,
+		LogoImageBlob:    c.LogoImageBlob,
+	}
+	if c.Description != "" {
+		input.Description = aws.String(c.Description)
+	}
+	if c.AboutText != "" {
+		input.AboutText = aws.String(c.AboutText)
+	}
+	if c.UsageText != "" {
+		input.UsageText = aws.String(c.UsageText)
+	}
+	return input
+}
+
+func createPublicTags(labels map[string]string) []types.Tag {
+	tags := make([]types.Tag, 0)
+	for k, v := range labels {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return tags
+}
+
+// find all PublicRepositories in the namespace referencing the changed ConfigMap or Secret by name
+func (r *PublicRepositoryReconciler) findObjectsForIndex(indexKey string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &ecrv1beta1.PublicRepositoryList{}
+		err := r.List(context.TODO(), list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{indexKey: obj.GetName()})
+		if err != nil {
+			return []reconcile.Request{}
+		}
+
+		requests := make([]reconcile.Request, len(list.Items))
+		for i, item := range list.Items {
+			requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+		}
+		return requests
+	}
+}
+
+// find all PublicRepositories of the cluster, since any of them might be affected by a constraint change
+func (r *PublicRepositoryReconciler) findObjectsForConstraint(obj client.Object) []reconcile.Request {
+	list := &ecrv1beta1.PublicRepositoryList{}
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, item := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PublicRepositoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.PublicRepository{}, policyConfigMapIndexKey, func(o client.Object) []string {
+		return policyConfigMapName(o.(*ecrv1beta1.PublicRepository).Spec.PolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.PublicRepository{}, policySecretIndexKey, func(o client.Object) []string {
+		return policySecretName(o.(*ecrv1beta1.PublicRepository).Spec.PolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.PublicRepository{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&source.Kind{Type: &ecrv1beta1.RepositoryPolicyConstraint{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForConstraint)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(policyConfigMapIndexKey))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(policySecretIndexKey))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,198 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+const (
+	// the namespace label to opt-in to the rewrite of upstream images
+	pullThroughCacheLabel = "ecr.aws.cloud.qaware.de/pull-through-cache"
+	// the Pod annotation with the original images by container name
+	originalImagesAnnotation = "ecr.aws.cloud.qaware.de/original-images"
+)
+
+// the registry hosts of Docker Hub, images without registry are pulled from Docker Hub
+var dockerHubHosts = []string{"docker.io", "index.docker.io", "registry-1.docker.io"}
+
+//+kubebuilder:webhook:path=/mutate-v1-pod-pull-through-cache,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod-pull-through-cache.kb.io,admissionReviewVersions={v1,v1beta1}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=pullthroughcacherules,verbs=get;list;watch
+
+// PullThroughCacheMutator rewrites upstream container images of Pods to the ECR pull through cache
+type PullThroughCacheMutator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+
+	regionMutex sync.Mutex
+	region      string
+}
+
+// Handle rewrites the images of upstream registries with a ready PullThroughCacheRule to the ECR cache
+// prefix, if the namespace has opted-in with the ecr.aws.cloud.qaware.de/pull-through-cache=enabled label.
+func (m *PullThroughCacheMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrllog.FromContext(ctx).WithValues("pod", req.Namespace+"/"+req.Name)
+
+	pod := &corev1.Pod{}
+	if err := m.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := m.Client.Get(ctx, k8stypes.NamespacedName{Name: req.Namespace}, namespace); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if namespace.Labels[pullThroughCacheLabel] != "enabled" {
+		return admission.Allowed("")
+	}
+
+	rules := &ecrv1beta1.PullThroughCacheRuleList{}
+	if err := m.Client.List(ctx, rules); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// without a region the cache registry is unknown, the images are kept until the region can be loaded
+	region, err := m.awsRegion()
+	if err != nil {
+		logger.Error(err, "Unable to determine AWS region. Skipping image rewrite.")
+		return admission.Allowed("")
+	}
+
+	// the cache prefixes of ready rules by upstream registry host
+	prefixes := map[string]string{}
+	for _, rule := range rules.Items {
+		if !meta.IsStatusConditionTrue(rule.Status.Conditions, conditionTypeReady) || rule.Status.RegistryId == "" {
+			continue
+		}
+		registry := fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com/%s", rule.Status.RegistryId, region, rule.Spec.EcrRepositoryPrefix)
+		host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(rule.Spec.UpstreamRegistryUrl, "https://"), "http://"), "/")
+		if containsString(dockerHubHosts, host) {
+			for _, h := range dockerHubHosts {
+				prefixes[h] = registry
+			}
+			prefixes[""] = registry
+		} else {
+			prefixes[host] = registry
+		}
+	}
+	if len(prefixes) == 0 {
+		return admission.Allowed("")
+	}
+
+	originals := map[string]string{}
+	rewrite := func(containers []corev1.Container) {
+		for i := range containers {
+			if image, ok := rewriteImage(containers[i].Image, prefixes); ok {
+				originals[containers[i].Name] = containers[i].Image
+				containers[i].Image = image
+			}
+		}
+	}
+	rewrite(pod.Spec.InitContainers)
+	rewrite(pod.Spec.Containers)
+	if len(originals) == 0 {
+		return admission.Allowed("")
+	}
+
+	annotation, err := json.Marshal(originals)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[originalImagesAnnotation] = string(annotation)
+
+	marshaled, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	logger.Info("Rewrote images to ECR pull through cache.", "originalImages", originals)
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// InjectDecoder injects the decoder into the PullThroughCacheMutator.
+func (m *PullThroughCacheMutator) InjectDecoder(d *admission.Decoder) error {
+	m.decoder = d
+	return nil
+}
+
+// Returns the region of the operator AWS config, the pull through cache rules are created in.
+// The config is loaded again until a region has been found.
+func (m *PullThroughCacheMutator) awsRegion() (string, error) {
+	m.regionMutex.Lock()
+	defer m.regionMutex.Unlock()
+
+	if m.region == "" {
+		cfg, err := LoadAwsConfig()
+		if err != nil {
+			return "", err
+		}
+		if cfg.Region == "" {
+			return "", errors.New("no AWS region configured")
+		}
+		m.region = cfg.Region
+	}
+	return m.region, nil
+}
+
+// Rewrites the image to the cache prefix of its registry, official Docker Hub images are in the library namespace
+func rewriteImage(image string, prefixes map[string]string) (string, bool) {
+	ref := ParseImageReference(image)
+	prefix, ok := prefixes[ref.Registry]
+	if !ok {
+		return "", false
+	}
+
+	repository := ref.Repository
+	if (ref.Registry == "" || containsString(dockerHubHosts, ref.Registry)) && !strings.Contains(repository, "/") {
+		repository = "library/" + repository
+	}
+
+	rewritten := prefix + "/" + repository
+	if ref.Tag != "" {
+		rewritten += ":" + ref.Tag
+	}
+	if ref.Digest != "" {
+		rewritten += "@" + ref.Digest
+	}
+	return rewritten, true
+}
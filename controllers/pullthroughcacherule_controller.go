@@ -0,0 +1,415 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	ecrPullThroughCacheFinalizer = "pullthroughcache.ecr.aws.cloud.qaware.de/finalizer"
+	// the label of Repository objects adopting a repository created by a pull through cache rule
+	pullThroughCacheRuleLabel = "ecr.aws.cloud.qaware.de/pull-through-cache-rule"
+	// the type of the condition reporting whether an AWS resource has been reconciled
+	conditionTypeReady = "Ready"
+)
+
+// PullThroughCacheRuleReconciler reconciles a PullThroughCacheRule object
+type PullThroughCacheRuleReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=pullthroughcacherules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=pullthroughcacherules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=pullthroughcacherules/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch;create;delete
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *PullThroughCacheRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("pullThroughCacheRule", req.NamespacedName)
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	// lookup the PullThroughCacheRule instance for this reconcile request
+	cacheRule := &ecrv1beta1.PullThroughCacheRule{}
+	geterr := r.Get(ctx, req.NamespacedName, cacheRule)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("PullThroughCacheRule already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get PullThroughCacheRule.")
+		return ctrl.Result{}, geterr
+	}
+
+	// Check if the PullThroughCacheRule instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isCacheRuleMarkedToBeDeleted := cacheRule.GetDeletionTimestamp() != nil
+	if isCacheRuleMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(cacheRule, ecrPullThroughCacheFinalizer) {
+			// Run finalization logic for ecrPullThroughCacheFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizePullThroughCacheRule(logger, client, cacheRule); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrPullThroughCacheFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(cacheRule, ecrPullThroughCacheFinalizer)
+			err := r.Update(ctx, cacheRule)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(cacheRule, ecrPullThroughCacheFinalizer) {
+		logger.Info("Update Finalizer for PullThroughCacheRule.")
+		controllerutil.AddFinalizer(cacheRule, ecrPullThroughCacheFinalizer)
+		upderr := r.Update(ctx, cacheRule)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update PullThroughCacheRule with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	status := cacheRule.Status.DeepCopy()
+	spec := cacheRule.Spec
+
+	// the prefix identifies the rule, so a changed prefix requires to delete the previous rule
+	if previous := cacheRule.Status.EcrRepositoryPrefix; previous != "" && previous != spec.EcrRepositoryPrefix {
+		if err := deletePullThroughCacheRule(logger, client, previous); err != nil {
+			return r.updateNotReady(ctx, logger, cacheRule, "DeleteFailed", err)
+		}
+		cacheRule.Status.EcrRepositoryPrefix = ""
+	}
+
+	rule, descerr := describePullThroughCacheRule(client, spec.EcrRepositoryPrefix)
+	if descerr != nil {
+		return r.updateNotReady(ctx, logger, cacheRule, "DescribeFailed", descerr)
+	}
+
+	// the upstream registry can not be updated, so the rule needs to be recreated
+	if rule != nil && aws.ToString(rule.UpstreamRegistryUrl) != spec.UpstreamRegistryUrl {
+		logger.Info("Upstream registry of pull through cache rule changed. Recreating.", "upstreamRegistryUrl", rule.UpstreamRegistryUrl)
+		if err := deletePullThroughCacheRule(logger, client, spec.EcrRepositoryPrefix); err != nil {
+			return r.updateNotReady(ctx, logger, cacheRule, "DeleteFailed", err)
+		}
+		rule = nil
+	}
+
+	if rule == nil {
+		input := &ecr.CreatePullThroughCacheRuleInput{
+			EcrRepositoryPrefix: aws.String(spec.EcrRepositoryPrefix),
+			UpstreamRegistryUrl: aws.String(spec.UpstreamRegistryUrl),
+		}
+		if spec.CredentialArn != "" {
+			input.CredentialArn = aws.String(spec.CredentialArn)
+		}
+		output, err := client.CreatePullThroughCacheRule(context.TODO(), input)
+		if err != nil {
+			return r.updateNotReady(ctx, logger, cacheRule, "CreateFailed", err)
+		}
+
+		logger.Info("Created pull through cache rule.", "ecrRepositoryPrefix", output.EcrRepositoryPrefix, "upstreamRegistryUrl", output.UpstreamRegistryUrl)
+		cacheRule.Status.RegistryId = aws.ToString(output.RegistryId)
+		cacheRule.Status.CreatedAt = &metav1.Time{Time: aws.ToTime(output.CreatedAt)}
+	} else {
+		if aws.ToString(rule.CredentialArn) != spec.CredentialArn {
+			_, err := client.UpdatePullThroughCacheRule(context.TODO(), &ecr.UpdatePullThroughCacheRuleInput{
+				EcrRepositoryPrefix: aws.String(spec.EcrRepositoryPrefix),
+				CredentialArn:       aws.String(spec.CredentialArn),
+			})
+			if err != nil {
+				return r.updateNotReady(ctx, logger, cacheRule, "UpdateFailed", err)
+			}
+			logger.Info("Updated credentials of pull through cache rule.", "ecrRepositoryPrefix", spec.EcrRepositoryPrefix)
+		}
+		cacheRule.Status.RegistryId = aws.ToString(rule.RegistryId)
+		cacheRule.Status.CreatedAt = &metav1.Time{Time: aws.ToTime(rule.CreatedAt)}
+	}
+	cacheRule.Status.EcrRepositoryPrefix = spec.EcrRepositoryPrefix
+
+	cached, err := findCachedRepositories(client, spec.EcrRepositoryPrefix)
+	if err != nil {
+		logger.Error(err, "Could not describe cached repositories.")
+	} else {
+		cacheRule.Status.CachedRepositories = make([]string, 0, len(cached))
+		for _, repository := range cached {
+			cacheRule.Status.CachedRepositories = append(cacheRule.Status.CachedRepositories, aws.ToString(repository.RepositoryName))
+		}
+
+		adopted, err := r.adoptCachedRepositories(ctx, logger, client, cacheRule, cached)
+		if err != nil {
+			logger.Error(err, "Could not adopt cached repositories.")
+		} else {
+			cacheRule.Status.AdoptedRepositories = adopted
+		}
+	}
+
+	meta.SetStatusCondition(&cacheRule.Status.Conditions, metav1.Condition{
+		Type:               conditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Created",
+		Message:            "Pull through cache rule is active",
+		ObservedGeneration: cacheRule.Generation,
+	})
+	if !equality.Semantic.DeepEqual(status, &cacheRule.Status) {
+		if err := r.Status().Update(ctx, cacheRule); err != nil {
+			logger.Error(err, "Failed to update PullThroughCacheRule status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: driftDetectionInterval}, nil
+}
+
+// Reports the failed AWS operation in the Ready condition and requeues the request
+func (r *PullThroughCacheRuleReconciler) updateNotReady(ctx context.Context, logger logr.Logger, cacheRule *ecrv1beta1.PullThroughCacheRule, reason string, err error) (ctrl.Result, error) {
+	logger.Error(err, "Could not reconcile pull through cache rule.", "reason", reason)
+	meta.SetStatusCondition(&cacheRule.Status.Conditions, metav1.Condition{
+		Type:               conditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: cacheRule.Generation,
+	})
+	if upderr := r.Status().Update(ctx, cacheRule); upderr != nil {
+		logger.Error(upderr, "Failed to update PullThroughCacheRule status")
+	}
+	return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(30) * time.Second}, nil
+}
+
+func (r *PullThroughCacheRuleReconciler) finalizePullThroughCacheRule(logger logr.Logger, client *ecr.Client, cacheRule *ecrv1beta1.PullThroughCacheRule) error {
+	prefix := cacheRule.Status.EcrRepositoryPrefix
+	if prefix == "" {
+		prefix = cacheRule.Spec.EcrRepositoryPrefix
+	}
+	if err := deletePullThroughCacheRule(logger, client, prefix); err != nil {
+		return err
+	}
+
+	logger.Info("Successfully finalized and deleted PullThroughCacheRule.")
+	return nil
+}
+
+func deletePullThroughCacheRule(logger logr.Logger, client *ecr.Client, prefix string) error {
+	_, delerr := client.DeletePullThroughCacheRule(context.TODO(), &ecr.DeletePullThroughCacheRuleInput{
+		EcrRepositoryPrefix: aws.String(prefix),
+	})
+	if delerr != nil {
+		var ptcrnfe *types.PullThroughCacheRuleNotFoundException
+		if errors.As(delerr, &ptcrnfe) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("Pull through cache rule already deleted. Skipping.", "ecrRepositoryPrefix", prefix)
+			return nil
+		}
+		logger.Error(delerr, "Failed to delete pull through cache rule.", "ecrRepositoryPrefix", prefix)
+		return delerr
+	}
+	return nil
+}
+
+// Returns the pull through cache rule with the given prefix, or nil if it does not exist
+func describePullThroughCacheRule(client *ecr.Client, prefix string) (*types.PullThroughCacheRule, error) {
+	output, err := client.DescribePullThroughCacheRules(context.TODO(), &ecr.DescribePullThroughCacheRulesInput{
+		EcrRepositoryPrefixes: []string{prefix},
+	})
+	if err != nil {
+		var ptcrnfe *types.PullThroughCacheRuleNotFoundException
+		if errors.As(err, &ptcrnfe) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(output.PullThroughCacheRules) == 0 {
+		return nil, nil
+	}
+	return &output.PullThroughCacheRules[0], nil
+}
+
+// Creates a Repository object in the adoption namespace for each cached repository and deletes
+// the adopted Repository objects of repositories no longer cached or adopted. The cached repository
+// is tagged with the owning Repository object before, deleting an adopted Repository object keeps
+// the ECR repository. Returns the names of the adopted Repository objects.
+func (r *PullThroughCacheRuleReconciler) adoptCachedRepositories(ctx context.Context, logger logr.Logger, ecrClient *ecr.Client, cacheRule *ecrv1beta1.PullThroughCacheRule, cached []types.Repository) ([]string, error) {
+	namespace := cacheRule.Spec.AdoptionNamespace
+	desired := make(map[string]types.Repository)
+	if namespace != "" {
+		for _, repository := range cached {
+			desired[adoptedRepositoryName(aws.ToString(repository.RepositoryName))] = repository
+		}
+	}
+
+	list := &ecrv1beta1.RepositoryList{}
+	if err := r.List(ctx, list, client.MatchingLabels{pullThroughCacheRuleLabel: cacheRule.Name}); err != nil {
+		return nil, err
+	}
+
+	adopted := make([]string, 0, len(desired))
+	for i := range list.Items {
+		repository := &list.Items[i]
+		if !metav1.IsControlledBy(repository, cacheRule) {
+			// the label alone does not make a Repository object adopted by this rule
+			continue
+		}
+		if _, ok := desired[repository.Name]; ok && repository.Namespace == namespace {
+			adopted = append(adopted, repository.Name)
+			delete(desired, repository.Name)
+			continue
+		}
+
+		logger.Info("Deleting adopted Repository no longer cached.", "repository", repository.Namespace+"/"+repository.Name)
+		if err := r.Delete(ctx, repository); err != nil && !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	for name, cachedRepository := range desired {
+		repositoryName := aws.ToString(cachedRepository.RepositoryName)
+		if len(name) > validation.DNS1123SubdomainMaxLength {
+			logger.Info("Name of cached repository too long to adopt. Skipping.", "repositoryName", repositoryName)
+			continue
+		}
+
+		repository := &ecrv1beta1.Repository{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels:    map[string]string{pullThroughCacheRuleLabel: cacheRule.Name},
+			},
+			Spec: ecrv1beta1.RepositorySpec{
+				RepositoryName:     repositoryName,
+				ImageTagMutability: ecrv1beta1.ImageTagMutability(cachedRepository.ImageTagMutability),
+			},
+		}
+		if c := cachedRepository.ImageScanningConfiguration; c != nil {
+			repository.Spec.ImageScanningConfiguration = &ecrv1beta1.ImageScanningConfiguration{ScanOnPush: c.ScanOnPush}
+		}
+		if c := cachedRepository.EncryptionConfiguration; c != nil {
+			repository.Spec.EncryptionConfiguration = &ecrv1beta1.EncryptionConfiguration{
+				EncryptionType: ecrv1beta1.EncryptionType(c.EncryptionType),
+				KmsKey:         c.KmsKey,
+			}
+		}
+		if err := controllerutil.SetControllerReference(cacheRule, repository, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		// the Repository controller only manages ECR repositories tagged with the owning object
+		_, err := ecrClient.TagResource(context.TODO(), &ecr.TagResourceInput{
+			ResourceArn: cachedRepository.RepositoryArn,
+			Tags:        []types.Tag{ownerTag(repository)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := r.Create(ctx, repository); err != nil {
+			if k8serrors.IsAlreadyExists(err) {
+				// a Repository object not adopted from this rule already uses the name
+				logger.Info("Repository with the name of the cached repository already exists. Skipping.", "repository", namespace+"/"+name)
+				continue
+			}
+			return nil, err
+		}
+
+		logger.Info("Adopted cached repository.", "repository", namespace+"/"+name, "repositoryName", repositoryName)
+		adopted = append(adopted, name)
+	}
+
+	sort.Strings(adopted)
+	return adopted, nil
+}
+
+// Returns the Repository object name for a cached repository, e.g. docker-hub--library--nginx
+func adoptedRepositoryName(repositoryName string) string {
+	return strings.NewReplacer("/", "--", "_", "-").Replace(repositoryName)
+}
+
+// Returns all repositories created by the pull through cache rule
+func findCachedRepositories(client *ecr.Client, prefix string) ([]types.Repository, error) {
+	cached := make([]types.Repository, 0)
+	paginator := ecr.NewDescribeRepositoriesPaginator(client, &ecr.DescribeRepositoriesInput{})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, repository := range output.Repositories {
+			if strings.HasPrefix(aws.ToString(repository.RepositoryName), prefix+"/") {
+				cached = append(cached, repository)
+			}
+		}
+	}
+	return cached, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PullThroughCacheRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.PullThroughCacheRule{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,493 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+const (
+	ecrCredentialsFinalizer = "credentials.ecr.aws.cloud.qaware.de/finalizer"
+	ecrCredentialsLabel     = "ecr.aws.cloud.qaware.de/registry-credentials"
+
+	// ECR tokens are valid for 12 hours, refresh at least one hour after the token was issued
+	maxRefreshBefore = time.Duration(11) * time.Hour
+)
+
+// RegistryCredentialsReconciler reconciles a RegistryCredentials object
+type RegistryCredentialsReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// The dockerConfigJson defines the content of a kubernetes.io/dockerconfigjson Secret
+type dockerConfigJson struct {
+	Auths map[string]dockerConfigEntry `json:"auths"`
+}
+
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registrycredentials,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registrycredentials/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registrycredentials/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *RegistryCredentialsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("registryCredentials", req.NamespacedName)
+
+	// lookup the RegistryCredentials instance for this reconcile request
+	registryCredentials := &ecrv1beta1.RegistryCredentials{}
+	geterr := r.Get(ctx, req.NamespacedName, registryCredentials)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("RegistryCredentials already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get RegistryCredentials.")
+		return ctrl.Result{}, geterr
+	}
+
+	// Check if the RegistryCredentials instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isRegistryCredentialsMarkedToBeDeleted := registryCredentials.GetDeletionTimestamp() != nil
+	if isRegistryCredentialsMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(registryCredentials, ecrCredentialsFinalizer) {
+			// Run finalization logic for registryCredentials. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeRegistryCredentials(ctx, logger, registryCredentials); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrCredentialsFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(registryCredentials, ecrCredentialsFinalizer)
+			err := r.Update(ctx, registryCredentials)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(registryCredentials, ecrCredentialsFinalizer) {
+		logger.Info("Update Finalizer for RegistryCredentials.")
+		controllerutil.AddFinalizer(registryCredentials, ecrCredentialsFinalizer)
+		upderr := r.Update(ctx, registryCredentials)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update RegistryCredentials with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	namespaces, nserr := r.findTargetNamespaces(ctx, registryCredentials)
+	if nserr != nil {
+		logger.Error(nserr, "Unable to find target Namespaces.")
+		return ctrl.Result{}, nserr
+	}
+
+	// refresh the token if it is about to expire or the target namespaces have changed
+	refreshBefore := registryCredentialsRefreshBefore(registryCredentials)
+	refreshAt := time.Now()
+	if expiresAt := registryCredentials.Status.ExpiresAt; expiresAt != nil {
+		refreshAt = expiresAt.Add(-refreshBefore)
+	}
+	applied := append(append([]string{}, registryCredentials.Status.Namespaces...), registryCredentials.Status.ConflictingNamespaces...)
+	upToDate := registryCredentials.Status.ObservedGeneration == registryCredentials.Generation &&
+		stringSetEqual(namespaces, applied) && r.secretsExist(ctx, registryCredentials, namespaces)
+	if time.Now().Before(refreshAt) && upToDate {
+		return ctrl.Result{RequeueAfter: time.Until(refreshAt)}, nil
+	}
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	output, tokenerr := client.GetAuthorizationToken(context.TODO(), &ecr.GetAuthorizationTokenInput{
+		RegistryIds: registryCredentials.Spec.RegistryIds,
+	})
+	if tokenerr != nil {
+		logger.Error(tokenerr, "Could not get ECR authorization token.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, tokenerr
+	}
+
+	dockerConfig, endpoints, expiresAt, configerr := createDockerConfigJson(output)
+	if configerr != nil {
+		logger.Error(configerr, "Could not create docker config from ECR authorization token.")
+		return ctrl.Result{}, configerr
+	}
+
+	written := make([]string, 0, len(namespaces))
+	conflicting := make([]string, 0)
+	for _, namespace := range namespaces {
+		if err := r.writeSecret(ctx, registryCredentials, namespace, dockerConfig); err != nil {
+			if errors.Is(err, errSecretNotOwned) {
+				logger.Info("Secret not created for RegistryCredentials already exists. Skipping.", "namespace", namespace, "secret", registryCredentials.Spec.SecretName)
+				conflicting = append(conflicting, namespace)
+				continue
+			}
+			logger.Error(err, "Could not write registry credentials Secret.", "namespace", namespace)
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+		written = append(written, namespace)
+		for _, name := range registryCredentials.Spec.ServiceAccountNames {
+			if err := r.addImagePullSecret(ctx, namespace, name, registryCredentials.Spec.SecretName); err != nil {
+				logger.Error(err, "Could not add imagePullSecret to ServiceAccount.", "namespace", namespace, "serviceAccount", name)
+				return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+			}
+		}
+	}
+
+	// cleanup the Secrets in namespaces that are no longer targeted or were written under a previous name,
+	// and the imagePullSecrets of ServiceAccounts that are no longer listed
+	secretName, serviceAccountNames := appliedRegistryCredentials(registryCredentials)
+	for _, namespace := range registryCredentials.Status.Namespaces {
+		if !containsString(written, namespace) || secretName != registryCredentials.Spec.SecretName {
+			if err := r.removeSecret(ctx, registryCredentials, namespace, secretName, serviceAccountNames); err != nil {
+				logger.Error(err, "Could not remove registry credentials Secret.", "namespace", namespace)
+				return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+			}
+			continue
+		}
+		for _, name := range serviceAccountNames {
+			if containsString(registryCredentials.Spec.ServiceAccountNames, name) {
+				continue
+			}
+			if err := r.removeImagePullSecret(ctx, namespace, name, secretName); err != nil {
+				logger.Error(err, "Could not remove imagePullSecret from ServiceAccount.", "namespace", namespace, "serviceAccount", name)
+				return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+			}
+		}
+	}
+
+	logger.Info("Refreshed registry credentials.", "namespaces", written, "expiresAt", expiresAt)
+
+	// we need to update the status
+	now := metav1.Now()
+	registryCredentials.Status.ProxyEndpoints = endpoints
+	registryCredentials.Status.Namespaces = written
+	registryCredentials.Status.ConflictingNamespaces = conflicting
+	registryCredentials.Status.SecretName = registryCredentials.Spec.SecretName
+	registryCredentials.Status.ServiceAccountNames = registryCredentials.Spec.ServiceAccountNames
+	registryCredentials.Status.ExpiresAt = &metav1.Time{Time: expiresAt}
+	registryCredentials.Status.LastRefreshTime = &now
+	registryCredentials.Status.ObservedGeneration = registryCredentials.Generation
+	if err := r.Status().Update(ctx, registryCredentials); err != nil {
+		logger.Error(err, "Failed to update RegistryCredentials status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: time.Until(expiresAt.Add(-refreshBefore))}, nil
+}
+
+// Returns how long before the token expires it is refreshed, capped to the token validity
+func registryCredentialsRefreshBefore(rc *ecrv1beta1.RegistryCredentials) time.Duration {
+	refreshBefore := rc.Spec.RefreshBefore.Duration
+	if refreshBefore > maxRefreshBefore {
+		return maxRefreshBefore
+	}
+	if refreshBefore < 0 {
+		return 0
+	}
+	return refreshBefore
+}
+
+// Returns the Secret name and ServiceAccount names the credentials were last written with. Objects written
+// before these were recorded in the status fall back to the spec, including all listed ServiceAccounts.
+func appliedRegistryCredentials(rc *ecrv1beta1.RegistryCredentials) (string, []string) {
+	secretName := rc.Status.SecretName
+	if secretName == "" {
+		secretName = rc.Spec.SecretName
+	}
+
+	serviceAccountNames := append([]string{}, rc.Status.ServiceAccountNames...)
+	for _, name := range rc.Spec.ServiceAccountNames {
+		if !containsString(serviceAccountNames, name) {
+			serviceAccountNames = append(serviceAccountNames, name)
+		}
+	}
+	return secretName, serviceAccountNames
+}
+
+// Returns the sorted names of the named and selected namespaces
+func (r *RegistryCredentialsReconciler) findTargetNamespaces(ctx context.Context, rc *ecrv1beta1.RegistryCredentials) ([]string, error) {
+	names := map[string]bool{}
+	for _, name := range rc.Spec.Namespaces {
+		names[name] = true
+	}
+
+	if rc.Spec.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(rc.Spec.NamespaceSelector)
+		if err != nil {
+			return nil, err
+		}
+		list := &corev1.NamespaceList{}
+		if err := r.List(ctx, list, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		for _, namespace := range list.Items {
+			names[namespace.Name] = true
+		}
+	}
+
+	namespaces := make([]string, 0, len(names))
+	for name := range names {
+		namespaces = append(namespaces, name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// Creates the docker config JSON for all returned authorization data, and returns the endpoints and the earliest expiry
+func createDockerConfigJson(output *ecr.GetAuthorizationTokenOutput) ([]byte, []string, time.Time, error) {
+	config := dockerConfigJson{Auths: map[string]dockerConfigEntry{}}
+	endpoints := make([]string, 0, len(output.AuthorizationData))
+	expiresAt := time.Now().Add(time.Duration(12) * time.Hour)
+
+	for _, data := range output.AuthorizationData {
+		if data.AuthorizationToken == nil || data.ProxyEndpoint == nil {
+			continue
+		}
+
+		// the token is the base64 encoded username and password separated by a colon
+		decoded, err := base64.StdEncoding.DecodeString(*data.AuthorizationToken)
+		if err != nil {
+			return nil, nil, expiresAt, err
+		}
+		credentials := strings.SplitN(string(decoded), ":", 2)
+		if len(credentials) != 2 {
+			return nil, nil, expiresAt, fmt.Errorf("invalid authorization token for %s", *data.ProxyEndpoint)
+		}
+
+		registry := *data.ProxyEndpoint
+		if u, err := url.Parse(registry); err == nil && u.Host != "" {
+			registry = u.Host
+		}
+		config.Auths[registry] = dockerConfigEntry{
+			Username: credentials[0],
+			Password: credentials[1],
+			Auth:     *data.AuthorizationToken,
+		}
+		endpoints = append(endpoints, registry)
+
+		if data.ExpiresAt != nil && data.ExpiresAt.Before(expiresAt) {
+			expiresAt = *data.ExpiresAt
+		}
+	}
+
+	bytes, err := json.Marshal(config)
+	return bytes, endpoints, expiresAt, err
+}
+
+// the error of writing a Secret that already exists and was not created for the RegistryCredentials object
+var errSecretNotOwned = errors.New("secret not created for the RegistryCredentials")
+
+// Creates or updates the Secret in the namespace. Existing Secrets not controlled by the
+// RegistryCredentials object are never taken over, errSecretNotOwned is returned instead.
+func (r *RegistryCredentialsReconciler) writeSecret(ctx context.Context, rc *ecrv1beta1.RegistryCredentials, namespace string, dockerConfig []byte) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: rc.Spec.SecretName, Namespace: namespace}}
+	_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		if !secret.CreationTimestamp.IsZero() && !metav1.IsControlledBy(secret, rc) {
+			return errSecretNotOwned
+		}
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[ecrCredentialsLabel] = rc.Name
+		secret.Type = corev1.SecretTypeDockerConfigJson
+		secret.Data = map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig}
+		return controllerutil.SetControllerReference(rc, secret, r.Scheme)
+	})
+	return err
+}
+
+func (r *RegistryCredentialsReconciler) secretsExist(ctx context.Context, rc *ecrv1beta1.RegistryCredentials, namespaces []string) bool {
+	for _, namespace := range namespaces {
+		secret := &corev1.Secret{}
+		if err := r.Get(ctx, k8stypes.NamespacedName{Name: rc.Spec.SecretName, Namespace: namespace}, secret); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *RegistryCredentialsReconciler) removeSecret(ctx context.Context, rc *ecrv1beta1.RegistryCredentials, namespace string, secretName string, serviceAccountNames []string) error {
+	for _, name := range serviceAccountNames {
+		if err := r.removeImagePullSecret(ctx, namespace, name, secretName); err != nil {
+			return err
+		}
+	}
+
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, k8stypes.NamespacedName{Name: secretName, Namespace: namespace}, secret)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	// only delete Secrets created for this RegistryCredentials object
+	if secret.Labels[ecrCredentialsLabel] != rc.Name || !metav1.IsControlledBy(secret, rc) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, secret))
+}
+
+func (r *RegistryCredentialsReconciler) addImagePullSecret(ctx context.Context, namespace string, name string, secretName string) error {
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: namespace}, serviceAccount); err != nil {
+		// the ServiceAccount might not exist in every target namespace
+		return client.IgnoreNotFound(err)
+	}
+
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		if ref.Name == secretName {
+			return nil
+		}
+	}
+
+	patch := client.MergeFrom(serviceAccount.DeepCopy())
+	serviceAccount.ImagePullSecrets = append(serviceAccount.ImagePullSecrets, corev1.LocalObjectReference{Name: secretName})
+	return r.Patch(ctx, serviceAccount, patch)
+}
+
+func (r *RegistryCredentialsReconciler) removeImagePullSecret(ctx context.Context, namespace string, name string, secretName string) error {
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := r.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: namespace}, serviceAccount); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	refs := make([]corev1.LocalObjectReference, 0, len(serviceAccount.ImagePullSecrets))
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		if ref.Name != secretName {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(serviceAccount.ImagePullSecrets) {
+		return nil
+	}
+
+	patch := client.MergeFrom(serviceAccount.DeepCopy())
+	serviceAccount.ImagePullSecrets = refs
+	return r.Patch(ctx, serviceAccount, patch)
+}
+
+func (r *RegistryCredentialsReconciler) finalizeRegistryCredentials(ctx context.Context, logger logr.Logger, rc *ecrv1beta1.RegistryCredentials) error {
+	secretName, serviceAccountNames := appliedRegistryCredentials(rc)
+	for _, namespace := range rc.Status.Namespaces {
+		if err := r.removeSecret(ctx, rc, namespace, secretName, serviceAccountNames); err != nil {
+			logger.Error(err, "Failed to remove registry credentials Secret.", "namespace", namespace)
+			return err
+		}
+	}
+
+	logger.Info("Successfully finalized RegistryCredentials.")
+	return nil
+}
+
+func stringSetEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, value := range a {
+		if !containsString(b, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// find all RegistryCredentials with a namespace selector matching the given Namespace
+func (r *RegistryCredentialsReconciler) findObjectsForNamespace(obj client.Object) []reconcile.Request {
+	list := &ecrv1beta1.RegistryCredentialsList{}
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for _, item := range list.Items {
+		if item.Spec.NamespaceSelector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(item.Spec.NamespaceSelector)
+		// the namespace might have started or stopped to match the selector
+		if err == nil && (selector.Matches(labels.Set(obj.GetLabels())) || containsString(item.Status.Namespaces, obj.GetName())) {
+			requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name}})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RegistryCredentialsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.RegistryCredentials{}).
+		Owns(&corev1.Secret{}).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForNamespace)).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,284 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const ecrRegistryPolicyFinalizer = "registrypolicy.ecr.aws.cloud.qaware.de/finalizer"
+
+// RegistryPolicyReconciler reconciles a RegistryPolicy object
+type RegistryPolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registrypolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registrypolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registrypolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core,resources=configmaps;secrets,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *RegistryPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("registryPolicy", req.NamespacedName)
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	// lookup the RegistryPolicy instance for this reconcile request
+	registryPolicy := &ecrv1beta1.RegistryPolicy{}
+	geterr := r.Get(ctx, req.NamespacedName, registryPolicy)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("RegistryPolicy already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get RegistryPolicy.")
+		return ctrl.Result{}, geterr
+	}
+
+	// the registry policy is a registry singleton, objects not named default are never applied
+	// and must not delete the registry policy when deleted
+	if !isRegistrySingleton(registryPolicy) {
+		return r.ignoreRegistryPolicy(ctx, logger, registryPolicy)
+	}
+
+	// Check if the RegistryPolicy instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isRegistryPolicyMarkedToBeDeleted := registryPolicy.GetDeletionTimestamp() != nil
+	if isRegistryPolicyMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(registryPolicy, ecrRegistryPolicyFinalizer) {
+			// Run finalization logic for ecrRegistryPolicyFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeRegistryPolicy(logger, client); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrRegistryPolicyFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(registryPolicy, ecrRegistryPolicyFinalizer)
+			err := r.Update(ctx, registryPolicy)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(registryPolicy, ecrRegistryPolicyFinalizer) {
+		logger.Info("Update Finalizer for RegistryPolicy.")
+		controllerutil.AddFinalizer(registryPolicy, ecrRegistryPolicyFinalizer)
+		upderr := r.Update(ctx, registryPolicy)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update RegistryPolicy with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	status := registryPolicy.Status.DeepCopy()
+
+	// the registry ID is required to render the policy template
+	describeout, describeerr := client.DescribeRegistry(context.TODO(), &ecr.DescribeRegistryInput{})
+	if describeerr != nil {
+		logger.Error(describeerr, "Could not describe registry.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, describeerr
+	}
+	registryPolicy.Status.RegistryId = aws.ToString(describeout.RegistryId)
+
+	data := registryPolicyTemplateData{
+		RegistryId: registryPolicy.Status.RegistryId,
+		Region:     client.Options().Region,
+	}
+	policyText, texterr := renderPolicyText(ctx, r.Client, registryPolicy.Spec.PolicyTextFromNamespace, registryPolicy.Spec.PolicyText, registryPolicy.Spec.PolicyTextFrom, data)
+	if texterr != nil {
+		// the referenced ConfigMap or Secret is watched, so we will be called again on changes
+		logger.Error(texterr, "Could not resolve registry policy text.")
+		registryPolicy.Status.Message = texterr.Error()
+		return r.updateStatus(ctx, registryPolicy, status)
+	}
+
+	// compare the actual registry policy to detect drift
+	actualText := ""
+	getout, geterr := client.GetRegistryPolicy(context.TODO(), &ecr.GetRegistryPolicyInput{})
+	if geterr != nil {
+		var notFound *types.RegistryPolicyNotFoundException
+		if !errors.As(geterr, &notFound) {
+			logger.Error(geterr, "Could not get registry policy.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, geterr
+		}
+	} else {
+		actualText = aws.ToString(getout.PolicyText)
+	}
+
+	if !policyTextEqual(policyText, actualText) {
+		if registryPolicy.Status.LastAppliedTime != nil && registryPolicy.Status.ObservedGeneration == registryPolicy.Generation {
+			logger.Info("Registry policy has drifted. Reapplying.", "policyText", actualText)
+			registryPolicy.Status.LastDriftTime = &metav1.Time{Time: time.Now()}
+		}
+
+		_, puterr := client.PutRegistryPolicy(context.TODO(), &ecr.PutRegistryPolicyInput{
+			PolicyText: aws.String(policyText),
+		})
+		if puterr != nil {
+			logger.Error(puterr, "Could not put registry policy.")
+			registryPolicy.Status.Message = puterr.Error()
+			if _, err := r.updateStatus(ctx, registryPolicy, status); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, puterr
+		}
+
+		logger.Info("Applied registry policy.", "registryId", registryPolicy.Status.RegistryId)
+		registryPolicy.Status.LastAppliedTime = &metav1.Time{Time: time.Now()}
+	}
+	registryPolicy.Status.Message = ""
+	registryPolicy.Status.ObservedGeneration = registryPolicy.Generation
+
+	return r.updateStatus(ctx, registryPolicy, status)
+}
+
+// Updates the RegistryPolicy status if changed and requeues for the next drift detection
+func (r *RegistryPolicyReconciler) updateStatus(ctx context.Context, registryPolicy *ecrv1beta1.RegistryPolicy, status *ecrv1beta1.RegistryPolicyStatus) (ctrl.Result, error) {
+	if !equality.Semantic.DeepEqual(status, &registryPolicy.Status) {
+		if err := r.Status().Update(ctx, registryPolicy); err != nil {
+			ctrllog.FromContext(ctx).Error(err, "Failed to update RegistryPolicy status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: driftDetectionInterval}, nil
+}
+
+func (r *RegistryPolicyReconciler) ignoreRegistryPolicy(ctx context.Context, logger logr.Logger, registryPolicy *ecrv1beta1.RegistryPolicy) (ctrl.Result, error) {
+	logger.Info("Ignoring RegistryPolicy not named " + registrySingletonName + ".")
+	if controllerutil.ContainsFinalizer(registryPolicy, ecrRegistryPolicyFinalizer) {
+		controllerutil.RemoveFinalizer(registryPolicy, ecrRegistryPolicyFinalizer)
+		if err := r.Update(ctx, registryPolicy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	message := fmt.Sprintf("ignored, the registry policy must be named %s", registrySingletonName)
+	if registryPolicy.GetDeletionTimestamp() == nil && registryPolicy.Status.Message != message {
+		registryPolicy.Status.Message = message
+		if err := r.Status().Update(ctx, registryPolicy); err != nil {
+			logger.Error(err, "Failed to update RegistryPolicy status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *RegistryPolicyReconciler) finalizeRegistryPolicy(logger logr.Logger, client *ecr.Client) error {
+	_, delerr := client.DeleteRegistryPolicy(context.TODO(), &ecr.DeleteRegistryPolicyInput{})
+	if delerr != nil {
+		var notFound *types.RegistryPolicyNotFoundException
+		if !errors.As(delerr, &notFound) {
+			logger.Error(delerr, "Failed to delete registry policy.")
+			return delerr
+		}
+	}
+
+	logger.Info("Successfully finalized and deleted RegistryPolicy.")
+	return nil
+}
+
+// find all RegistryPolicies referencing the changed ConfigMap or Secret by name and namespace
+func (r *RegistryPolicyReconciler) findObjectsForIndex(indexKey string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &ecrv1beta1.RegistryPolicyList{}
+		err := r.List(context.TODO(), list, client.MatchingFields{indexKey: obj.GetName()})
+		if err != nil {
+			return []reconcile.Request{}
+		}
+
+		requests := make([]reconcile.Request, 0)
+		for _, item := range list.Items {
+			if item.Spec.PolicyTextFromNamespace == obj.GetNamespace() {
+				requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name}})
+			}
+		}
+		return requests
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RegistryPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.RegistryPolicy{}, policyConfigMapIndexKey, func(o client.Object) []string {
+		return policyConfigMapName(o.(*ecrv1beta1.RegistryPolicy).Spec.PolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.RegistryPolicy{}, policySecretIndexKey, func(o client.Object) []string {
+		return policySecretName(o.(*ecrv1beta1.RegistryPolicy).Spec.PolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.RegistryPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(policyConfigMapIndexKey))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(policySecretIndexKey))).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,343 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	ecrScanningFinalizer = "scanning.ecr.aws.cloud.qaware.de/finalizer"
+	// the interval to check the registry scanning configuration for drift
+	driftDetectionInterval = time.Duration(10) * time.Minute
+)
+
+// RegistryScanningConfigurationReconciler reconciles a RegistryScanningConfiguration object
+type RegistryScanningConfigurationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registryscanningconfigurations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registryscanningconfigurations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=registryscanningconfigurations/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *RegistryScanningConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("registryScanningConfiguration", req.NamespacedName)
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	// lookup the RegistryScanningConfiguration instance for this reconcile request
+	scanningConfiguration := &ecrv1beta1.RegistryScanningConfiguration{}
+	geterr := r.Get(ctx, req.NamespacedName, scanningConfiguration)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("RegistryScanningConfiguration already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get RegistryScanningConfiguration.")
+		return ctrl.Result{}, geterr
+	}
+
+	// the scanning configuration is a registry singleton, objects not named default are never applied
+	// and must not reset the registry when deleted
+	if !isRegistrySingleton(scanningConfiguration) {
+		return r.ignoreRegistryScanningConfiguration(ctx, logger, scanningConfiguration)
+	}
+
+	// Check if the RegistryScanningConfiguration instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isScanningConfigurationMarkedToBeDeleted := scanningConfiguration.GetDeletionTimestamp() != nil
+	if isScanningConfigurationMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(scanningConfiguration, ecrScanningFinalizer) {
+			// Run finalization logic for ecrScanningFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeRegistryScanningConfiguration(logger, client); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrScanningFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(scanningConfiguration, ecrScanningFinalizer)
+			err := r.Update(ctx, scanningConfiguration)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(scanningConfiguration, ecrScanningFinalizer) {
+		logger.Info("Update Finalizer for RegistryScanningConfiguration.")
+		controllerutil.AddFinalizer(scanningConfiguration, ecrScanningFinalizer)
+		upderr := r.Update(ctx, scanningConfiguration)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update RegistryScanningConfiguration with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	status := scanningConfiguration.Status.DeepCopy()
+	desired := createRegistryScanningConfiguration(scanningConfiguration.Spec)
+
+	// compare the actual registry scanning configuration to detect drift
+	getout, geterr := client.GetRegistryScanningConfiguration(context.TODO(), &ecr.GetRegistryScanningConfigurationInput{})
+	if geterr != nil {
+		logger.Error(geterr, "Could not get registry scanning configuration.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, geterr
+	}
+
+	if !registryScanningConfigurationEqual(desired, getout.ScanningConfiguration) {
+		if scanningConfiguration.Status.LastAppliedTime != nil && scanningConfiguration.Status.ObservedGeneration == scanningConfiguration.Generation {
+			logger.Info("Registry scanning configuration has drifted. Reapplying.", "scanningConfiguration", getout.ScanningConfiguration)
+			scanningConfiguration.Status.LastDriftTime = &metav1.Time{Time: time.Now()}
+		}
+
+		_, puterr := client.PutRegistryScanningConfiguration(context.TODO(), &ecr.PutRegistryScanningConfigurationInput{
+			ScanType: desired.ScanType,
+			Rules:    desired.Rules,
+		})
+		if puterr != nil {
+			logger.Error(puterr, "Could not put registry scanning configuration.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, puterr
+		}
+
+		logger.Info("Applied registry scanning configuration.", "scanType", desired.ScanType)
+		scanningConfiguration.Status.LastAppliedTime = &metav1.Time{Time: time.Now()}
+	}
+	scanningConfiguration.Status.RegistryId = aws.ToString(getout.RegistryId)
+	scanningConfiguration.Status.ObservedGeneration = scanningConfiguration.Generation
+
+	overridden, err := r.findOverriddenRepositories(ctx, scanningConfiguration.Spec)
+	if err != nil {
+		logger.Error(err, "Failed to list Repositories.")
+		return ctrl.Result{}, err
+	}
+	scanningConfiguration.Status.OverriddenRepositories = overridden
+
+	if !equality.Semantic.DeepEqual(status, &scanningConfiguration.Status) {
+		if err := r.Status().Update(ctx, scanningConfiguration); err != nil {
+			logger.Error(err, "Failed to update RegistryScanningConfiguration status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: driftDetectionInterval}, nil
+}
+
+func (r *RegistryScanningConfigurationReconciler) ignoreRegistryScanningConfiguration(ctx context.Context, logger logr.Logger, scanningConfiguration *ecrv1beta1.RegistryScanningConfiguration) (ctrl.Result, error) {
+	logger.Info("Ignoring RegistryScanningConfiguration not named " + registrySingletonName + ".")
+	if controllerutil.ContainsFinalizer(scanningConfiguration, ecrScanningFinalizer) {
+		controllerutil.RemoveFinalizer(scanningConfiguration, ecrScanningFinalizer)
+		if err := r.Update(ctx, scanningConfiguration); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	message := fmt.Sprintf("ignored, the registry scanning configuration must be named %s", registrySingletonName)
+	if scanningConfiguration.GetDeletionTimestamp() == nil && scanningConfiguration.Status.Message != message {
+		scanningConfiguration.Status.Message = message
+		if err := r.Status().Update(ctx, scanningConfiguration); err != nil {
+			logger.Error(err, "Failed to update RegistryScanningConfiguration status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *RegistryScanningConfigurationReconciler) finalizeRegistryScanningConfiguration(logger logr.Logger, client *ecr.Client) error {
+	// reset to basic scanning without rules, so the repository settings apply again
+	_, puterr := client.PutRegistryScanningConfiguration(context.TODO(), &ecr.PutRegistryScanningConfigurationInput{
+		ScanType: types.ScanTypeBasic,
+		Rules:    []types.RegistryScanningRule{},
+	})
+	if puterr != nil {
+		logger.Error(puterr, "Failed to reset registry scanning configuration.")
+		return puterr
+	}
+
+	logger.Info("Successfully finalized and reset RegistryScanningConfiguration.")
+	return nil
+}
+
+// Returns the namespaced names of all Repository objects whose scanning configuration is overridden
+func (r *RegistryScanningConfigurationReconciler) findOverriddenRepositories(ctx context.Context, spec ecrv1beta1.RegistryScanningConfigurationSpec) ([]string, error) {
+	list := &ecrv1beta1.RepositoryList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	overridden := make([]string, 0)
+	for i := range list.Items {
+		if scanningOverride(spec, &list.Items[i]) != "" {
+			overridden = append(overridden, list.Items[i].Namespace+"/"+list.Items[i].Name)
+		}
+	}
+	sort.Strings(overridden)
+	return overridden, nil
+}
+
+func createRegistryScanningConfiguration(spec ecrv1beta1.RegistryScanningConfigurationSpec) *types.RegistryScanningConfiguration {
+	scanType := types.ScanType(spec.ScanType)
+	if scanType == "" {
+		scanType = types.ScanTypeBasic
+	}
+
+	rules := make([]types.RegistryScanningRule, 0, len(spec.Rules))
+	for _, rule := range spec.Rules {
+		filters := make([]types.ScanningRepositoryFilter, 0, len(rule.RepositoryFilters))
+		for _, filter := range rule.RepositoryFilters {
+			filters = append(filters, types.ScanningRepositoryFilter{
+				Filter:     aws.String(filter),
+				FilterType: types.ScanningRepositoryFilterTypeWildcard,
+			})
+		}
+		rules = append(rules, types.RegistryScanningRule{
+			ScanFrequency:     types.ScanFrequency(rule.ScanFrequency),
+			RepositoryFilters: filters,
+		})
+	}
+	return &types.RegistryScanningConfiguration{ScanType: scanType, Rules: rules}
+}
+
+func registryScanningConfigurationEqual(desired *types.RegistryScanningConfiguration, actual *types.RegistryScanningConfiguration) bool {
+	if actual == nil {
+		return false
+	}
+	if desired.ScanType != actual.ScanType || len(desired.Rules) != len(actual.Rules) {
+		return false
+	}
+	for i := range desired.Rules {
+		if desired.Rules[i].ScanFrequency != actual.Rules[i].ScanFrequency || len(desired.Rules[i].RepositoryFilters) != len(actual.Rules[i].RepositoryFilters) {
+			return false
+		}
+		for j := range desired.Rules[i].RepositoryFilters {
+			if aws.ToString(desired.Rules[i].RepositoryFilters[j].Filter) != aws.ToString(actual.Rules[i].RepositoryFilters[j].Filter) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns a message if the imageScanningConfiguration of the Repository is overridden by the registry rules, or an empty string
+func scanningOverride(spec ecrv1beta1.RegistryScanningConfigurationSpec, repository *ecrv1beta1.Repository) string {
+	if repository.Spec.ImageScanningConfiguration == nil {
+		return ""
+	}
+	// without rules the repository setting applies to basic scanning
+	if len(spec.Rules) == 0 && spec.ScanType != string(types.ScanTypeEnhanced) {
+		return ""
+	}
+
+	frequency := string(types.ScanFrequencyManual)
+	for _, rule := range spec.Rules {
+		for _, filter := range rule.RepositoryFilters {
+			if scanFilterMatches(filter, ecrRepositoryName(repository)) && scanFrequencyRank(rule.ScanFrequency) > scanFrequencyRank(frequency) {
+				frequency = rule.ScanFrequency
+			}
+		}
+	}
+
+	scanOnPush := repository.Spec.ImageScanningConfiguration.ScanOnPush
+	if scanOnPush != (frequency != string(types.ScanFrequencyManual)) {
+		return fmt.Sprintf("imageScanningConfiguration.scanOnPush=%t is overridden by the registry scanning configuration with scan frequency %s", scanOnPush, frequency)
+	}
+	return ""
+}
+
+// Checks whether the ECR wildcard filter matches the repository name
+func scanFilterMatches(filter string, name string) bool {
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(filter), `\*`, ".*") + "$"
+	matched, err := regexp.MatchString(expr, name)
+	return err == nil && matched
+}
+
+func scanFrequencyRank(frequency string) int {
+	switch types.ScanFrequency(frequency) {
+	case types.ScanFrequencyContinuousScan:
+		return 2
+	case types.ScanFrequencyScanOnPush:
+		return 1
+	}
+	return 0
+}
+
+// find the RegistryScanningConfiguration singleton, since the created, deleted or changed Repository might be overridden by it
+func (r *RegistryScanningConfigurationReconciler) findAllObjects(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Name: registrySingletonName}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RegistryScanningConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.RegistryScanningConfiguration{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// status updates of the Repository do not change its scanning configuration
+		Watches(&source.Kind{Type: &ecrv1beta1.Repository{}}, handler.EnqueueRequestsFromMapFunc(r.findAllObjects),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
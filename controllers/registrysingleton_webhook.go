@@ -0,0 +1,55 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// registry-wide settings exist once per registry, so only the object with this name is applied
+const registrySingletonName = "default"
+
+//+kubebuilder:webhook:path=/validate-ecr-aws-cloud-qaware-de-v1beta1-registry-singleton,mutating=false,failurePolicy=fail,sideEffects=None,groups=ecr.aws.cloud.qaware.de,resources=registrypolicies;registryscanningconfigurations;replicationconfigurations,verbs=create,versions=v1beta1,name=vregistrysingleton.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// RegistrySingletonValidator validates the names of the registry-wide singleton objects
+type RegistrySingletonValidator struct{}
+
+// Handle denies registry-wide singleton objects not named default, since a second object
+// would overwrite the settings of the first and reset them when deleted.
+func (v *RegistrySingletonValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Name != registrySingletonName {
+		return admission.Denied(fmt.Sprintf("%s is a registry singleton and must be named %s", req.Kind.Kind, registrySingletonName))
+	}
+	return admission.Allowed("")
+}
+
+// Checks whether the object is the registry-wide singleton, all other objects are ignored
+func isRegistrySingleton(obj metav1.Object) bool {
+	return obj.GetName() == registrySingletonName
+}
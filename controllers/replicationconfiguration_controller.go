@@ -0,0 +1,362 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const ecrReplicationFinalizer = "replication.ecr.aws.cloud.qaware.de/finalizer"
+
+// ReplicationConfigurationReconciler reconciles a ReplicationConfiguration object
+type ReplicationConfigurationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=replicationconfigurations,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=replicationconfigurations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=replicationconfigurations/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *ReplicationConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("replicationConfiguration", req.NamespacedName)
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	// lookup the ReplicationConfiguration instance for this reconcile request
+	replicationConfiguration := &ecrv1beta1.ReplicationConfiguration{}
+	geterr := r.Get(ctx, req.NamespacedName, replicationConfiguration)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ReplicationConfiguration already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get ReplicationConfiguration.")
+		return ctrl.Result{}, geterr
+	}
+
+	// the replication configuration is a registry singleton, objects not named default are never applied
+	// and must not remove the replication rules when deleted
+	if !isRegistrySingleton(replicationConfiguration) {
+		return r.ignoreReplicationConfiguration(ctx, logger, replicationConfiguration)
+	}
+
+	// Check if the ReplicationConfiguration instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isReplicationConfigurationMarkedToBeDeleted := replicationConfiguration.GetDeletionTimestamp() != nil
+	if isReplicationConfigurationMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(replicationConfiguration, ecrReplicationFinalizer) {
+			// Run finalization logic for ecrReplicationFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeReplicationConfiguration(logger, client); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrReplicationFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(replicationConfiguration, ecrReplicationFinalizer)
+			err := r.Update(ctx, replicationConfiguration)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(replicationConfiguration, ecrReplicationFinalizer) {
+		logger.Info("Update Finalizer for ReplicationConfiguration.")
+		controllerutil.AddFinalizer(replicationConfiguration, ecrReplicationFinalizer)
+		upderr := r.Update(ctx, replicationConfiguration)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update ReplicationConfiguration with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	status := replicationConfiguration.Status.DeepCopy()
+	desired := createReplicationConfiguration(replicationConfiguration.Spec)
+
+	// compare the actual registry replication configuration to detect drift
+	descout, descerr := client.DescribeRegistry(context.TODO(), &ecr.DescribeRegistryInput{})
+	if descerr != nil {
+		logger.Error(descerr, "Could not describe registry.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, descerr
+	}
+
+	if !replicationConfigurationEqual(desired, descout.ReplicationConfiguration) {
+		if replicationConfiguration.Status.LastAppliedTime != nil && replicationConfiguration.Status.ObservedGeneration == replicationConfiguration.Generation {
+			logger.Info("Registry replication configuration has drifted. Reapplying.", "replicationConfiguration", descout.ReplicationConfiguration)
+			replicationConfiguration.Status.LastDriftTime = &metav1.Time{Time: time.Now()}
+		}
+
+		_, puterr := client.PutReplicationConfiguration(context.TODO(), &ecr.PutReplicationConfigurationInput{
+			ReplicationConfiguration: desired,
+		})
+		if puterr != nil {
+			logger.Error(puterr, "Could not put replication configuration.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, puterr
+		}
+
+		logger.Info("Applied registry replication configuration.", "rules", len(desired.Rules))
+		replicationConfiguration.Status.LastAppliedTime = &metav1.Time{Time: time.Now()}
+	}
+	replicationConfiguration.Status.RegistryId = aws.ToString(descout.RegistryId)
+	replicationConfiguration.Status.ObservedGeneration = replicationConfiguration.Generation
+
+	replicated, err := r.findReplicatedRepositories(ctx, replicationConfiguration.Spec)
+	if err != nil {
+		logger.Error(err, "Failed to list Repositories.")
+		return ctrl.Result{}, err
+	}
+	replicationConfiguration.Status.ReplicatedRepositories = replicated
+
+	if !equality.Semantic.DeepEqual(status, &replicationConfiguration.Status) {
+		if err := r.Status().Update(ctx, replicationConfiguration); err != nil {
+			logger.Error(err, "Failed to update ReplicationConfiguration status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: driftDetectionInterval}, nil
+}
+
+func (r *ReplicationConfigurationReconciler) ignoreReplicationConfiguration(ctx context.Context, logger logr.Logger, replicationConfiguration *ecrv1beta1.ReplicationConfiguration) (ctrl.Result, error) {
+	logger.Info("Ignoring ReplicationConfiguration not named " + registrySingletonName + ".")
+	if controllerutil.ContainsFinalizer(replicationConfiguration, ecrReplicationFinalizer) {
+		controllerutil.RemoveFinalizer(replicationConfiguration, ecrReplicationFinalizer)
+		if err := r.Update(ctx, replicationConfiguration); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	message := fmt.Sprintf("ignored, the replication configuration must be named %s", registrySingletonName)
+	if replicationConfiguration.GetDeletionTimestamp() == nil && replicationConfiguration.Status.Message != message {
+		replicationConfiguration.Status.Message = message
+		if err := r.Status().Update(ctx, replicationConfiguration); err != nil {
+			logger.Error(err, "Failed to update ReplicationConfiguration status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *ReplicationConfigurationReconciler) finalizeReplicationConfiguration(logger logr.Logger, client *ecr.Client) error {
+	// remove all replication rules of the registry
+	_, puterr := client.PutReplicationConfiguration(context.TODO(), &ecr.PutReplicationConfigurationInput{
+		ReplicationConfiguration: &types.ReplicationConfiguration{Rules: []types.ReplicationRule{}},
+	})
+	if puterr != nil {
+		logger.Error(puterr, "Failed to remove registry replication configuration.")
+		return puterr
+	}
+
+	logger.Info("Successfully finalized and removed ReplicationConfiguration.")
+	return nil
+}
+
+// Returns the namespaced names of all Repository objects matching any of the replication rules
+func (r *ReplicationConfigurationReconciler) findReplicatedRepositories(ctx context.Context, spec ecrv1beta1.ReplicationConfigurationSpec) ([]string, error) {
+	list := &ecrv1beta1.RepositoryList{}
+	if err := r.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	replicated := make([]string, 0)
+	for _, repository := range list.Items {
+		if isRepositoryReplicated(spec, ecrRepositoryName(&repository)) {
+			replicated = append(replicated, repository.Namespace+"/"+repository.Name)
+		}
+	}
+	sort.Strings(replicated)
+	return replicated, nil
+}
+
+func createReplicationConfiguration(spec ecrv1beta1.ReplicationConfigurationSpec) *types.ReplicationConfiguration {
+	rules := make([]types.ReplicationRule, 0, len(spec.Rules))
+	for _, rule := range spec.Rules {
+		destinations := make([]types.ReplicationDestination, 0, len(rule.Destinations))
+		for _, destination := range rule.Destinations {
+			destinations = append(destinations, types.ReplicationDestination{
+				Region:     aws.String(destination.Region),
+				RegistryId: aws.String(destination.RegistryId),
+			})
+		}
+
+		var filters []types.RepositoryFilter
+		for _, filter := range rule.RepositoryFilters {
+			filters = append(filters, types.RepositoryFilter{
+				Filter:     aws.String(filter),
+				FilterType: types.RepositoryFilterTypePrefixMatch,
+			})
+		}
+
+		rules = append(rules, types.ReplicationRule{Destinations: destinations, RepositoryFilters: filters})
+	}
+	return &types.ReplicationConfiguration{Rules: rules}
+}
+
+func replicationConfigurationEqual(desired *types.ReplicationConfiguration, actual *types.ReplicationConfiguration) bool {
+	if actual == nil {
+		return len(desired.Rules) == 0
+	}
+	if len(desired.Rules) != len(actual.Rules) {
+		return false
+	}
+	for i := range desired.Rules {
+		d, a := desired.Rules[i], actual.Rules[i]
+		if len(d.Destinations) != len(a.Destinations) || len(d.RepositoryFilters) != len(a.RepositoryFilters) {
+			return false
+		}
+		for j := range d.Destinations {
+			if aws.ToString(d.Destinations[j].Region) != aws.ToString(a.Destinations[j].Region) ||
+				aws.ToString(d.Destinations[j].RegistryId) != aws.ToString(a.Destinations[j].RegistryId) {
+				return false
+			}
+		}
+		for j := range d.RepositoryFilters {
+			if aws.ToString(d.RepositoryFilters[j].Filter) != aws.ToString(a.RepositoryFilters[j].Filter) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Checks whether any replication rule matches the repository name
+func isRepositoryReplicated(spec ecrv1beta1.ReplicationConfigurationSpec, name string) bool {
+	for _, rule := range spec.Rules {
+		if len(rule.RepositoryFilters) == 0 {
+			return true
+		}
+		for _, prefix := range rule.RepositoryFilters {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Describes the replication status of the latest tags, if the repository matches any ReplicationConfiguration
+func updateReplicationStatuses(ctx context.Context, c client.Client, ecrClient *ecr.Client, repository *ecrv1beta1.Repository) error {
+	list := &ecrv1beta1.ReplicationConfigurationList{}
+	if err := c.List(ctx, list); err != nil {
+		return err
+	}
+
+	replicated := false
+	for _, replicationConfiguration := range list.Items {
+		if isRegistrySingleton(&replicationConfiguration) && isRepositoryReplicated(replicationConfiguration.Spec, ecrRepositoryName(repository)) {
+			replicated = true
+		}
+	}
+	if !replicated {
+		return nil
+	}
+
+	statuses := map[string][]ecrv1beta1.ImageReplicationStatus{}
+	for i := range repository.Status.LatestTags {
+		tag := &repository.Status.LatestTags[i]
+		if _, ok := statuses[tag.Digest]; !ok {
+			output, err := ecrClient.DescribeImageReplicationStatus(context.TODO(), &ecr.DescribeImageReplicationStatusInput{
+				RepositoryName: aws.String(ecrRepositoryName(repository)),
+				ImageId:        &types.ImageIdentifier{ImageDigest: aws.String(tag.Digest)},
+			})
+			if err != nil {
+				return err
+			}
+
+			replicationStatuses := make([]ecrv1beta1.ImageReplicationStatus, 0, len(output.ReplicationStatuses))
+			for _, s := range output.ReplicationStatuses {
+				replicationStatuses = append(replicationStatuses, ecrv1beta1.ImageReplicationStatus{
+					Region:      aws.ToString(s.Region),
+					RegistryId:  aws.ToString(s.RegistryId),
+					Status:      string(s.Status),
+					FailureCode: aws.ToString(s.FailureCode),
+				})
+			}
+			statuses[tag.Digest] = replicationStatuses
+		}
+		tag.ReplicationStatuses = statuses[tag.Digest]
+	}
+	return nil
+}
+
+// find the ReplicationConfiguration singleton, since the created or deleted Repository might match it
+func (r *ReplicationConfigurationReconciler) findAllObjects(obj client.Object) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Name: registrySingletonName}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ReplicationConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.ReplicationConfiguration{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the replication rules only match repository names, so only created and deleted repositories matter
+		Watches(&source.Kind{Type: &ecrv1beta1.Repository{}}, handler.EnqueueRequestsFromMapFunc(r.findAllObjects),
+			builder.WithPredicates(predicate.Funcs{UpdateFunc: func(event.UpdateEvent) bool { return false }})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -27,15 +27,24 @@ package controllers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/go-logr/logr"
 	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -43,6 +52,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
 )
 
+const (
+	ecrRepositoryFinalizer = "repository.ecr.aws.cloud.qaware.de/finalizer"
+	// the tag of ECR repositories with the namespace/name of the Repository object managing them
+	ecrOwnerTag = "ecr.aws.cloud.qaware.de/owner"
+	// the type of the condition reporting whether the ECR repository is managed by the Repository object
+	conditionTypeOwned = "Owned"
+	// the interval to refresh the image inventory of the repository
+	inventoryRefreshInterval = time.Duration(5) * time.Minute
+	// the number of most recently pushed tags published in the status
+	latestTagsLimit = 10
+)
+
 // RepositoryReconciler reconciles a Repository object
 type RepositoryReconciler struct {
 	client.Client
@@ -52,6 +73,8 @@ type RepositoryReconciler struct {
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=imagescans,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=replicationconfigurations,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -71,31 +94,13 @@ func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, awserr
 	}
 
-	// TODO: add Finalizer logic to delete Repository
 	// lookup the Repository instance for this reconcile request
 	repository := &ecrv1beta1.Repository{}
 	k8serr := r.Get(ctx, req.NamespacedName, repository)
 	if k8serr != nil {
 		if k8serrors.IsNotFound(k8serr) {
-			// delete the associated AWS ECR repository
-			output, err := client.DeleteRepository(context.TODO(), &ecr.DeleteRepositoryInput{
-				// need to use Name from request
-				RepositoryName: aws.String(req.Name),
-				Force:          true,
-			})
-			if err != nil {
-				var rnfe *types.RepositoryNotFoundException
-				if errors.As(err, &rnfe) {
-					// check for already deleted, might occur due to timing and duplicate reconcile
-					logger.Info("Repository already deleted. Skipping.")
-					return ctrl.Result{}, nil
-				} else {
-					logger.Error(err, "Could not delete ECR repository.")
-					return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
-				}
-			}
-
-			logger.Info("Successfully deleted ECR repository.", "repositoryUri", output.Repository.RepositoryUri)
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("Repository already deleted. Skipping.")
 			return ctrl.Result{}, nil
 		}
 
@@ -103,16 +108,50 @@ func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, k8serr
 	}
 
+	// Check if the Repository instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isRepositoryMarkedToBeDeleted := repository.GetDeletionTimestamp() != nil
+	if isRepositoryMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(repository, ecrRepositoryFinalizer) {
+			// Run finalization logic for ecrRepositoryFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeRepository(logger, client, repository); err != nil {
+				return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+			}
+
+			// Remove ecrRepositoryFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(repository, ecrRepositoryFinalizer)
+			err := r.Update(ctx, repository)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(repository, ecrRepositoryFinalizer) {
+		logger.Info("Update Finalizer for Repository.")
+		controllerutil.AddFinalizer(repository, ecrRepositoryFinalizer)
+		upderr := r.Update(ctx, repository)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update Repository with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
 	// try to get the matching AWS ECR repository
-	_, repoerr := client.DescribeRepositories(context.TODO(), &ecr.DescribeRepositoriesInput{
-		RepositoryNames: []string{repository.Name},
+	described, repoerr := client.DescribeRepositories(context.TODO(), &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{ecrRepositoryName(repository)},
 	})
 	if repoerr != nil {
 		var rnfe *types.RepositoryNotFoundException
 		if errors.As(repoerr, &rnfe) {
 			// reconcile and create AWS ECR repository
 			input := &ecr.CreateRepositoryInput{
-				RepositoryName:             aws.String(repository.Name),
+				RepositoryName:             aws.String(ecrRepositoryName(repository)),
 				ImageTagMutability:         createImageTagMutability(*repository),
 				ImageScanningConfiguration: createImageScanningConfiguration(*repository),
 				EncryptionConfiguration:    createEncryptionConfiguration(*repository),
@@ -148,16 +187,58 @@ func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				return ctrl.Result{}, err
 			}
 
-			return ctrl.Result{}, nil
+			// the image inventory is published with the next periodic reconcile
+			return ctrl.Result{RequeueAfter: inventoryRefreshInterval}, nil
 		} else {
 			logger.Error(repoerr, "Could not retrieve list of ECR repository.")
 			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, repoerr
 		}
 	}
 
+	// existing repositories are only managed if tagged with this Repository object, e.g. when adopted from a
+	// pull through cache, so that a Repository object can not take over the ECR repository of another team
+	status := repository.Status.DeepCopy()
+	if len(described.Repositories) > 0 {
+		existing := described.Repositories[0]
+		owned, err := isOwnedRepository(client, repository, existing)
+		if err != nil {
+			logger.Error(err, "Could not list tags of ECR repository.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+		if !owned {
+			logger.Info("ECR repository not owned by Repository. Skipping.", "repositoryName", ecrRepositoryName(repository))
+			repository.Status.RepositoryArn = ""
+			repository.Status.RegistryId = ""
+			repository.Status.RepositoryUri = ""
+			meta.SetStatusCondition(&repository.Status.Conditions, metav1.Condition{
+				Type:    conditionTypeOwned,
+				Status:  metav1.ConditionFalse,
+				Reason:  "OwnedByOther",
+				Message: fmt.Sprintf("ECR repository %s exists and is not tagged %s=%s", ecrRepositoryName(repository), ecrOwnerTag, aws.ToString(ownerTag(repository).Value)),
+			})
+			if !equality.Semantic.DeepEqual(status, &repository.Status) {
+				if err := r.Status().Update(ctx, repository); err != nil {
+					logger.Error(err, "Failed to update Repository status")
+					return ctrl.Result{}, err
+				}
+			}
+			return ctrl.Result{RequeueAfter: inventoryRefreshInterval}, nil
+		}
+
+		repository.Status.RepositoryArn = aws.ToString(existing.RepositoryArn)
+		repository.Status.RegistryId = aws.ToString(existing.RegistryId)
+		repository.Status.RepositoryUri = aws.ToString(existing.RepositoryUri)
+		meta.SetStatusCondition(&repository.Status.Conditions, metav1.Condition{
+			Type:    conditionTypeOwned,
+			Status:  metav1.ConditionTrue,
+			Reason:  "Owned",
+			Message: "ECR repository is managed by the Repository",
+		})
+	}
+
 	// reconcile and update AWS ECR repository ImageTagMutability
 	mutout, muterr := client.PutImageTagMutability(context.TODO(), &ecr.PutImageTagMutabilityInput{
-		RepositoryName:     aws.String(repository.Name),
+		RepositoryName:     aws.String(ecrRepositoryName(repository)),
 		ImageTagMutability: createImageTagMutability(*repository),
 	})
 	if muterr != nil {
@@ -170,7 +251,7 @@ func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// reconcile and update AWS ECR repository ImageScanningConfiguration
 	scanout, scanerr := client.PutImageScanningConfiguration(context.TODO(), &ecr.PutImageScanningConfigurationInput{
-		RepositoryName:             aws.String(repository.Name),
+		RepositoryName:             aws.String(ecrRepositoryName(repository)),
 		ImageScanningConfiguration: createImageScanningConfiguration(*repository),
 	})
 	if scanerr != nil {
@@ -194,7 +275,174 @@ func (r *RepositoryReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// ATTENTION: update of AWS ECR repository EncryptionConfiguration not possible
 
-	return ctrl.Result{}, nil
+	// publish the image inventory of the AWS ECR repository in the status
+	images, inverr := updateImageInventory(client, repository)
+	if inverr != nil {
+		logger.Error(inverr, "Could not describe images of ECR repository.")
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, inverr
+	}
+
+	// point the tag aliases at their images, the inventory is refreshed if any tag was moved
+	if applyTagAliases(logger, client, repository, images) {
+		images, inverr = updateImageInventory(client, repository)
+		if inverr != nil {
+			logger.Error(inverr, "Could not describe images of ECR repository.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, inverr
+		}
+	}
+
+	// report the replication status of the latest tags of replicated repositories
+	replerr := updateReplicationStatuses(ctx, r.Client, client, repository)
+	if replerr != nil {
+		logger.Error(replerr, "Could not describe image replication status.")
+	}
+
+	// rescan the most recently pushed images if the rescan schedule is due
+	requeueAfter := inventoryRefreshInterval
+	if repository.Spec.RescanSchedule != "" {
+		next, err := scheduleImageRescans(ctx, r.Client, r.Scheme, repository, images)
+		if err != nil {
+			logger.Error(err, "Could not schedule image rescans.")
+		} else if next < requeueAfter {
+			requeueAfter = next
+		}
+	}
+	if !equality.Semantic.DeepEqual(status, &repository.Status) {
+		err := r.Status().Update(ctx, repository)
+		if err != nil {
+			logger.Error(err, "Failed to update Repository status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// Pages through all images of the repository and updates the inventory summary of the status.
+// Returns the images ordered by push time, most recent first.
+func updateImageInventory(client *ecr.Client, repository *ecrv1beta1.Repository) ([]types.ImageDetail, error) {
+	images, err := describeAllImages(client, ecrRepositoryName(repository))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &repository.Status
+	status.ImageCount = len(images)
+	status.TaggedImageCount = 0
+	status.UntaggedImageCount = 0
+	status.TotalSizeInBytes = 0
+	status.LastPushedAt = nil
+	status.LatestTags = make([]ecrv1beta1.ImageTag, 0)
+
+	for _, image := range images {
+		if len(image.ImageTags) > 0 {
+			status.TaggedImageCount++
+		} else {
+			status.UntaggedImageCount++
+		}
+		status.TotalSizeInBytes += aws.ToInt64(image.ImageSizeInBytes)
+
+		var pushedAt *metav1.Time
+		if image.ImagePushedAt != nil {
+			pushedAt = &metav1.Time{Time: *image.ImagePushedAt}
+			if status.LastPushedAt == nil {
+				status.LastPushedAt = pushedAt
+			}
+		}
+
+		for _, tag := range image.ImageTags {
+			if len(status.LatestTags) < latestTagsLimit {
+				status.LatestTags = append(status.LatestTags, ecrv1beta1.ImageTag{
+					Tag:      tag,
+					Digest:   aws.ToString(image.ImageDigest),
+					PushedAt: pushedAt,
+				})
+			}
+		}
+	}
+
+	return images, nil
+}
+
+// Pages through all images of the repository, ordered by push time, most recent first
+func describeAllImages(client *ecr.Client, repositoryName string) ([]types.ImageDetail, error) {
+	images := make([]types.ImageDetail, 0)
+	paginator := ecr.NewDescribeImagesPaginator(client, &ecr.DescribeImagesInput{
+		RepositoryName: aws.String(repositoryName),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, output.ImageDetails...)
+	}
+
+	// most recently pushed images first
+	sort.Slice(images, func(i, j int) bool {
+		return aws.ToTime(images[i].ImagePushedAt).After(aws.ToTime(images[j].ImagePushedAt))
+	})
+	return images, nil
+}
+
+func (r *RepositoryReconciler) finalizeRepository(logger logr.Logger, client *ecr.Client, repository *ecrv1beta1.Repository) error {
+	// repositories adopted from a pull through cache are owned by the cache, only the
+	// PullThroughCacheRule controller sets itself as controller of the Repository object
+	if owner := metav1.GetControllerOf(repository); owner != nil && owner.Kind == "PullThroughCacheRule" && owner.APIVersion == ecrv1beta1.GroupVersion.String() {
+		logger.Info("Repository adopted from pull through cache rule. Keeping ECR repository.", "pullThroughCacheRule", owner.Name)
+		return nil
+	}
+
+	// only delete ECR repositories managed by this Repository object
+	described, err := client.DescribeRepositories(context.TODO(), &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{ecrRepositoryName(repository)},
+	})
+	if err != nil {
+		var rnfe *types.RepositoryNotFoundException
+		if errors.As(err, &rnfe) {
+			logger.Info("ECR repository already deleted. Skipping.")
+			return nil
+		}
+		return err
+	}
+	if len(described.Repositories) == 0 {
+		return nil
+	}
+	owned, err := isOwnedRepository(client, repository, described.Repositories[0])
+	if err != nil {
+		return err
+	}
+	if !owned {
+		logger.Info("ECR repository not owned by Repository. Keeping ECR repository.", "repositoryName", ecrRepositoryName(repository))
+		return nil
+	}
+
+	output, err := client.DeleteRepository(context.TODO(), &ecr.DeleteRepositoryInput{
+		RepositoryName: aws.String(ecrRepositoryName(repository)),
+		Force:          true,
+	})
+	if err != nil {
+		var rnfe *types.RepositoryNotFoundException
+		if errors.As(err, &rnfe) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("ECR repository already deleted. Skipping.")
+			return nil
+		}
+
+		logger.Error(err, "Could not delete ECR repository.")
+		return err
+	}
+
+	logger.Info("Successfully finalized and deleted ECR repository.", "repositoryUri", output.Repository.RepositoryUri)
+	return nil
+}
+
+// Returns the name of the ECR repository, which defaults to the name of the Repository object
+func ecrRepositoryName(repository *ecrv1beta1.Repository) string {
+	if repository.Spec.RepositoryName != "" {
+		return repository.Spec.RepositoryName
+	}
+	return repository.Name
 }
 
 func createImageTagMutability(r ecrv1beta1.Repository) types.ImageTagMutability {
@@ -221,15 +469,40 @@ func createEncryptionConfiguration(r ecrv1beta1.Repository) *types.EncryptionCon
 func createTags(r ecrv1beta1.Repository) []types.Tag {
 	tags := make([]types.Tag, 0)
 	for k, v := range r.Labels {
+		if k == ecrOwnerTag {
+			continue
+		}
 		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
 	}
-	return tags
+	return append(tags, ownerTag(&r))
+}
+
+// Returns the tag naming the Repository object that manages the ECR repository
+func ownerTag(repository *ecrv1beta1.Repository) types.Tag {
+	return types.Tag{Key: aws.String(ecrOwnerTag), Value: aws.String(repository.Namespace + "/" + repository.Name)}
+}
+
+// Checks whether the existing ECR repository is tagged with the Repository object. Repositories created
+// before the owner tag was introduced are owned if the Repository object already publishes their ARN.
+func isOwnedRepository(client *ecr.Client, repository *ecrv1beta1.Repository, existing types.Repository) (bool, error) {
+	output, err := client.ListTagsForResource(context.TODO(), &ecr.ListTagsForResourceInput{
+		ResourceArn: existing.RepositoryArn,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, tag := range output.Tags {
+		if aws.ToString(tag.Key) == ecrOwnerTag {
+			return aws.ToString(tag.Value) == aws.ToString(ownerTag(repository).Value), nil
+		}
+	}
+	return repository.Status.RepositoryArn != "" && repository.Status.RepositoryArn == aws.ToString(existing.RepositoryArn), nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *RepositoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ecrv1beta1.Repository{}).
+		For(&ecrv1beta1.Repository{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
 		Complete(r)
 }
@@ -0,0 +1,186 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+// Returns the Repository objects targeted either by name or by label selector in the given namespace
+func findTargetRepositories(ctx context.Context, c client.Client, namespace string, name string, selector *metav1.LabelSelector) ([]ecrv1beta1.Repository, error) {
+	if selector != nil {
+		s, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+
+		list := &ecrv1beta1.RepositoryList{}
+		if err := c.List(ctx, list, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: s}); err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+
+	if name == "" {
+		return nil, errors.New("either repositoryName or repositorySelector is required")
+	}
+
+	repository := &ecrv1beta1.Repository{}
+	if err := c.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: namespace}, repository); err != nil {
+		return nil, err
+	}
+	return []ecrv1beta1.Repository{*repository}, nil
+}
+
+// Returns the names of previously targeted repositories that are no longer targeted
+func findStaleTargets(previous []ecrv1beta1.RepositoryTargetStatus, current []ecrv1beta1.Repository) []string {
+	names := make(map[string]bool, len(current))
+	for i := range current {
+		names[ecrRepositoryName(&current[i])] = true
+	}
+
+	stale := make([]string, 0)
+	for _, target := range previous {
+		if !names[target.RepositoryName] {
+			stale = append(stale, target.RepositoryName)
+		}
+	}
+	return stale
+}
+
+// Returns the names of all previously targeted repositories including the named repository
+func findAllTargets(previous []ecrv1beta1.RepositoryTargetStatus, name string) []string {
+	names := make([]string, 0, len(previous)+1)
+	if name != "" {
+		names = append(names, name)
+	}
+	for _, target := range previous {
+		if target.RepositoryName != name {
+			names = append(names, target.RepositoryName)
+		}
+	}
+	return names
+}
+
+// Returns all managed Repository objects of the cluster by their RepositoryUri
+func findManagedRepositories(ctx context.Context, c client.Client) (map[string]ecrv1beta1.Repository, error) {
+	list := &ecrv1beta1.RepositoryList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	repositories := make(map[string]ecrv1beta1.Repository, len(list.Items))
+	for _, repository := range list.Items {
+		if repository.Status.RepositoryUri != "" {
+			repositories[repository.Status.RepositoryUri] = repository
+		}
+	}
+	return repositories, nil
+}
+
+// The repositorySelectorRef identifies an object targeting repositories by label selector
+type repositorySelectorRef struct {
+	Name      string
+	Namespace string
+	Selector  *metav1.LabelSelector
+}
+
+// Returns an event handler enqueueing the objects whose repository selector matches the labels
+// of a Repository. On updates both the old and the new labels are matched, so objects are also
+// reconciled when a Repository stops matching their selector.
+func enqueueRepositorySelectors(listSelectors func(namespace string) ([]repositorySelectorRef, error)) handler.EventHandler {
+	enqueue := func(q workqueue.RateLimitingInterface, namespace string, labelSets ...map[string]string) {
+		refs, err := listSelectors(namespace)
+		if err != nil {
+			return
+		}
+		for _, ref := range refs {
+			selector, err := metav1.LabelSelectorAsSelector(ref.Selector)
+			if err != nil {
+				continue
+			}
+			for _, labelSet := range labelSets {
+				if selector.Matches(labels.Set(labelSet)) {
+					q.Add(reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}})
+					break
+				}
+			}
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Object.GetNamespace(), e.Object.GetLabels())
+		},
+		UpdateFunc: func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.ObjectNew.GetNamespace(), e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
+		},
+		DeleteFunc: func(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Object.GetNamespace(), e.Object.GetLabels())
+		},
+		GenericFunc: func(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+			enqueue(q, e.Object.GetNamespace(), e.Object.GetLabels())
+		},
+	}
+}
+
+// Makes the given Repository the only Repository owner of the object, or removes all Repository
+// owner references if the repository is nil. Returns whether the owner references changed.
+func setRepositoryOwnerReference(repository *ecrv1beta1.Repository, object metav1.Object, scheme *runtime.Scheme) (bool, error) {
+	previous := object.GetOwnerReferences()
+	references := make([]metav1.OwnerReference, 0, len(previous))
+	for _, reference := range previous {
+		if reference.APIVersion == ecrv1beta1.GroupVersion.String() && reference.Kind == "Repository" {
+			if repository == nil || reference.Name != repository.Name {
+				continue
+			}
+		}
+		references = append(references, reference)
+	}
+	object.SetOwnerReferences(references)
+
+	if repository != nil {
+		if err := controllerutil.SetOwnerReference(repository, object, scheme); err != nil {
+			object.SetOwnerReferences(previous)
+			return false, err
+		}
+	}
+	return !equality.Semantic.DeepEqual(previous, object.GetOwnerReferences()), nil
+}
@@ -0,0 +1,141 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+	"github.com/robfig/cron/v3"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+//+kubebuilder:webhook:path=/validate-ecr-aws-cloud-qaware-de-v1beta1-repository,mutating=false,failurePolicy=fail,sideEffects=None,groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=create;update,versions=v1beta1,name=vrepository.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// RepositoryValidator validates Repository objects against the RepositoryCompliancePolicies of their namespace
+type RepositoryValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle denies Repository objects changing their ECR repository name, with an invalid rescan schedule,
+// invalid tag aliases or violating any RepositoryCompliancePolicy selecting their namespace, and warns
+// if the scanning configuration is overridden by a RegistryScanningConfiguration.
+func (v *RepositoryValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	repository := &ecrv1beta1.Repository{}
+	if err := v.decoder.Decode(req, repository); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// allow updates that do not change the spec or labels, e.g. finalizers of existing repositories
+	if req.Operation == admissionv1.Update {
+		old := &ecrv1beta1.Repository{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if equality.Semantic.DeepEqual(old.Spec, repository.Spec) && equality.Semantic.DeepEqual(old.Labels, repository.Labels) {
+			return admission.Allowed("")
+		}
+		// renaming would orphan the ECR repository managed so far
+		if ecrRepositoryName(old) != ecrRepositoryName(repository) {
+			return admission.Denied(fmt.Sprintf("repositoryName is immutable, the ECR repository is %s", ecrRepositoryName(old)))
+		}
+	}
+
+	if repository.Spec.RescanSchedule != "" {
+		if _, err := cron.ParseStandard(repository.Spec.RescanSchedule); err != nil {
+			return admission.Denied(fmt.Sprintf("invalid rescanSchedule: %s", err))
+		}
+	}
+
+	if errs := validateTagAliases(repository.Spec.TagAliases); len(errs) > 0 {
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := v.Client.Get(ctx, k8stypes.NamespacedName{Name: req.Namespace}, namespace); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	compliancePolicies := &ecrv1beta1.RepositoryCompliancePolicyList{}
+	if err := v.Client.List(ctx, compliancePolicies); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	violations := make([]string, 0)
+	for i := range compliancePolicies.Items {
+		compliancePolicy := &compliancePolicies.Items[i]
+		matches, err := compliancePolicyMatchesNamespace(compliancePolicy, namespace)
+		if err != nil || !matches {
+			continue
+		}
+		for _, violation := range evaluateRepositoryCompliance(repository, compliancePolicy.Spec) {
+			violations = append(violations, fmt.Sprintf("%s: %s", compliancePolicy.Name, violation))
+		}
+	}
+
+	if len(violations) > 0 {
+		return admission.Denied(strings.Join(violations, "; "))
+	}
+
+	// warn if the scanning configuration of the repository has no effect
+	scanningConfigurations := &ecrv1beta1.RegistryScanningConfigurationList{}
+	if err := v.Client.List(ctx, scanningConfigurations); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	warnings := make([]string, 0)
+	if len(repository.Spec.TagAliases) > 0 && repository.Spec.ImageTagMutability == ecrv1beta1.ImageTagMutability(types.ImageTagMutabilityImmutable) {
+		warnings = append(warnings, "tagAliases only create missing tags in IMMUTABLE repositories, existing tags are not moved")
+	}
+	for _, scanningConfiguration := range scanningConfigurations.Items {
+		if !isRegistrySingleton(&scanningConfiguration) {
+			continue
+		}
+		if message := scanningOverride(scanningConfiguration.Spec, repository); message != "" {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", scanningConfiguration.Name, message))
+		}
+	}
+	if len(warnings) > 0 {
+		return admission.Allowed("").WithWarnings(warnings...)
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder into the RepositoryValidator.
+func (v *RepositoryValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,224 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+// RepositoryCompliancePolicyReconciler reconciles a RepositoryCompliancePolicy object
+type RepositoryCompliancePolicyReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorycompliancepolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorycompliancepolicies/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorycompliancepolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *RepositoryCompliancePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("repositoryCompliancePolicy", req.NamespacedName)
+
+	// lookup the RepositoryCompliancePolicy instance for this reconcile request
+	compliancePolicy := &ecrv1beta1.RepositoryCompliancePolicy{}
+	geterr := r.Get(ctx, req.NamespacedName, compliancePolicy)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("RepositoryCompliancePolicy already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get RepositoryCompliancePolicy.")
+		return ctrl.Result{}, geterr
+	}
+
+	// find all repositories in the namespaces selected by the policy
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces); err != nil {
+		logger.Error(err, "Unable to list Namespaces.")
+		return ctrl.Result{}, err
+	}
+
+	repositoryCount := 0
+	nonCompliant := make([]ecrv1beta1.NonCompliantRepository, 0)
+	for _, namespace := range namespaces.Items {
+		matches, err := compliancePolicyMatchesNamespace(compliancePolicy, &namespace)
+		if err != nil {
+			logger.Error(err, "Invalid namespace selector of RepositoryCompliancePolicy.")
+			return ctrl.Result{}, err
+		}
+		if !matches {
+			continue
+		}
+
+		repositories := &ecrv1beta1.RepositoryList{}
+		if err := r.List(ctx, repositories, client.InNamespace(namespace.Name)); err != nil {
+			logger.Error(err, "Unable to list Repositories.", "namespace", namespace.Name)
+			return ctrl.Result{}, err
+		}
+
+		for _, repository := range repositories.Items {
+			repositoryCount++
+			if violations := evaluateRepositoryCompliance(&repository, compliancePolicy.Spec); len(violations) > 0 {
+				nonCompliant = append(nonCompliant, ecrv1beta1.NonCompliantRepository{
+					Namespace:  repository.Namespace,
+					Name:       repository.Name,
+					Violations: violations,
+				})
+			}
+		}
+	}
+
+	logger.Info("Evaluated RepositoryCompliancePolicy.", "repositories", repositoryCount, "nonCompliant", len(nonCompliant))
+
+	// we need to update the status
+	now := metav1.Now()
+	compliancePolicy.Status.RepositoryCount = repositoryCount
+	compliancePolicy.Status.NonCompliantCount = len(nonCompliant)
+	compliancePolicy.Status.NonCompliantRepositories = nonCompliant
+	compliancePolicy.Status.LastEvaluationTime = &now
+	if err := r.Status().Update(ctx, compliancePolicy); err != nil {
+		logger.Error(err, "Failed to update RepositoryCompliancePolicy status")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: time.Duration(10) * time.Minute}, nil
+}
+
+func compliancePolicyMatchesNamespace(cp *ecrv1beta1.RepositoryCompliancePolicy, namespace *corev1.Namespace) (bool, error) {
+	if cp.Spec.NamespaceSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(cp.Spec.NamespaceSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(namespace.Labels)), nil
+}
+
+// Evaluates the Repository against the compliance policy and returns all violations
+func evaluateRepositoryCompliance(repository *ecrv1beta1.Repository, spec ecrv1beta1.RepositoryCompliancePolicySpec) []string {
+	violations := make([]string, 0)
+
+	// the default encryption type is AES256 if no configuration is specified
+	encryptionType := ecrv1beta1.EncryptionType("AES256")
+	var kmsKey *string
+	if c := repository.Spec.EncryptionConfiguration; c != nil {
+		encryptionType = c.EncryptionType
+		kmsKey = c.KmsKey
+	}
+	if len(spec.AllowedEncryptionTypes) > 0 && !containsEncryptionType(spec.AllowedEncryptionTypes, encryptionType) {
+		violations = append(violations, fmt.Sprintf("encryption type %s is not allowed", encryptionType))
+	}
+	if encryptionType == "KMS" && len(spec.AllowedKmsKeys) > 0 {
+		if kmsKey == nil || *kmsKey == "" {
+			violations = append(violations, "the AWS managed KMS key is not allowed")
+		} else if !containsString(spec.AllowedKmsKeys, *kmsKey) {
+			violations = append(violations, fmt.Sprintf("KMS key %s is not allowed", *kmsKey))
+		}
+	}
+
+	if spec.RequireScanOnPush {
+		if c := repository.Spec.ImageScanningConfiguration; c == nil || !c.ScanOnPush {
+			violations = append(violations, "scan on push is required")
+		}
+	}
+
+	if spec.RequiredImageTagMutability != "" && repository.Spec.ImageTagMutability != spec.RequiredImageTagMutability {
+		violations = append(violations, fmt.Sprintf("image tag mutability %s is required", spec.RequiredImageTagMutability))
+	}
+
+	for _, tag := range spec.RequiredTags {
+		if _, ok := repository.Labels[tag]; !ok {
+			violations = append(violations, fmt.Sprintf("tag %s is required", tag))
+		}
+	}
+
+	return violations
+}
+
+func containsEncryptionType(values []ecrv1beta1.EncryptionType, value ecrv1beta1.EncryptionType) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// find all RepositoryCompliancePolicies, since any of them might apply to the changed object
+func (r *RepositoryCompliancePolicyReconciler) findAllObjects(obj client.Object) []reconcile.Request {
+	list := &ecrv1beta1.RepositoryCompliancePolicyList{}
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, item := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name}}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RepositoryCompliancePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.RepositoryCompliancePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// only the repository spec and the namespace labels are evaluated
+		Watches(&source.Kind{Type: &ecrv1beta1.Repository{}}, handler.EnqueueRequestsFromMapFunc(r.findAllObjects),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&source.Kind{Type: &corev1.Namespace{}}, handler.EnqueueRequestsFromMapFunc(r.findAllObjects),
+			builder.WithPredicates(predicate.LabelChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,418 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const ecrCreationTemplateFinalizer = "creationtemplate.ecr.aws.cloud.qaware.de/finalizer"
+
+// RepositoryCreationTemplateReconciler reconciles a RepositoryCreationTemplate object
+type RepositoryCreationTemplateReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorycreationtemplates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorycreationtemplates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorycreationtemplates/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *RepositoryCreationTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("repositoryCreationTemplate", req.NamespacedName)
+
+	client, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	// lookup the RepositoryCreationTemplate instance for this reconcile request
+	creationTemplate := &ecrv1beta1.RepositoryCreationTemplate{}
+	geterr := r.Get(ctx, req.NamespacedName, creationTemplate)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("RepositoryCreationTemplate already deleted. Skipping.")
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get RepositoryCreationTemplate.")
+		return ctrl.Result{}, geterr
+	}
+
+	// Check if the RepositoryCreationTemplate instance is marked to be deleted, which is
+	// indicated by the deletion timestamp being set.
+	isCreationTemplateMarkedToBeDeleted := creationTemplate.GetDeletionTimestamp() != nil
+	if isCreationTemplateMarkedToBeDeleted {
+		if controllerutil.ContainsFinalizer(creationTemplate, ecrCreationTemplateFinalizer) {
+			// Run finalization logic for ecrCreationTemplateFinalizer. If the
+			// finalization logic fails, don't remove the finalizer so
+			// that we can retry during the next reconciliation.
+			if err := r.finalizeRepositoryCreationTemplate(ctx, logger, client, creationTemplate); err != nil {
+				return ctrl.Result{}, err
+			}
+
+			// Remove ecrCreationTemplateFinalizer. Once all finalizers have been
+			// removed, the object will be deleted.
+			controllerutil.RemoveFinalizer(creationTemplate, ecrCreationTemplateFinalizer)
+			err := r.Update(ctx, creationTemplate)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// add finalizer for this CR
+	if !controllerutil.ContainsFinalizer(creationTemplate, ecrCreationTemplateFinalizer) {
+		logger.Info("Update Finalizer for RepositoryCreationTemplate.")
+		controllerutil.AddFinalizer(creationTemplate, ecrCreationTemplateFinalizer)
+		upderr := r.Update(ctx, creationTemplate)
+		if upderr != nil {
+			logger.Error(upderr, "Unable to update RepositoryCreationTemplate with Finalizer")
+			return ctrl.Result{}, upderr
+		}
+	}
+
+	status := creationTemplate.Status.DeepCopy()
+	spec := creationTemplate.Spec
+
+	// the prefix identifies the template, so a changed prefix requires to delete the previous template
+	if previous := creationTemplate.Status.Prefix; previous != "" && previous != spec.Prefix {
+		if err := r.deleteUnusedRepositoryCreationTemplate(ctx, logger, client, creationTemplate, previous); err != nil {
+			return r.updateNotReady(ctx, logger, creationTemplate, "DeleteFailed", err)
+		}
+		creationTemplate.Status.Prefix = ""
+	}
+
+	// only the oldest object using the prefix applies the template, the others would overwrite it
+	templates, listerr := listPrefixTemplates(ctx, r.Client, spec.Prefix)
+	if listerr != nil {
+		logger.Error(listerr, "Failed to list RepositoryCreationTemplates.")
+		return ctrl.Result{}, listerr
+	}
+	if len(templates) > 0 && templates[0].UID != creationTemplate.UID {
+		conflict := fmt.Errorf("prefix %s is already used by RepositoryCreationTemplate %s", spec.Prefix, templates[0].Name)
+		return r.updateNotReady(ctx, logger, creationTemplate, "PrefixConflict", conflict)
+	}
+
+	// compare the actual template to detect drift
+	actual, descerr := describeRepositoryCreationTemplate(client, spec.Prefix)
+	if descerr != nil {
+		return r.updateNotReady(ctx, logger, creationTemplate, "DescribeFailed", descerr)
+	}
+
+	desired := createRepositoryCreationTemplate(spec)
+	if actual == nil || !repositoryCreationTemplateEqual(desired, actual) {
+		if creationTemplate.Status.LastAppliedTime != nil && creationTemplate.Status.ObservedGeneration == creationTemplate.Generation {
+			logger.Info("Repository creation template has drifted. Reapplying.", "prefix", spec.Prefix)
+			creationTemplate.Status.LastDriftTime = &metav1.Time{Time: time.Now()}
+		}
+
+		if actual == nil {
+			output, err := client.CreateRepositoryCreationTemplate(context.TODO(), &ecr.CreateRepositoryCreationTemplateInput{
+				Prefix:                  desired.Prefix,
+				Description:             desired.Description,
+				AppliedFor:              desired.AppliedFor,
+				ImageTagMutability:      desired.ImageTagMutability,
+				EncryptionConfiguration: desired.EncryptionConfiguration,
+				LifecyclePolicy:         desired.LifecyclePolicy,
+				RepositoryPolicy:        desired.RepositoryPolicy,
+				CustomRoleArn:           desired.CustomRoleArn,
+				ResourceTags:            desired.ResourceTags,
+			})
+			if err != nil {
+				return r.updateNotReady(ctx, logger, creationTemplate, "CreateFailed", err)
+			}
+			logger.Info("Created repository creation template.", "prefix", spec.Prefix)
+			creationTemplate.Status.RegistryId = aws.ToString(output.RegistryId)
+		} else {
+			// omitted fields are left unchanged on update, so removed fields are cleared with explicit empty values
+			output, err := client.UpdateRepositoryCreationTemplate(context.TODO(), &ecr.UpdateRepositoryCreationTemplateInput{
+				Prefix:                  desired.Prefix,
+				Description:             aws.String(aws.ToString(desired.Description)),
+				AppliedFor:              desired.AppliedFor,
+				ImageTagMutability:      desired.ImageTagMutability,
+				EncryptionConfiguration: desired.EncryptionConfiguration,
+				LifecyclePolicy:         aws.String(aws.ToString(desired.LifecyclePolicy)),
+				RepositoryPolicy:        aws.String(aws.ToString(desired.RepositoryPolicy)),
+				CustomRoleArn:           aws.String(aws.ToString(desired.CustomRoleArn)),
+				ResourceTags:            desired.ResourceTags,
+			})
+			if err != nil {
+				return r.updateNotReady(ctx, logger, creationTemplate, "UpdateFailed", err)
+			}
+			logger.Info("Updated repository creation template.", "prefix", spec.Prefix)
+			creationTemplate.Status.RegistryId = aws.ToString(output.RegistryId)
+		}
+		creationTemplate.Status.LastAppliedTime = &metav1.Time{Time: time.Now()}
+	}
+	creationTemplate.Status.Prefix = spec.Prefix
+	creationTemplate.Status.ObservedGeneration = creationTemplate.Generation
+
+	meta.SetStatusCondition(&creationTemplate.Status.Conditions, metav1.Condition{
+		Type:               conditionTypeReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Applied",
+		Message:            "Repository creation template is applied",
+		ObservedGeneration: creationTemplate.Generation,
+	})
+	if !equality.Semantic.DeepEqual(status, &creationTemplate.Status) {
+		if err := r.Status().Update(ctx, creationTemplate); err != nil {
+			logger.Error(err, "Failed to update RepositoryCreationTemplate status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{RequeueAfter: driftDetectionInterval}, nil
+}
+
+// Reports the failed AWS operation in the Ready condition and requeues the request
+func (r *RepositoryCreationTemplateReconciler) updateNotReady(ctx context.Context, logger logr.Logger, creationTemplate *ecrv1beta1.RepositoryCreationTemplate, reason string, err error) (ctrl.Result, error) {
+	logger.Error(err, "Could not reconcile repository creation template.", "reason", reason)
+	meta.SetStatusCondition(&creationTemplate.Status.Conditions, metav1.Condition{
+		Type:               conditionTypeReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: creationTemplate.Generation,
+	})
+	if upderr := r.Status().Update(ctx, creationTemplate); upderr != nil {
+		logger.Error(upderr, "Failed to update RepositoryCreationTemplate status")
+	}
+	return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(30) * time.Second}, nil
+}
+
+func (r *RepositoryCreationTemplateReconciler) finalizeRepositoryCreationTemplate(ctx context.Context, logger logr.Logger, client *ecr.Client, creationTemplate *ecrv1beta1.RepositoryCreationTemplate) error {
+	prefix := creationTemplate.Status.Prefix
+	if prefix == "" {
+		prefix = creationTemplate.Spec.Prefix
+	}
+	if err := r.deleteUnusedRepositoryCreationTemplate(ctx, logger, client, creationTemplate, prefix); err != nil {
+		return err
+	}
+
+	logger.Info("Successfully finalized and deleted RepositoryCreationTemplate.")
+	return nil
+}
+
+// Deletes the template with the prefix unless another object uses the prefix and takes the template over
+func (r *RepositoryCreationTemplateReconciler) deleteUnusedRepositoryCreationTemplate(ctx context.Context, logger logr.Logger, client *ecr.Client, creationTemplate *ecrv1beta1.RepositoryCreationTemplate, prefix string) error {
+	templates, err := listPrefixTemplates(ctx, r.Client, prefix)
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		if template.UID != creationTemplate.UID {
+			logger.Info("Repository creation template is used by another RepositoryCreationTemplate. Skipping delete.", "prefix", prefix, "usedBy", template.Name)
+			return nil
+		}
+	}
+	return deleteRepositoryCreationTemplate(logger, client, prefix)
+}
+
+// Returns the RepositoryCreationTemplate objects with the prefix that are not being deleted, oldest first.
+// The oldest object owns the prefix.
+func listPrefixTemplates(ctx context.Context, c client.Client, prefix string) ([]ecrv1beta1.RepositoryCreationTemplate, error) {
+	list := &ecrv1beta1.RepositoryCreationTemplateList{}
+	if err := c.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	templates := make([]ecrv1beta1.RepositoryCreationTemplate, 0)
+	for _, item := range list.Items {
+		if item.Spec.Prefix == prefix && item.GetDeletionTimestamp() == nil {
+			templates = append(templates, item)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if !templates[i].CreationTimestamp.Equal(&templates[j].CreationTimestamp) {
+			return templates[i].CreationTimestamp.Before(&templates[j].CreationTimestamp)
+		}
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+func deleteRepositoryCreationTemplate(logger logr.Logger, client *ecr.Client, prefix string) error {
+	_, delerr := client.DeleteRepositoryCreationTemplate(context.TODO(), &ecr.DeleteRepositoryCreationTemplateInput{
+		Prefix: aws.String(prefix),
+	})
+	if delerr != nil {
+		var tnfe *types.TemplateNotFoundException
+		if errors.As(delerr, &tnfe) {
+			// check for already deleted, might occur due to timing and duplicate reconcile
+			logger.Info("Repository creation template already deleted. Skipping.", "prefix", prefix)
+			return nil
+		}
+		logger.Error(delerr, "Failed to delete repository creation template.", "prefix", prefix)
+		return delerr
+	}
+	return nil
+}
+
+// Returns the repository creation template with the given prefix, or nil if it does not exist
+func describeRepositoryCreationTemplate(client *ecr.Client, prefix string) (*types.RepositoryCreationTemplate, error) {
+	output, err := client.DescribeRepositoryCreationTemplates(context.TODO(), &ecr.DescribeRepositoryCreationTemplatesInput{
+		Prefixes: []string{prefix},
+	})
+	if err != nil {
+		var tnfe *types.TemplateNotFoundException
+		if errors.As(err, &tnfe) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	for i := range output.RepositoryCreationTemplates {
+		if aws.ToString(output.RepositoryCreationTemplates[i].Prefix) == prefix {
+			return &output.RepositoryCreationTemplates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func createRepositoryCreationTemplate(spec ecrv1beta1.RepositoryCreationTemplateSpec) *types.RepositoryCreationTemplate {
+	appliedFor := make([]types.RCTAppliedFor, 0, len(spec.AppliedFor))
+	for _, a := range spec.AppliedFor {
+		appliedFor = append(appliedFor, types.RCTAppliedFor(a))
+	}
+
+	mutability := types.ImageTagMutability(spec.ImageTagMutability)
+	if mutability == "" {
+		mutability = types.ImageTagMutabilityMutable
+	}
+
+	// AES256 is the default encryption of created repositories
+	encryption := &types.EncryptionConfigurationForRepositoryCreationTemplate{EncryptionType: types.EncryptionTypeAes256}
+	if c := spec.EncryptionConfiguration; c != nil {
+		encryption = &types.EncryptionConfigurationForRepositoryCreationTemplate{EncryptionType: types.EncryptionType(c.EncryptionType), KmsKey: c.KmsKey}
+	}
+
+	tags := make([]types.Tag, 0, len(spec.ResourceTags))
+	for k, v := range spec.ResourceTags {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	template := &types.RepositoryCreationTemplate{
+		Prefix:                  aws.String(spec.Prefix),
+		AppliedFor:              appliedFor,
+		ImageTagMutability:      mutability,
+		EncryptionConfiguration: encryption,
+		ResourceTags:            tags,
+	}
+	if spec.Description != "" {
+		template.Description = aws.String(spec.Description)
+	}
+	if spec.LifecyclePolicyText != "" {
+		template.LifecyclePolicy = aws.String(spec.LifecyclePolicyText)
+	}
+	if spec.RepositoryPolicyText != "" {
+		template.RepositoryPolicy = aws.String(spec.RepositoryPolicyText)
+	}
+	if spec.CustomRoleArn != "" {
+		template.CustomRoleArn = aws.String(spec.CustomRoleArn)
+	}
+	return template
+}
+
+func repositoryCreationTemplateEqual(desired *types.RepositoryCreationTemplate, actual *types.RepositoryCreationTemplate) bool {
+	if aws.ToString(desired.Description) != aws.ToString(actual.Description) ||
+		desired.ImageTagMutability != actual.ImageTagMutability ||
+		aws.ToString(desired.CustomRoleArn) != aws.ToString(actual.CustomRoleArn) ||
+		!policyTextEqual(aws.ToString(desired.LifecyclePolicy), aws.ToString(actual.LifecyclePolicy)) ||
+		!policyTextEqual(aws.ToString(desired.RepositoryPolicy), aws.ToString(actual.RepositoryPolicy)) {
+		return false
+	}
+
+	if actual.EncryptionConfiguration == nil || desired.EncryptionConfiguration.EncryptionType != actual.EncryptionConfiguration.EncryptionType {
+		return false
+	}
+	// without a KMS key the AWS managed key is used, which is not compared
+	if desired.EncryptionConfiguration.KmsKey != nil && aws.ToString(desired.EncryptionConfiguration.KmsKey) != aws.ToString(actual.EncryptionConfiguration.KmsKey) {
+		return false
+	}
+
+	desiredAppliedFor := make([]string, 0, len(desired.AppliedFor))
+	for _, a := range desired.AppliedFor {
+		desiredAppliedFor = append(desiredAppliedFor, string(a))
+	}
+	actualAppliedFor := make([]string, 0, len(actual.AppliedFor))
+	for _, a := range actual.AppliedFor {
+		actualAppliedFor = append(actualAppliedFor, string(a))
+	}
+	sort.Strings(desiredAppliedFor)
+	sort.Strings(actualAppliedFor)
+	if !equality.Semantic.DeepEqual(desiredAppliedFor, actualAppliedFor) {
+		return false
+	}
+
+	return equality.Semantic.DeepEqual(tagMap(desired.ResourceTags), tagMap(actual.ResourceTags))
+}
+
+func tagMap(tags []types.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return m
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RepositoryCreationTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&ecrv1beta1.RepositoryCreationTemplate{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -0,0 +1,82 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+//+kubebuilder:webhook:path=/validate-ecr-aws-cloud-qaware-de-v1beta1-repositorycreationtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=ecr.aws.cloud.qaware.de,resources=repositorycreationtemplates,verbs=create;update,versions=v1beta1,name=vrepositorycreationtemplate.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// RepositoryCreationTemplateValidator validates the prefixes of RepositoryCreationTemplate objects
+type RepositoryCreationTemplateValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle denies RepositoryCreationTemplate objects using the prefix of another object, since both would
+// apply the same template. The RepositoryCreationTemplateReconciler only applies the template of the oldest one.
+func (v *RepositoryCreationTemplateValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	creationTemplate := &ecrv1beta1.RepositoryCreationTemplate{}
+	if err := v.decoder.Decode(req, creationTemplate); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// objects created with a conflicting prefix before must still be updated, e.g. to remove the finalizer
+	if req.Operation == admissionv1.Update {
+		old := &ecrv1beta1.RepositoryCreationTemplate{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if old.Spec.Prefix == creationTemplate.Spec.Prefix {
+			return admission.Allowed("")
+		}
+	}
+
+	templates, err := listPrefixTemplates(ctx, v.Client, creationTemplate.Spec.Prefix)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	for _, template := range templates {
+		if template.Name != req.Name {
+			return admission.Denied(fmt.Sprintf("prefix %s is already used by RepositoryCreationTemplate %s", creationTemplate.Spec.Prefix, template.Name))
+		}
+	}
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder into the RepositoryCreationTemplateValidator.
+func (v *RepositoryCreationTemplateValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
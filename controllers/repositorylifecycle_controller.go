@@ -29,14 +29,21 @@ import (
 	"errors"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
@@ -57,6 +64,7 @@ type RepositoryLifecycleReconciler struct {
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorylifecycles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorylifecycles/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorylifecycles/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -109,33 +117,45 @@ func (r *RepositoryLifecycleReconciler) Reconcile(ctx context.Context, req ctrl.
 		return ctrl.Result{}, nil
 	}
 
-	// check if the referenced repository exists in the same namespace
-	objectKey := k8stypes.NamespacedName{Name: repositoryLifecycle.Spec.RepositoryName, Namespace: req.Namespace}
-	repository := &ecrv1beta1.Repository{}
-	geterr = r.Get(ctx, objectKey, repository)
+	// find the target repositories, either by name or by label selector in the same namespace
+	repositories, geterr := findTargetRepositories(ctx, r.Client, req.Namespace, repositoryLifecycle.Spec.RepositoryName, repositoryLifecycle.Spec.RepositorySelector)
 	if geterr != nil {
 		// wait and requeue until repository can be found
-		logger.Error(geterr, "Unable to get referenced Repository object.", "objectKey", objectKey)
+		logger.Error(geterr, "Unable to get target Repository objects.")
 		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, geterr
 	}
 
-	// reconcile and create the lifecycle policy
-	setout, seterr := client.PutLifecyclePolicy(context.TODO(), &ecr.PutLifecyclePolicyInput{
-		RepositoryName:      aws.String(repositoryLifecycle.Spec.RepositoryName),
-		LifecyclePolicyText: aws.String(repositoryLifecycle.Spec.LifecyclePolicyText),
-	})
-	if seterr != nil {
-		logger.Error(seterr, "Could not set ECR LifecyclePolicy.")
-		return ctrl.Result{}, seterr
+	// reconcile and create the lifecycle policy for each target repository
+	var seterr error
+	targets := make([]ecrv1beta1.RepositoryTargetStatus, 0, len(repositories))
+	for i := range repositories {
+		target, err := r.applyLifecyclePolicy(ctx, logger, client, repositoryLifecycle, &repositories[i])
+		if err != nil {
+			seterr = err
+		}
+		targets = append(targets, target)
 	}
 
-	logger.Info("Successfully set ECR LifecyclePolicy.", "RepositoryName", setout.RepositoryName, "LifecyclePolicyText", setout.LifecyclePolicyText)
+	// cleanup the lifecycle policy for repositories that are no longer targeted
+	for _, name := range findStaleTargets(repositoryLifecycle.Status.Targets, repositories) {
+		if err := r.deleteLifecyclePolicy(logger, client, name); err != nil {
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+	}
 
-	// add finalizer for this CR
-	if !controllerutil.ContainsFinalizer(repositoryLifecycle, ecrLifecycleFinalizer) {
+	// add finalizer for this CR, only a named repository owns the CR
+	var owner *ecrv1beta1.Repository
+	if repositoryLifecycle.Spec.RepositorySelector == nil {
+		owner = &repositories[0]
+	}
+	ownerChanged, ownererr := setRepositoryOwnerReference(owner, repositoryLifecycle, r.Scheme)
+	if ownererr != nil {
+		logger.Error(ownererr, "Unable to set OwnerReference for RepositoryLifecycle")
+		return ctrl.Result{}, ownererr
+	}
+	if !controllerutil.ContainsFinalizer(repositoryLifecycle, ecrLifecycleFinalizer) || ownerChanged {
 		logger.Info("Update Finalizer and OwnerReference for RepositoryLifecycle.")
 		controllerutil.AddFinalizer(repositoryLifecycle, ecrLifecycleFinalizer)
-		controllerutil.SetOwnerReference(repository, repositoryLifecycle, r.Scheme)
 		upderr := r.Update(ctx, repositoryLifecycle)
 		if upderr != nil {
 			logger.Error(upderr, "Unable to update RepositoryLifecycle with Finalizer and OwnerReference")
@@ -143,33 +163,132 @@ func (r *RepositoryLifecycleReconciler) Reconcile(ctx context.Context, req ctrl.
 		}
 	}
 
+	repositoryLifecycle.Status.Targets = targets
+	if err := r.Status().Update(ctx, repositoryLifecycle); err != nil {
+		logger.Error(err, "Failed to update RepositoryLifecycle status")
+		return ctrl.Result{}, err
+	}
+
+	if seterr != nil {
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, seterr
+	}
+
 	return ctrl.Result{}, nil
 }
 
+func (r *RepositoryLifecycleReconciler) applyLifecyclePolicy(ctx context.Context, logger logr.Logger, client *ecr.Client, rl *ecrv1beta1.RepositoryLifecycle, repository *ecrv1beta1.Repository) (ecrv1beta1.RepositoryTargetStatus, error) {
+	target := ecrv1beta1.RepositoryTargetStatus{RepositoryName: ecrRepositoryName(repository)}
+
+	// resolve the lifecycle policy text, either inline or from the referenced ConfigMap or Secret
+	lifecyclePolicyText, texterr := resolvePolicyText(ctx, r.Client, rl.Namespace, rl.Spec.LifecyclePolicyText, rl.Spec.LifecyclePolicyTextFrom, repository)
+	if texterr != nil {
+		logger.Error(texterr, "Unable to resolve LifecyclePolicy text.", "RepositoryName", ecrRepositoryName(repository))
+		target.Message = texterr.Error()
+		return target, texterr
+	}
+
+	setout, seterr := client.PutLifecyclePolicy(context.TODO(), &ecr.PutLifecyclePolicyInput{
+		RepositoryName:      aws.String(ecrRepositoryName(repository)),
+		LifecyclePolicyText: aws.String(lifecyclePolicyText),
+	})
+	if seterr != nil {
+		logger.Error(seterr, "Could not set ECR LifecyclePolicy.", "RepositoryName", ecrRepositoryName(repository))
+		target.Message = seterr.Error()
+		return target, seterr
+	}
+
+	logger.Info("Successfully set ECR LifecyclePolicy.", "RepositoryName", setout.RepositoryName, "LifecyclePolicyText", setout.LifecyclePolicyText)
+
+	now := metav1.Now()
+	target.Applied = true
+	target.LastAppliedTime = &now
+	return target, nil
+}
+
 func (r *RepositoryLifecycleReconciler) finalizeRepositoryLifecycle(logger logr.Logger, client *ecr.Client, rl *ecrv1beta1.RepositoryLifecycle) error {
+	for _, name := range findAllTargets(rl.Status.Targets, rl.Spec.RepositoryName) {
+		if err := r.deleteLifecyclePolicy(logger, client, name); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Successfully finalized and deleted RepositoryLifecycle.")
+	return nil
+}
+
+func (r *RepositoryLifecycleReconciler) deleteLifecyclePolicy(logger logr.Logger, client *ecr.Client, repositoryName string) error {
 	_, delerr := client.DeleteLifecyclePolicy(context.TODO(), &ecr.DeleteLifecyclePolicyInput{
-		RepositoryName: aws.String(rl.Spec.RepositoryName),
+		RepositoryName: aws.String(repositoryName),
 	})
 	if delerr != nil {
 		var rnfe *types.RepositoryNotFoundException
-		if errors.As(delerr, &rnfe) {
+		var lpnfe *types.LifecyclePolicyNotFoundException
+		if errors.As(delerr, &rnfe) || errors.As(delerr, &lpnfe) {
 			// check for already deleted, might occur due to timing and duplicate reconcile
-			logger.Info("Repository already deleted. Skipping RepositoryLifecycle delete.")
+			logger.Info("Repository or LifecyclePolicy already deleted. Skipping RepositoryLifecycle delete.", "RepositoryName", repositoryName)
 			return nil
 		} else {
-			logger.Error(delerr, "Failed to delete RepositoryLifecycle.", "repositoryLifecycleName", rl.Name)
+			logger.Error(delerr, "Failed to delete RepositoryLifecycle.", "RepositoryName", repositoryName)
 			return delerr
 		}
 	}
-
-	logger.Info("Successfully finalized and deleted RepositoryLifecycle.")
 	return nil
 }
 
+// find all RepositoryLifecycles referencing the given ConfigMap or Secret via the given index
+func (r *RepositoryLifecycleReconciler) findObjectsForIndex(indexKey string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &ecrv1beta1.RepositoryLifecycleList{}
+		err := r.List(context.TODO(), list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{indexKey: obj.GetName()})
+		if err != nil {
+			return []reconcile.Request{}
+		}
+
+		requests := make([]reconcile.Request, len(list.Items))
+		for i, item := range list.Items {
+			requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+		}
+		return requests
+	}
+}
+
+// find all RepositoryLifecycles with a repository selector in the given namespace
+func (r *RepositoryLifecycleReconciler) findRepositorySelectors(namespace string) ([]repositorySelectorRef, error) {
+	list := &ecrv1beta1.RepositoryLifecycleList{}
+	if err := r.List(context.TODO(), list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	refs := make([]repositorySelectorRef, 0)
+	for _, item := range list.Items {
+		if item.Spec.RepositorySelector != nil {
+			refs = append(refs, repositorySelectorRef{Name: item.Name, Namespace: item.Namespace, Selector: item.Spec.RepositorySelector})
+		}
+	}
+	return refs, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RepositoryLifecycleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// index the referenced ConfigMaps and Secrets to re-reconcile on changes
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.RepositoryLifecycle{}, lifecycleConfigMapIndexKey, func(o client.Object) []string {
+		return policyConfigMapName(o.(*ecrv1beta1.RepositoryLifecycle).Spec.LifecyclePolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.RepositoryLifecycle{}, lifecycleSecretIndexKey, func(o client.Object) []string {
+		return policySecretName(o.(*ecrv1beta1.RepositoryLifecycle).Spec.LifecyclePolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ecrv1beta1.RepositoryLifecycle{}).
+		For(&ecrv1beta1.RepositoryLifecycle{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the repository might have started or stopped to match a selector
+		Watches(&source.Kind{Type: &ecrv1beta1.Repository{}}, enqueueRepositorySelectors(r.findRepositorySelectors),
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(lifecycleConfigMapIndexKey))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(lifecycleSecretIndexKey))).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
 		Complete(r)
 }
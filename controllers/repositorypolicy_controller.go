@@ -27,16 +27,24 @@ package controllers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
@@ -57,6 +65,8 @@ const ecrPolicyFinalizer = "policy.ecr.aws.cloud.qaware.de/finalizer"
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorypolicies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorypolicies/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorypolicies/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositorypolicyconstraints,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps;secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -109,34 +119,54 @@ func (r *RepositoryPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		return ctrl.Result{}, nil
 	}
 
-	// check if the referenced repository exists in the same namespace
-	objectKey := k8stypes.NamespacedName{Name: repositoryPolicy.Spec.RepositoryName, Namespace: req.Namespace}
-	repository := &ecrv1beta1.Repository{}
-	geterr = r.Get(ctx, objectKey, repository)
+	// find the target repositories, either by name or by label selector in the same namespace
+	repositories, geterr := findTargetRepositories(ctx, r.Client, req.Namespace, repositoryPolicy.Spec.RepositoryName, repositoryPolicy.Spec.RepositorySelector)
 	if geterr != nil {
 		// wait and requeue until repository can be found
-		logger.Error(geterr, "Unable to get referenced Repository object.", "objectKey", objectKey)
+		logger.Error(geterr, "Unable to get target Repository objects.")
 		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, geterr
 	}
 
-	// reconcile and create the repository policy
-	setout, seterr := client.SetRepositoryPolicy(context.TODO(), &ecr.SetRepositoryPolicyInput{
-		RepositoryName: aws.String(repositoryPolicy.Spec.RepositoryName),
-		PolicyText:     aws.String(repositoryPolicy.Spec.PolicyText),
-		Force:          repositoryPolicy.Spec.Force,
-	})
-	if seterr != nil {
-		logger.Error(seterr, "Could not set ECR RepositoryPolicy.")
-		return ctrl.Result{}, seterr
+	// lookup the cluster-wide constraints to enforce before setting the policy
+	constraints, listerr := listPolicyConstraints(ctx, r.Client)
+	if listerr != nil {
+		logger.Error(listerr, "Unable to list RepositoryPolicyConstraint objects.")
+		return ctrl.Result{}, listerr
 	}
 
-	logger.Info("Successfully set ECR RepositoryPolicy.", "RepositoryName", setout.RepositoryName, "PolicyText", setout.PolicyText)
+	// reconcile and create the repository policy for each target repository
+	var seterr error
+	targets := make([]ecrv1beta1.RepositoryTargetStatus, 0, len(repositories))
+	violations := make([]string, 0)
+	for i := range repositories {
+		target, targetViolations, err := r.applyRepositoryPolicy(ctx, logger, client, repositoryPolicy, &repositories[i], constraints)
+		if err != nil {
+			seterr = err
+		}
+		targets = append(targets, target)
+		violations = append(violations, targetViolations...)
+	}
+
+	// cleanup the repository policy for repositories that are no longer targeted
+	for _, name := range findStaleTargets(repositoryPolicy.Status.Targets, repositories) {
+		if err := r.deleteRepositoryPolicy(logger, client, name); err != nil {
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+	}
 
-	// add finalizer for this CR
-	if !controllerutil.ContainsFinalizer(repositoryPolicy, ecrPolicyFinalizer) {
+	// add finalizer for this CR, only a named repository owns the CR
+	var owner *ecrv1beta1.Repository
+	if repositoryPolicy.Spec.RepositorySelector == nil {
+		owner = &repositories[0]
+	}
+	ownerChanged, ownererr := setRepositoryOwnerReference(owner, repositoryPolicy, r.Scheme)
+	if ownererr != nil {
+		logger.Error(ownererr, "Unable to set OwnerReference for RepositoryPolicy")
+		return ctrl.Result{}, ownererr
+	}
+	if !controllerutil.ContainsFinalizer(repositoryPolicy, ecrPolicyFinalizer) || ownerChanged {
 		logger.Info("Update Finalizer and OwnerReference for RepositoryPolicy.")
 		controllerutil.AddFinalizer(repositoryPolicy, ecrPolicyFinalizer)
-		controllerutil.SetOwnerReference(repository, repositoryPolicy, r.Scheme)
 		upderr := r.Update(ctx, repositoryPolicy)
 		if upderr != nil {
 			logger.Error(upderr, "Unable to update RepositoryPolicy with Finalizer and OwnerReference")
@@ -144,33 +174,170 @@ func (r *RepositoryPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		}
 	}
 
+	repositoryPolicy.Status.Targets = targets
+	repositoryPolicy.Status.Violations = violations
+	if err := r.Status().Update(ctx, repositoryPolicy); err != nil {
+		logger.Error(err, "Failed to update RepositoryPolicy status")
+		return ctrl.Result{}, err
+	}
+
+	if seterr != nil {
+		return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, seterr
+	}
+
 	return ctrl.Result{}, nil
 }
 
+func (r *RepositoryPolicyReconciler) applyRepositoryPolicy(ctx context.Context, logger logr.Logger, client *ecr.Client, rp *ecrv1beta1.RepositoryPolicy, repository *ecrv1beta1.Repository, constraints []ecrv1beta1.RepositoryPolicyConstraint) (ecrv1beta1.RepositoryTargetStatus, []string, error) {
+	target := ecrv1beta1.RepositoryTargetStatus{RepositoryName: ecrRepositoryName(repository)}
+
+	// resolve the policy text, either inline or from the referenced ConfigMap or Secret
+	policyText, texterr := resolvePolicyText(ctx, r.Client, rp.Namespace, rp.Spec.PolicyText, rp.Spec.PolicyTextFrom, repository)
+	if texterr != nil {
+		logger.Error(texterr, "Unable to resolve RepositoryPolicy text.", "RepositoryName", ecrRepositoryName(repository))
+		target.Message = texterr.Error()
+		return target, nil, texterr
+	}
+
+	// enforce the cluster-wide constraints, violations are not retried until the policy or a constraint changes.
+	// A previously applied policy that can not be evaluated or violates a constraint now is removed.
+	violations, evalerr := evaluatePolicyConstraints(policyText, constraints)
+	if evalerr != nil {
+		logger.Error(evalerr, "Unable to evaluate RepositoryPolicyConstraints.", "RepositoryName", ecrRepositoryName(repository))
+		target.Message = evalerr.Error()
+		if delerr := r.deleteRepositoryPolicy(logger, client, ecrRepositoryName(repository)); delerr != nil {
+			return target, nil, delerr
+		}
+		return target, nil, nil
+	}
+	if len(violations) > 0 {
+		logger.Info("RepositoryPolicy violates RepositoryPolicyConstraints. Removing.", "RepositoryName", ecrRepositoryName(repository), "violations", violations)
+		target.Message = fmt.Sprintf("policy violates %d constraint(s)", len(violations))
+		if delerr := r.deleteRepositoryPolicy(logger, client, ecrRepositoryName(repository)); delerr != nil {
+			return target, violations, delerr
+		}
+		return target, violations, nil
+	}
+
+	setout, seterr := client.SetRepositoryPolicy(context.TODO(), &ecr.SetRepositoryPolicyInput{
+		RepositoryName: aws.String(ecrRepositoryName(repository)),
+		PolicyText:     aws.String(policyText),
+		Force:          rp.Spec.Force,
+	})
+	if seterr != nil {
+		logger.Error(seterr, "Could not set ECR RepositoryPolicy.", "RepositoryName", ecrRepositoryName(repository))
+		target.Message = seterr.Error()
+		return target, nil, seterr
+	}
+
+	logger.Info("Successfully set ECR RepositoryPolicy.", "RepositoryName", setout.RepositoryName, "PolicyText", setout.PolicyText)
+
+	now := metav1.Now()
+	target.Applied = true
+	target.LastAppliedTime = &now
+	return target, nil, nil
+}
+
 func (r *RepositoryPolicyReconciler) finalizeRepositoryPolicy(logger logr.Logger, client *ecr.Client, rp *ecrv1beta1.RepositoryPolicy) error {
+	for _, name := range findAllTargets(rp.Status.Targets, rp.Spec.RepositoryName) {
+		if err := r.deleteRepositoryPolicy(logger, client, name); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Successfully finalized and deleted RepositoryPolicy.")
+	return nil
+}
+
+func (r *RepositoryPolicyReconciler) deleteRepositoryPolicy(logger logr.Logger, client *ecr.Client, repositoryName string) error {
 	_, delerr := client.DeleteRepositoryPolicy(context.TODO(), &ecr.DeleteRepositoryPolicyInput{
-		RepositoryName: aws.String(rp.Spec.RepositoryName),
+		RepositoryName: aws.String(repositoryName),
 	})
 	if delerr != nil {
 		var rnfe *types.RepositoryNotFoundException
-		if errors.As(delerr, &rnfe) {
+		var rpnfe *types.RepositoryPolicyNotFoundException
+		if errors.As(delerr, &rnfe) || errors.As(delerr, &rpnfe) {
 			// check for already deleted, might occur due to timing and duplicate reconcile
-			logger.Info("Repository already deleted. Skipping RepositoryPolicy delete.")
+			logger.Info("Repository or RepositoryPolicy already deleted. Skipping RepositoryPolicy delete.", "RepositoryName", repositoryName)
 			return nil
 		} else {
-			logger.Error(delerr, "Failed to delete RepositoryPolicy.", "repositoryPolicyName", rp.Name)
+			logger.Error(delerr, "Failed to delete RepositoryPolicy.", "RepositoryName", repositoryName)
 			return delerr
 		}
 	}
-
-	logger.Info("Successfully finalized and deleted RepositoryPolicy.")
 	return nil
 }
 
+// find all RepositoryPolicies referencing the given ConfigMap or Secret via the given index
+func (r *RepositoryPolicyReconciler) findObjectsForIndex(indexKey string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		list := &ecrv1beta1.RepositoryPolicyList{}
+		err := r.List(context.TODO(), list, client.InNamespace(obj.GetNamespace()), client.MatchingFields{indexKey: obj.GetName()})
+		if err != nil {
+			return []reconcile.Request{}
+		}
+
+		requests := make([]reconcile.Request, len(list.Items))
+		for i, item := range list.Items {
+			requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+		}
+		return requests
+	}
+}
+
+// find all RepositoryPolicies with a repository selector in the given namespace
+func (r *RepositoryPolicyReconciler) findRepositorySelectors(namespace string) ([]repositorySelectorRef, error) {
+	list := &ecrv1beta1.RepositoryPolicyList{}
+	if err := r.List(context.TODO(), list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	refs := make([]repositorySelectorRef, 0)
+	for _, item := range list.Items {
+		if item.Spec.RepositorySelector != nil {
+			refs = append(refs, repositorySelectorRef{Name: item.Name, Namespace: item.Namespace, Selector: item.Spec.RepositorySelector})
+		}
+	}
+	return refs, nil
+}
+
+// find all RepositoryPolicies of the cluster, since any of them might be affected by a constraint change
+func (r *RepositoryPolicyReconciler) findObjectsForConstraint(obj client.Object) []reconcile.Request {
+	list := &ecrv1beta1.RepositoryPolicyList{}
+	err := r.List(context.TODO(), list)
+	if err != nil {
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, len(list.Items))
+	for i, item := range list.Items {
+		requests[i] = reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: item.Name, Namespace: item.Namespace}}
+	}
+	return requests
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *RepositoryPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// index the referenced ConfigMaps and Secrets to re-reconcile on changes
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.RepositoryPolicy{}, policyConfigMapIndexKey, func(o client.Object) []string {
+		return policyConfigMapName(o.(*ecrv1beta1.RepositoryPolicy).Spec.PolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &ecrv1beta1.RepositoryPolicy{}, policySecretIndexKey, func(o client.Object) []string {
+		return policySecretName(o.(*ecrv1beta1.RepositoryPolicy).Spec.PolicyTextFrom)
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&ecrv1beta1.RepositoryPolicy{}).
+		For(&ecrv1beta1.RepositoryPolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		// the repository might have started or stopped to match a selector
+		Watches(&source.Kind{Type: &ecrv1beta1.Repository{}}, enqueueRepositorySelectors(r.findRepositorySelectors),
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{}))).
+		Watches(&source.Kind{Type: &ecrv1beta1.RepositoryPolicyConstraint{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForConstraint)).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(policyConfigMapIndexKey))).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.findObjectsForIndex(policySecretIndexKey))).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
 		Complete(r)
 }
@@ -0,0 +1,93 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+)
+
+//+kubebuilder:webhook:path=/validate-ecr-aws-cloud-qaware-de-v1beta1-repositorypolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=ecr.aws.cloud.qaware.de,resources=repositorypolicies,verbs=create;update,versions=v1beta1,name=vrepositorypolicy.kb.io,admissionReviewVersions={v1,v1beta1}
+
+// RepositoryPolicyValidator validates RepositoryPolicy objects against the RepositoryPolicyConstraints
+type RepositoryPolicyValidator struct {
+	Client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle validates the policy text of the RepositoryPolicy for every currently targeted Repository.
+// Targets that do not exist yet are validated later by the RepositoryPolicyReconciler.
+func (v *RepositoryPolicyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := ctrllog.FromContext(ctx).WithValues("repositoryPolicy", req.Namespace+"/"+req.Name)
+
+	repositoryPolicy := &ecrv1beta1.RepositoryPolicy{}
+	if err := v.decoder.Decode(req, repositoryPolicy); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	constraints, err := listPolicyConstraints(ctx, v.Client)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(constraints) == 0 {
+		return admission.Allowed("")
+	}
+
+	repositories, err := findTargetRepositories(ctx, v.Client, req.Namespace, repositoryPolicy.Spec.RepositoryName, repositoryPolicy.Spec.RepositorySelector)
+	if err != nil || len(repositories) == 0 {
+		logger.Info("No target Repository found, deferring RepositoryPolicyConstraint validation.")
+		return admission.Allowed("").WithWarnings("no target Repository found, constraints will be enforced on reconcile")
+	}
+
+	for i := range repositories {
+		policyText, err := resolvePolicyText(ctx, v.Client, req.Namespace, repositoryPolicy.Spec.PolicyText, repositoryPolicy.Spec.PolicyTextFrom, &repositories[i])
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+
+		violations, err := evaluatePolicyConstraints(policyText, constraints)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+		if len(violations) > 0 {
+			return admission.Denied(strings.Join(violations, "; "))
+		}
+	}
+
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder into the RepositoryPolicyValidator.
+func (v *RepositoryPolicyValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/go-logr/logr"
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const conditionTypeTagAliasesApplied = "TagAliasesApplied"
+
+var (
+	imageTagPattern    = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+	imageDigestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// Points the tag aliases of the Repository at their images using PutImage with the existing
+// manifest and reports the result in the TagAliasesApplied condition. Existing tags of
+// IMMUTABLE repositories can not be moved, they are reported instead of retried.
+// Returns whether any tag has been changed.
+func applyTagAliases(logger logr.Logger, client *ecr.Client, repository *ecrv1beta1.Repository, images []types.ImageDetail) bool {
+	if len(repository.Spec.TagAliases) == 0 {
+		meta.RemoveStatusCondition(&repository.Status.Conditions, conditionTypeTagAliasesApplied)
+		return false
+	}
+
+	// the digest each existing tag points to
+	digests := make(map[string]string)
+	for _, image := range images {
+		for _, tag := range image.ImageTags {
+			digests[tag] = aws.ToString(image.ImageDigest)
+		}
+	}
+
+	tags := make([]string, 0, len(repository.Spec.TagAliases))
+	for tag := range repository.Spec.TagAliases {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	immutable := repository.Spec.ImageTagMutability == ecrv1beta1.ImageTagMutability(types.ImageTagMutabilityImmutable)
+	copier := &imageCopier{
+		logger:      logger,
+		source:      ecrLocation{client: client, repositoryName: ecrRepositoryName(repository)},
+		destination: ecrLocation{client: client, repositoryName: ecrRepositoryName(repository)},
+	}
+
+	changed := false
+	blocked := make([]string, 0)
+	failed := make([]string, 0)
+	for _, tag := range tags {
+		target := repository.Spec.TagAliases[tag]
+		digest := target
+		if !imageDigestPattern.MatchString(target) {
+			digest = digests[target]
+		}
+		if digest == "" {
+			failed = append(failed, fmt.Sprintf("%s: image %s not found", tag, target))
+			continue
+		}
+
+		current, exists := digests[tag]
+		if current == digest {
+			continue
+		}
+		if exists && immutable {
+			blocked = append(blocked, tag)
+			continue
+		}
+
+		image, err := copier.getImage(&types.ImageIdentifier{ImageDigest: aws.String(digest)})
+		if err == nil {
+			err = copier.putImage(image, tag)
+		}
+		if err != nil {
+			logger.Error(err, "Could not apply tag alias.", "tag", tag, "target", target)
+			failed = append(failed, fmt.Sprintf("%s: %s", tag, err))
+			continue
+		}
+
+		logger.Info("Applied tag alias.", "tag", tag, "imageDigest", digest)
+		changed = true
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionTypeTagAliasesApplied,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Applied",
+		Message:            "All tag aliases point to their images",
+		ObservedGeneration: repository.Generation,
+	}
+	if len(blocked) > 0 {
+		failed = append([]string{fmt.Sprintf("existing tags can not be moved in an IMMUTABLE repository: %s", strings.Join(blocked, ", "))}, failed...)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ImmutableTags"
+		condition.Message = strings.Join(failed, "; ")
+	} else if len(failed) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "Failed"
+		condition.Message = strings.Join(failed, "; ")
+	}
+	meta.SetStatusCondition(&repository.Status.Conditions, condition)
+
+	return changed
+}
+
+// Returns the validation errors of the tag aliases
+func validateTagAliases(aliases map[string]string) []string {
+	errs := make([]string, 0)
+	for tag, target := range aliases {
+		if !imageTagPattern.MatchString(tag) {
+			errs = append(errs, fmt.Sprintf("invalid tagAliases tag %q", tag))
+		}
+		if !imageDigestPattern.MatchString(target) && !imageTagPattern.MatchString(target) {
+			errs = append(errs, fmt.Sprintf("invalid tagAliases target %q of tag %s, must be a sha256 digest or tag", target, tag))
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
@@ -0,0 +1,333 @@
+/*
+MIT License
+
+Copyright (c) 2021 M.-Leander Reimer
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	ctrllog "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+const (
+	ecrRepositoryLabel = "ecr.aws.cloud.qaware.de/repository"
+	// the interval to look for new scan findings of the repository images
+	vulnerabilityReportRefreshInterval = time.Duration(10) * time.Minute
+	// the number of findings listed in a report, the summary counts all findings
+	topFindingsLimit = 25
+)
+
+// the order of the ECR finding severities, most severe first
+var findingSeverityOrder = map[types.FindingSeverity]int{
+	types.FindingSeverityCritical:      0,
+	types.FindingSeverityHigh:          1,
+	types.FindingSeverityMedium:        2,
+	types.FindingSeverityLow:           3,
+	types.FindingSeverityInformational: 4,
+	findingSeverityUntriaged:           5,
+	types.FindingSeverityUndefined:     6,
+}
+
+// enhanced scanning reports findings without a severity score as UNTRIAGED, the SDK has no constant for it
+const findingSeverityUntriaged types.FindingSeverity = "UNTRIAGED"
+
+// VulnerabilityReportReconciler reconciles the VulnerabilityReport objects of a Repository
+type VulnerabilityReportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=vulnerabilityreports,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ecr.aws.cloud.qaware.de,resources=repositories,verbs=get;list;watch
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.9.2/pkg/reconcile
+func (r *VulnerabilityReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := ctrllog.FromContext(ctx).WithValues("repository", req.NamespacedName)
+
+	// lookup the Repository instance for this reconcile request
+	repository := &ecrv1beta1.Repository{}
+	geterr := r.Get(ctx, req.NamespacedName, repository)
+	if geterr != nil {
+		if k8serrors.IsNotFound(geterr) {
+			// the VulnerabilityReports are garbage collected via their owner reference
+			return ctrl.Result{}, nil
+		}
+
+		logger.Error(geterr, "Failed to get Repository.")
+		return ctrl.Result{}, geterr
+	}
+
+	if repository.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+	if repository.Status.RepositoryUri == "" {
+		logger.Info("Repository not created yet. Retrying.")
+		return ctrl.Result{RequeueAfter: time.Duration(30) * time.Second}, nil
+	}
+
+	ecrClient, awserr := CreateEcrClient()
+	if awserr != nil {
+		logger.Error(awserr, "Unable to create ECR client.")
+		return ctrl.Result{}, awserr
+	}
+
+	reports := &ecrv1beta1.VulnerabilityReportList{}
+	if err := r.List(ctx, reports, client.InNamespace(repository.Namespace), client.MatchingLabels{ecrRepositoryLabel: repository.Name}); err != nil {
+		logger.Error(err, "Failed to list VulnerabilityReports.")
+		return ctrl.Result{}, err
+	}
+	existing := make(map[string]ecrv1beta1.VulnerabilityReport, len(reports.Items))
+	for _, report := range reports.Items {
+		existing[report.Name] = report
+	}
+
+	images := make([]types.ImageDetail, 0)
+	paginator := ecr.NewDescribeImagesPaginator(ecrClient, &ecr.DescribeImagesInput{
+		RepositoryName: aws.String(ecrRepositoryName(repository)),
+		Filter:         &types.DescribeImagesFilter{TagStatus: types.TagStatusTagged},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			logger.Error(err, "Could not describe images of ECR repository.")
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+		images = append(images, output.ImageDetails...)
+	}
+
+	current := make(map[string]bool, len(images))
+	for _, image := range images {
+		// enhanced scanning reports continuously scanned images as ACTIVE
+		if image.ImageScanStatus == nil || image.ImageScanFindingsSummary == nil {
+			continue
+		}
+		if image.ImageScanStatus.Status != types.ScanStatusComplete && image.ImageScanStatus.Status != types.ScanStatusActive {
+			continue
+		}
+
+		name := vulnerabilityReportName(repository.Name, aws.ToString(image.ImageDigest))
+		current[name] = true
+
+		// skip the findings if the report is up-to-date with the last completed scan,
+		// the stored timestamp is serialized with second precision
+		completedAt := aws.ToTime(image.ImageScanFindingsSummary.ImageScanCompletedAt).Truncate(time.Second)
+		if report, ok := existing[name]; ok && report.Report.UpdateTimestamp.Time.Equal(completedAt) {
+			continue
+		}
+
+		data, err := describeVulnerabilities(ecrClient, repository, image)
+		if err != nil {
+			logger.Error(err, "Could not describe image scan findings.", "imageDigest", image.ImageDigest)
+			return ctrl.Result{Requeue: true, RequeueAfter: time.Duration(5) * time.Second}, err
+		}
+
+		report := &ecrv1beta1.VulnerabilityReport{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: repository.Namespace},
+		}
+		_, err = controllerutil.CreateOrUpdate(ctx, r.Client, report, func() error {
+			if report.Labels == nil {
+				report.Labels = map[string]string{}
+			}
+			report.Labels[ecrRepositoryLabel] = repository.Name
+			report.Report = *data
+			return ctrl.SetControllerReference(repository, report, r.Scheme)
+		})
+		if err != nil {
+			logger.Error(err, "Failed to write VulnerabilityReport.", "name", name)
+			return ctrl.Result{}, err
+		}
+		logger.Info("Updated VulnerabilityReport.", "name", name, "summary", data.Summary)
+	}
+
+	// remove the reports of deleted or untagged images
+	for name, report := range existing {
+		if current[name] {
+			continue
+		}
+		if err := r.Delete(ctx, &report); err != nil && !k8serrors.IsNotFound(err) {
+			logger.Error(err, "Failed to delete VulnerabilityReport.", "name", name)
+			return ctrl.Result{}, err
+		}
+		logger.Info("Deleted VulnerabilityReport of removed image.", "name", name)
+	}
+
+	return ctrl.Result{RequeueAfter: vulnerabilityReportRefreshInterval}, nil
+}
+
+// Reads all scan findings of the image and converts them into a report
+func describeVulnerabilities(client *ecr.Client, repository *ecrv1beta1.Repository, image types.ImageDetail) (*ecrv1beta1.VulnerabilityReportData, error) {
+	findings := make([]types.ImageScanFinding, 0)
+	enhancedFindings := make([]types.EnhancedImageScanFinding, 0)
+	var scanFindings *types.ImageScanFindings
+
+	paginator := ecr.NewDescribeImageScanFindingsPaginator(client, &ecr.DescribeImageScanFindingsInput{
+		RepositoryName: aws.String(ecrRepositoryName(repository)),
+		ImageId:        &types.ImageIdentifier{ImageDigest: image.ImageDigest},
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		if output.ImageScanFindings != nil {
+			scanFindings = output.ImageScanFindings
+			findings = append(findings, output.ImageScanFindings.Findings...)
+			enhancedFindings = append(enhancedFindings, output.ImageScanFindings.EnhancedFindings...)
+		}
+	}
+
+	data := &ecrv1beta1.VulnerabilityReportData{
+		Scanner: ecrv1beta1.ReportScanner{
+			Name:   "ECR Basic Scanning",
+			Vendor: "Amazon Web Services",
+		},
+		Registry: ecrv1beta1.ReportRegistry{
+			Server: strings.SplitN(repository.Status.RepositoryUri, "/", 2)[0],
+		},
+		Artifact: ecrv1beta1.ReportArtifact{
+			Repository: ecrRepositoryName(repository),
+			Digest:     aws.ToString(image.ImageDigest),
+		},
+	}
+	if len(image.ImageTags) > 0 {
+		data.Artifact.Tag = image.ImageTags[0]
+	}
+	if scanFindings != nil {
+		data.UpdateTimestamp = metav1.Time{Time: aws.ToTime(scanFindings.ImageScanCompletedAt)}
+		data.Summary = createVulnerabilitySummary(scanFindings.FindingSeverityCounts)
+	}
+
+	vulnerabilities := make([]ecrv1beta1.Vulnerability, 0, len(findings)+len(enhancedFindings))
+	for _, finding := range findings {
+		vulnerability := ecrv1beta1.Vulnerability{
+			VulnerabilityID: aws.ToString(finding.Name),
+			Severity:        string(finding.Severity),
+			Title:           aws.ToString(finding.Description),
+			PrimaryLink:     aws.ToString(finding.Uri),
+		}
+		for _, attribute := range finding.Attributes {
+			switch aws.ToString(attribute.Key) {
+			case "package_name":
+				vulnerability.Resource = aws.ToString(attribute.Value)
+			case "package_version":
+				vulnerability.InstalledVersion = aws.ToString(attribute.Value)
+			case "fixed_in_version":
+				vulnerability.FixedVersion = aws.ToString(attribute.Value)
+			}
+		}
+		vulnerabilities = append(vulnerabilities, vulnerability)
+	}
+
+	// enhanced scanning findings are reported by Amazon Inspector
+	if len(enhancedFindings) > 0 {
+		data.Scanner = ecrv1beta1.ReportScanner{Name: "Amazon Inspector", Vendor: "Amazon Web Services"}
+	}
+	for _, finding := range enhancedFindings {
+		vulnerability := ecrv1beta1.Vulnerability{
+			VulnerabilityID: aws.ToString(finding.Title),
+			Severity:        aws.ToString(finding.Severity),
+			Title:           aws.ToString(finding.Description),
+		}
+		if details := finding.PackageVulnerabilityDetails; details != nil {
+			vulnerability.VulnerabilityID = aws.ToString(details.VulnerabilityId)
+			vulnerability.PrimaryLink = aws.ToString(details.SourceUrl)
+			if len(details.VulnerablePackages) > 0 {
+				vulnerability.Resource = aws.ToString(details.VulnerablePackages[0].Name)
+				vulnerability.InstalledVersion = aws.ToString(details.VulnerablePackages[0].Version)
+			}
+		}
+		vulnerabilities = append(vulnerabilities, vulnerability)
+	}
+
+	sort.SliceStable(vulnerabilities, func(i, j int) bool {
+		return severityRank(types.FindingSeverity(vulnerabilities[i].Severity)) < severityRank(types.FindingSeverity(vulnerabilities[j].Severity))
+	})
+	if len(vulnerabilities) > topFindingsLimit {
+		vulnerabilities = vulnerabilities[:topFindingsLimit]
+	}
+	data.Vulnerabilities = vulnerabilities
+
+	return data, nil
+}
+
+func createVulnerabilitySummary(counts map[string]int32) ecrv1beta1.VulnerabilitySummary {
+	return ecrv1beta1.VulnerabilitySummary{
+		CriticalCount:  int(counts[string(types.FindingSeverityCritical)]),
+		HighCount:      int(counts[string(types.FindingSeverityHigh)]),
+		MediumCount:    int(counts[string(types.FindingSeverityMedium)]),
+		LowCount:       int(counts[string(types.FindingSeverityLow)]),
+		NoneCount:      int(counts[string(types.FindingSeverityInformational)]),
+		UnknownCount:   int(counts[string(types.FindingSeverityUndefined)]),
+		UntriagedCount: int(counts[string(findingSeverityUntriaged)]),
+	}
+}
+
+func severityRank(severity types.FindingSeverity) int {
+	if rank, ok := findingSeverityOrder[severity]; ok {
+		return rank
+	}
+	return len(findingSeverityOrder)
+}
+
+// Returns the name of the VulnerabilityReport for an image digest of the repository
+func vulnerabilityReportName(repositoryName string, digest string) string {
+	hash := strings.TrimPrefix(digest, "sha256:")
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return fmt.Sprintf("%s-%s", repositoryName, hash)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VulnerabilityReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("vulnerabilityreport").
+		For(&ecrv1beta1.Repository{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&ecrv1beta1.VulnerabilityReport{}).
+		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
+		Complete(r)
+}
@@ -27,17 +27,20 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	ecrv1beta1 "github.com/lreimer/aws-ecr-operator/api/v1beta1"
 	"github.com/lreimer/aws-ecr-operator/controllers"
@@ -60,11 +63,18 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var allowedImagePrefixes string
+	var managedRepositoriesConfigMap string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&allowedImagePrefixes, "allowed-image-prefixes", "",
+		"Comma separated image prefixes allowed in namespaces restricted to images of managed repositories.")
+	flag.StringVar(&managedRepositoriesConfigMap, "managed-repositories-configmap", "",
+		"The namespace/name of the ConfigMap to publish the RepositoryUri values of managed repositories to "+
+			"for the ecr-credential-provider. Not published if empty.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -121,6 +131,152 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "RepositoryLifecycle")
 		os.Exit(1)
 	}
+	if err = (&controllers.RepositoryCompliancePolicyReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RepositoryCompliancePolicy")
+		os.Exit(1)
+	}
+	if err = (&controllers.RegistryCredentialsReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistryCredentials")
+		os.Exit(1)
+	}
+	if managedRepositoriesConfigMap != "" {
+		namespace, name, ok := strings.Cut(managedRepositoriesConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(nil, "invalid --managed-repositories-configmap, expected namespace/name", "value", managedRepositoriesConfigMap)
+			os.Exit(1)
+		}
+		if err = (&controllers.ManagedRepositoriesReconciler{
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			ConfigMap: k8stypes.NamespacedName{Namespace: namespace, Name: name},
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ManagedRepositories")
+			os.Exit(1)
+		}
+	}
+	if err = (&controllers.VulnerabilityReportReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VulnerabilityReport")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageScanReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageScan")
+		os.Exit(1)
+	}
+	if err = (&controllers.RegistryScanningConfigurationReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistryScanningConfiguration")
+		os.Exit(1)
+	}
+	if err = (&controllers.ReplicationConfigurationReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ReplicationConfiguration")
+		os.Exit(1)
+	}
+	if err = (&controllers.PullThroughCacheRuleReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PullThroughCacheRule")
+		os.Exit(1)
+	}
+	if err = (&controllers.RegistryPolicyReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RegistryPolicy")
+		os.Exit(1)
+	}
+	if err = (&controllers.PublicRepositoryReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PublicRepository")
+		os.Exit(1)
+	}
+	if err = (&controllers.RepositoryCreationTemplateReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "RepositoryCreationTemplate")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImagePromotionReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImagePromotion")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageUpdatePolicyReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageUpdatePolicy")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageUsageReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageUsage")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageCleanupPolicyReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageCleanupPolicy")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageInventoryReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageInventory")
+		os.Exit(1)
+	}
+	if err = (&controllers.ImageVerificationReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImageVerification")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		mgr.GetWebhookServer().Register("/validate-ecr-aws-cloud-qaware-de-v1beta1-repository",
+			&webhook.Admission{Handler: &controllers.RepositoryValidator{Client: mgr.GetClient()}})
+		mgr.GetWebhookServer().Register("/validate-ecr-aws-cloud-qaware-de-v1beta1-repositorypolicy",
+			&webhook.Admission{Handler: &controllers.RepositoryPolicyValidator{Client: mgr.GetClient()}})
+		mgr.GetWebhookServer().Register("/validate-ecr-aws-cloud-qaware-de-v1beta1-registry-singleton",
+			&webhook.Admission{Handler: &controllers.RegistrySingletonValidator{}})
+		mgr.GetWebhookServer().Register("/validate-ecr-aws-cloud-qaware-de-v1beta1-repositorycreationtemplate",
+			&webhook.Admission{Handler: &controllers.RepositoryCreationTemplateValidator{Client: mgr.GetClient()}})
+		mgr.GetWebhookServer().Register("/mutate-v1-pod-pull-through-cache",
+			&webhook.Admission{Handler: &controllers.PullThroughCacheMutator{Client: mgr.GetClient()}})
+		imageVulnerabilityValidator := &webhook.Admission{Handler: &controllers.ImageVulnerabilityValidator{Client: mgr.GetClient()}}
+		mgr.GetWebhookServer().Register("/validate-v1-pod-vulnerabilities", imageVulnerabilityValidator)
+		mgr.GetWebhookServer().Register("/validate-apps-v1-deployment-vulnerabilities", imageVulnerabilityValidator)
+		mgr.GetWebhookServer().Register("/validate-v1-pod-managed-images",
+			&webhook.Admission{Handler: &controllers.ManagedImageValidator{Client: mgr.GetClient(), AllowedImagePrefixes: splitList(allowedImagePrefixes)}})
+		mgr.GetWebhookServer().Register("/validate-v1-pod-signatures",
+			&webhook.Admission{Handler: &controllers.ImageSignatureValidator{Client: mgr.GetClient()}})
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -138,3 +294,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// Splits a comma separated list, ignoring empty entries
+func splitList(list string) []string {
+	values := make([]string, 0)
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}